@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+const (
+	AlternativeFunctionalReprint = "functional reprint"
+	AlternativeStrictlyBetter    = "strictly better"
+	AlternativeStrictlyWorse     = "strictly worse"
+	AlternativeSimilar           = "similar"
+)
+
+// Minimum word overlap between two rules-text skeletons for cards to be
+// reported as similar
+const similarSkeletonThreshold = 0.7
+
+var (
+	manaSymbolRegex  = regexp.MustCompile(`\{[^}]+\}`)
+	numberTokenRegex = regexp.MustCompile(`\b(three times|twice|\d+|x|one|two|three|four|five|six|seven|eight|nine|ten)\b`)
+	digitRegex       = regexp.MustCompile(`\d+`)
+	wordRegex        = regexp.MustCompile(`[a-z+\-/{}]+`)
+)
+
+// Keyword abilities that make a card worse rather than better
+var drawbackKeywords = []string{"defender"}
+
+var numberWords = map[string]string{
+	"one": "1", "two": "2", "three": "3", "four": "4", "five": "5",
+	"six": "6", "seven": "7", "eight": "8", "nine": "9", "ten": "10", "twice": "2", "three times": "3",
+}
+
+// normalizedOracleLines normalizes a card's rules text like
+// normalizeOracleText, but line by line, so that each ability stays separate
+func normalizedOracleLines(card scryfall.Card) []string {
+	text := cardOracleText(card)
+	for _, name := range cardSelfNames(card) {
+		text = strings.ReplaceAll(text, name, "CARDNAME")
+	}
+	text = reminderTextRegex.ReplaceAllString(text, "")
+
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(whitespaceRegex.ReplaceAllString(line, " ")); line != "" {
+			lines = append(lines, strings.ToLower(line))
+		}
+	}
+	return lines
+}
+
+// oracleSkeleton reduces a card's rules text to its shape: self-references,
+// mana symbols and numbers are replaced with placeholders and lines made up
+// only of keyword abilities are dropped, since keywords are compared
+// separately.
+func oracleSkeleton(card scryfall.Card) string {
+	skeleton := []string{}
+	for _, line := range normalizedOracleLines(card) {
+		if line == "" || isKeywordLine(line) {
+			continue
+		}
+		line = manaSymbolRegex.ReplaceAllString(line, "{m}")
+		line = numberTokenRegex.ReplaceAllString(line, "N")
+		skeleton = append(skeleton, line)
+	}
+	return strings.Join(skeleton, "\n")
+}
+
+// oracleNumbers returns every number in a card's rules text, including
+// generic mana in costs and spelled-out numbers, in order of appearance.
+func oracleNumbers(card scryfall.Card) []string {
+	numbers := []string{}
+	for _, line := range normalizedOracleLines(card) {
+		if isKeywordLine(line) {
+			continue
+		}
+		for _, symbol := range manaSymbolRegex.FindAllString(line, -1) {
+			if n := digitRegex.FindString(symbol); n != "" {
+				numbers = append(numbers, n)
+			}
+		}
+		line = manaSymbolRegex.ReplaceAllString(line, "")
+		for _, token := range numberTokenRegex.FindAllString(line, -1) {
+			if word, ok := numberWords[token]; ok {
+				token = word
+			}
+			numbers = append(numbers, token)
+		}
+	}
+	return numbers
+}
+
+// isKeywordLine reports whether a rules-text line consists only of keyword
+// abilities, such as "flying, vigilance" or "ward {2}".
+func isKeywordLine(line string) bool {
+	_, ok := parseKeywordLine(line)
+	return ok
+}
+
+// skeletonSimilarity returns the Jaccard similarity of the words in two skeletons
+func skeletonSimilarity(a, b string) float64 {
+	wordsA := map[string]bool{}
+	for _, w := range wordRegex.FindAllString(a, -1) {
+		wordsA[w] = true
+	}
+	wordsB := map[string]bool{}
+	for _, w := range wordRegex.FindAllString(b, -1) {
+		wordsB[w] = true
+	}
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// skeletonSearchFragment picks the longest run of words from the skeleton
+// without placeholders, which can be searched on Scryfall without knowing
+// the numbers, costs or name the candidate uses.
+func skeletonSearchFragment(skeleton string) string {
+	best := []string{}
+	for _, line := range strings.Split(skeleton, "\n") {
+		run := []string{}
+		for _, word := range strings.Fields(strings.ReplaceAll(line, `"`, "")) {
+			plain := strings.Trim(word, ".,;:")
+			if plain == "N" || plain == "cardname" || strings.ContainsAny(plain, "{}") {
+				run = run[:0]
+				continue
+			}
+			run = append(run, word)
+			if len(run) > len(best) {
+				best = append([]string{}, run...)
+			}
+		}
+	}
+	if len(best) < 3 {
+		return ""
+	}
+	return strings.TrimRight(strings.Join(best, " "), ".,;:")
+}
+
+// compareAlternative classifies a candidate against the main card and
+// describes every difference that informed the classification.
+func compareAlternative(mainCard, candidate scryfall.Card) FunctionalAlternative {
+	alternative := FunctionalAlternative{
+		Card:        candidate,
+		Similarity:  skeletonSimilarity(oracleSkeleton(mainCard), oracleSkeleton(candidate)),
+		Differences: []string{},
+	}
+
+	mainThemes := extractThemesFromCard(mainCard)
+	for _, theme := range extractThemesFromCard(candidate) {
+		if contains(mainThemes, theme) {
+			alternative.SharedThemes = append(alternative.SharedThemes, theme)
+		}
+	}
+
+	better, worse := 0, 0
+	comparable := oracleSkeleton(mainCard) == oracleSkeleton(candidate)
+	if !comparable {
+		alternative.Differences = append(alternative.Differences, "Rules text differs")
+	}
+
+	if comparable && !equalStrings(oracleNumbers(mainCard), oracleNumbers(candidate)) {
+		comparable = false
+		alternative.Differences = append(alternative.Differences, "Rules text uses different numbers or costs")
+	}
+
+	switch {
+	case candidate.CMC < mainCard.CMC:
+		better++
+		alternative.Differences = append(alternative.Differences, fmt.Sprintf("Lower mana value (%g vs %g)", candidate.CMC, mainCard.CMC))
+	case candidate.CMC > mainCard.CMC:
+		worse++
+		alternative.Differences = append(alternative.Differences, fmt.Sprintf("Higher mana value (%g vs %g)", candidate.CMC, mainCard.CMC))
+	}
+
+	if candidate.ManaCost != mainCard.ManaCost && candidate.CMC == mainCard.CMC {
+		comparable = false
+		alternative.Differences = append(alternative.Differences, fmt.Sprintf("Different mana cost (%s vs %s)", candidate.ManaCost, mainCard.ManaCost))
+	}
+
+	for _, stat := range []struct {
+		name       string
+		main, cand *string
+	}{
+		{"power", mainCard.Power, candidate.Power},
+		{"toughness", mainCard.Toughness, candidate.Toughness},
+	} {
+		cmp, ok := compareStat(stat.main, stat.cand)
+		if !ok {
+			comparable = false
+			alternative.Differences = append(alternative.Differences, fmt.Sprintf("Different %s", stat.name))
+			continue
+		}
+		if cmp > 0 {
+			better++
+			alternative.Differences = append(alternative.Differences, fmt.Sprintf("Higher %s (%s vs %s)", stat.name, *stat.cand, *stat.main))
+		} else if cmp < 0 {
+			worse++
+			alternative.Differences = append(alternative.Differences, fmt.Sprintf("Lower %s (%s vs %s)", stat.name, *stat.cand, *stat.main))
+		}
+	}
+
+	mainKeywords := extractKeywordsFromText(cardOracleText(mainCard))
+	candidateKeywords := extractKeywordsFromText(cardOracleText(candidate))
+	for _, kw := range candidateKeywords {
+		if !contains(mainKeywords, kw) {
+			if contains(drawbackKeywords, kw) {
+				worse++
+			} else {
+				better++
+			}
+			alternative.Differences = append(alternative.Differences, fmt.Sprintf("Adds %s", kw))
+		}
+	}
+	for _, kw := range mainKeywords {
+		if !contains(candidateKeywords, kw) {
+			if contains(drawbackKeywords, kw) {
+				better++
+			} else {
+				worse++
+			}
+			alternative.Differences = append(alternative.Differences, fmt.Sprintf("Lacks %s", kw))
+		}
+	}
+
+	switch {
+	case !comparable || better > 0 && worse > 0:
+		alternative.Classification = AlternativeSimilar
+	case better > 0:
+		alternative.Classification = AlternativeStrictlyBetter
+	case worse > 0:
+		alternative.Classification = AlternativeStrictlyWorse
+	default:
+		alternative.Classification = AlternativeFunctionalReprint
+	}
+	return alternative
+}
+
+// compareStat compares an optional numeric stat such as power. It returns
+// +1 when the candidate's is higher and reports false when either value is
+// not a plain number (e.g. "*") and the two differ.
+func compareStat(main, candidate *string) (int, bool) {
+	if main == nil && candidate == nil {
+		return 0, true
+	}
+	if main == nil || candidate == nil {
+		return 0, false
+	}
+	m, errM := strconv.Atoi(*main)
+	c, errC := strconv.Atoi(*candidate)
+	if errM != nil || errC != nil {
+		return 0, *main == *candidate
+	}
+	switch {
+	case c > m:
+		return 1, true
+	case c < m:
+		return -1, true
+	}
+	return 0, true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// colorIdentityString joins a card's color identity for use in Scryfall queries
+func colorIdentityString(card scryfall.Card) string {
+	identity := ""
+	for _, color := range card.ColorIdentity {
+		identity += string(color)
+	}
+	if identity == "" {
+		return "C"
+	}
+	return identity
+}
+
+// findFunctionalAlternativeCandidates searches Scryfall for cards of the same
+// type and within the main card's color identity that share the most
+// distinctive part of its rules text, or its keywords for keyword-only cards.
+func findFunctionalAlternativeCandidates(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions) ([]scryfall.Card, error) {
+	filter := fmt.Sprintf(`t:%s id<=%s -oracleid:%s`, primaryCardType(mainCard.TypeLine), colorIdentityString(mainCard), mainCard.OracleID)
+
+	query := ""
+	if fragment := skeletonSearchFragment(oracleSkeleton(mainCard)); fragment != "" {
+		query = fmt.Sprintf(`o:"%s" %s`, fragment, filter)
+	} else if keywords := extractKeywordsFromText(cardOracleText(mainCard)); len(keywords) > 0 {
+		parts := []string{}
+		for _, kw := range keywords {
+			parts = append(parts, fmt.Sprintf(`keyword:"%s"`, kw))
+		}
+		query = fmt.Sprintf(`%s cmc>=%g cmc<=%g %s`, strings.Join(parts, " "), mainCard.CMC-1, mainCard.CMC+1, filter)
+	} else {
+		query = fmt.Sprintf(`is:vanilla cmc>=%g cmc<=%g %s`, mainCard.CMC-1, mainCard.CMC+1, filter)
+	}
+
+	log.Printf("Searching for functional alternatives to %s (Query: %s)", mainCard.Name, query)
+	result, err := client.SearchCards(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Cards, nil
+}
+
+// rankFunctionalAlternatives classifies candidates and orders them from the
+// closest substitutes to the loosest matches.
+func rankFunctionalAlternatives(mainCard scryfall.Card, candidates []scryfall.Card, includeSimilar bool) []FunctionalAlternative {
+	order := map[string]int{
+		AlternativeFunctionalReprint: 0,
+		AlternativeStrictlyBetter:    1,
+		AlternativeStrictlyWorse:     2,
+		AlternativeSimilar:           3,
+	}
+
+	alternatives := []FunctionalAlternative{}
+	for _, candidate := range candidates {
+		alternative := compareAlternative(mainCard, candidate)
+		if alternative.Classification == AlternativeSimilar {
+			if !includeSimilar || alternative.Similarity < similarSkeletonThreshold {
+				continue
+			}
+		}
+		alternatives = append(alternatives, alternative)
+	}
+
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		a, b := alternatives[i], alternatives[j]
+		if order[a.Classification] != order[b.Classification] {
+			return order[a.Classification] < order[b.Classification]
+		}
+		return a.Similarity > b.Similarity
+	})
+	return alternatives
+}
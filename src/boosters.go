@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Booster types
+const (
+	BoosterPlay  = "play"
+	BoosterDraft = "draft"
+	BoosterSet   = "set"
+)
+
+// defaultBoosterSet is the set name of the definitions used for any set
+// without its own
+const defaultBoosterSet = "default"
+
+// Limits for generate_booster
+const (
+	maxBoosterCount = 36
+	maxPickAttempts = 20
+)
+
+// boosterSlotOption is one possible source of a slot's card, picked in
+// proportion to its weight. Cards come from a Scryfall search of the booster's
+// set, or of Set when given. Without Scryfall the search is run against the
+// offline card data, or Cards is used when that has no matches.
+type boosterSlotOption struct {
+	Query  string   `json:"query"`
+	Set    string   `json:"set,omitempty"`
+	Cards  []string `json:"cards,omitempty"`
+	Rarity string   `json:"rarity,omitempty"`
+	Foil   bool     `json:"foil,omitempty"`
+	Weight int      `json:"weight"`
+}
+
+// boosterSlot is a group of cards in a booster drawn from the same options
+type boosterSlot struct {
+	Name    string              `json:"name"`
+	Count   int                 `json:"count"`
+	Options []boosterSlotOption `json:"options"`
+}
+
+// boosterDefinition describes the contents of one booster type for one set,
+// or for every set when Set is "default"
+type boosterDefinition struct {
+	Set         string        `json:"set"`
+	Type        string        `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Slots       []boosterSlot `json:"slots"`
+}
+
+// boosterDataset is the format of res/boosters.json and of MCP_BOOSTER_FILE
+type boosterDataset struct {
+	Version  string              `json:"version"`
+	Boosters []boosterDefinition `json:"boosters"`
+}
+
+var (
+	boostersMu    sync.RWMutex
+	boostersCache *boosterDataset
+)
+
+func decodeBoosterDataset(data []byte) (boosterDataset, error) {
+	var dataset boosterDataset
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&dataset); err != nil {
+		return boosterDataset{}, err
+	}
+	return dataset, nil
+}
+
+func boosterKey(set, boosterType string) string {
+	return strings.ToLower(set) + "/" + strings.ToLower(boosterType)
+}
+
+// validateBoosterDataset checks that every definition names a set and a known
+// type, is defined once, and has slots whose options have a positive weight
+// and a source of cards.
+func validateBoosterDataset(dataset boosterDataset) error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, booster := range dataset.Boosters {
+		label := fmt.Sprintf("#%d (%s %s)", i, booster.Set, booster.Type)
+		if booster.Set == "" {
+			errs = append(errs, fmt.Errorf("booster %s: missing set", label))
+		}
+		if !contains([]string{BoosterPlay, BoosterDraft, BoosterSet}, booster.Type) {
+			errs = append(errs, fmt.Errorf("booster %s: type must be play, draft or set", label))
+		}
+		key := boosterKey(booster.Set, booster.Type)
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("booster %s: defined twice", label))
+		}
+		seen[key] = true
+
+		if len(booster.Slots) == 0 {
+			errs = append(errs, fmt.Errorf("booster %s: missing slots", label))
+		}
+		for _, slot := range booster.Slots {
+			if slot.Name == "" || slot.Count <= 0 {
+				errs = append(errs, fmt.Errorf("booster %s: every slot needs a name and a positive count", label))
+			}
+			if len(slot.Options) == 0 {
+				errs = append(errs, fmt.Errorf("booster %s: slot '%s' has no options", label, slot.Name))
+			}
+			for _, option := range slot.Options {
+				if option.Weight <= 0 {
+					errs = append(errs, fmt.Errorf("booster %s: slot '%s' has an option without a positive weight", label, slot.Name))
+				}
+				if option.Query == "" && option.Set == "" && len(option.Cards) == 0 {
+					errs = append(errs, fmt.Errorf("booster %s: slot '%s' has an option without a query, set or cards", label, slot.Name))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func loadEmbeddedBoosterDataset() (boosterDataset, error) {
+	data, err := embeddedResources.ReadFile("res/boosters.json")
+	if err != nil {
+		return boosterDataset{}, err
+	}
+	return decodeBoosterDataset(data)
+}
+
+// loadBoosterDataset returns the booster definitions, loading the embedded
+// ones on first use
+func loadBoosterDataset() boosterDataset {
+	boostersMu.RLock()
+	if boostersCache != nil {
+		defer boostersMu.RUnlock()
+		return *boostersCache
+	}
+	boostersMu.RUnlock()
+
+	dataset, err := loadEmbeddedBoosterDataset()
+	if err != nil {
+		log.Printf("Error loading booster definitions: %v", err)
+		return boosterDataset{}
+	}
+
+	boostersMu.Lock()
+	defer boostersMu.Unlock()
+	if boostersCache == nil {
+		boostersCache = &dataset
+	}
+	return *boostersCache
+}
+
+// validateEmbeddedBoosterDataset checks res/boosters.json at startup
+func validateEmbeddedBoosterDataset() error {
+	dataset, err := loadEmbeddedBoosterDataset()
+	if err != nil {
+		return fmt.Errorf("res/boosters.json: %w", err)
+	}
+	if err := validateBoosterDataset(dataset); err != nil {
+		return fmt.Errorf("res/boosters.json:\n%w", err)
+	}
+	return nil
+}
+
+// loadBoosterFile merges the definitions in path into the embedded ones. A
+// definition for the same set and type replaces the embedded one.
+func loadBoosterFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	extra, err := decodeBoosterDataset(data)
+	if err != nil {
+		return err
+	}
+	if err := validateBoosterDataset(extra); err != nil {
+		return err
+	}
+
+	dataset, err := loadEmbeddedBoosterDataset()
+	if err != nil {
+		return err
+	}
+	index := map[string]int{}
+	for i, booster := range dataset.Boosters {
+		index[boosterKey(booster.Set, booster.Type)] = i
+	}
+	for _, booster := range extra.Boosters {
+		if i, ok := index[boosterKey(booster.Set, booster.Type)]; ok {
+			dataset.Boosters[i] = booster
+			continue
+		}
+		dataset.Boosters = append(dataset.Boosters, booster)
+	}
+	if extra.Version != "" {
+		dataset.Version = fmt.Sprintf("%s+%s", dataset.Version, extra.Version)
+	}
+
+	boostersMu.Lock()
+	boostersCache = &dataset
+	boostersMu.Unlock()
+
+	log.Printf("Loaded %d booster definitions from %s", len(extra.Boosters), path)
+	return nil
+}
+
+// setupBoosterFile loads the configured booster definition file, if any
+func setupBoosterFile(config *Config) {
+	if config.BoosterFile == "" {
+		return
+	}
+	if err := loadBoosterFile(config.BoosterFile); err != nil {
+		log.Printf("Error loading booster file %s: %v", config.BoosterFile, err)
+	}
+}
+
+// findBoosterDefinition returns the set's own definition of a booster type,
+// or the default one
+func findBoosterDefinition(set, boosterType string) (boosterDefinition, bool) {
+	dataset := loadBoosterDataset()
+	var fallback *boosterDefinition
+	for i, booster := range dataset.Boosters {
+		if boosterKey(booster.Set, booster.Type) == boosterKey(set, boosterType) {
+			return booster, true
+		}
+		if boosterKey(booster.Set, booster.Type) == boosterKey(defaultBoosterSet, boosterType) {
+			fallback = &dataset.Boosters[i]
+		}
+	}
+	if fallback == nil {
+		return boosterDefinition{}, false
+	}
+	return *fallback, true
+}
+
+// newSeededRand returns a random source that always gives the same sequence
+// for the same seed
+func newSeededRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(seed)^0x9e3779b97f4a7c15))
+}
+
+// boosterGenerator opens boosters of one set, caching the card pool of each
+// slot option and keeping every card opened so far
+type boosterGenerator struct {
+	ctx     context.Context
+	client  *scryfall.Client
+	rng     *rand.Rand
+	set     string
+	pools   map[string][]scryfall.Card
+	opened  []scryfall.Card
+	notes   []string
+	offline bool
+}
+
+func newBoosterGenerator(ctx context.Context, client *scryfall.Client, set string, seed int64) *boosterGenerator {
+	return &boosterGenerator{
+		ctx:    ctx,
+		client: client,
+		rng:    newSeededRand(seed),
+		set:    strings.ToLower(set),
+		pools:  map[string][]scryfall.Card{},
+	}
+}
+
+func (g *boosterGenerator) note(format string, args ...any) {
+	note := fmt.Sprintf(format, args...)
+	if !contains(g.notes, note) {
+		g.notes = append(g.notes, note)
+	}
+}
+
+// optionQuery returns the Scryfall search for an option's cards
+func (g *boosterGenerator) optionQuery(option boosterSlotOption) string {
+	set := g.set
+	if option.Set != "" {
+		set = option.Set
+	}
+	return strings.TrimSpace(fmt.Sprintf("e:%s %s", set, option.Query))
+}
+
+// pool returns the cards an option draws from, searching Scryfall the first
+// time. When Scryfall can't be reached the search is run against the offline
+// card data, and failing that the option's card list is used. Pools are
+// sorted by collector number so that a seed always picks the same cards.
+func (g *boosterGenerator) pool(option boosterSlotOption) []scryfall.Card {
+	query := g.optionQuery(option)
+	key := query + "|" + strings.Join(option.Cards, "|")
+	if cards, ok := g.pools[key]; ok {
+		return cards
+	}
+
+	cards := []scryfall.Card{}
+	searchable := option.Query != "" || option.Set != ""
+	var err error
+	if g.client != nil && searchable {
+		cards, err = searchAllCards(g.ctx, g.client, query, scryfall.SearchCardsOptions{
+			Unique: scryfall.UniqueModeCards,
+			Order:  scryfall.OrderSet,
+			Dir:    scryfall.DirAsc,
+		})
+		if err != nil {
+			log.Printf("Error searching booster pool (Query: %s): %v", query, err)
+			cards = []scryfall.Card{}
+		}
+	}
+
+	if g.client == nil || err != nil {
+		g.offline = true
+		if searchable {
+			if offline, err := offlineSearch(query, true); err == nil && len(offline) > 0 {
+				g.note("Scryfall could not be reached, so cards come from the offline card data")
+				cards = offline
+			}
+		}
+		if len(cards) == 0 && len(option.Cards) > 0 {
+			g.note("Used the card list from the booster definition for '%s' because Scryfall could not be reached", query)
+			for _, name := range option.Cards {
+				card, ok := offlineCardByName(name)
+				if !ok {
+					card = scryfall.Card{Name: name}
+				}
+				card.Set = g.set
+				if option.Rarity != "" {
+					card.Rarity = option.Rarity
+				}
+				cards = append(cards, card)
+			}
+		}
+	}
+	g.pools[key] = cards
+	return cards
+}
+
+// pickOption picks one of a slot's options in proportion to their weights,
+// skipping options without cards
+func (g *boosterGenerator) pickOption(slot boosterSlot) (boosterSlotOption, bool) {
+	options := []boosterSlotOption{}
+	total := 0
+	for _, option := range slot.Options {
+		if len(g.pool(option)) > 0 {
+			options = append(options, option)
+			total += option.Weight
+		}
+	}
+	if total == 0 {
+		return boosterSlotOption{}, false
+	}
+	roll := g.rng.IntN(total)
+	for _, option := range options {
+		if roll < option.Weight {
+			return option, true
+		}
+		roll -= option.Weight
+	}
+	return options[len(options)-1], true
+}
+
+// openBooster generates one booster. The same card is not opened twice in a
+// booster unless its pool has nothing else left.
+func (g *boosterGenerator) openBooster(definition boosterDefinition, number int) Booster {
+	booster := Booster{Number: number, Cards: []BoosterCard{}}
+	opened := map[string]bool{}
+	for _, slot := range definition.Slots {
+		for range slot.Count {
+			option, ok := g.pickOption(slot)
+			if !ok {
+				g.note("No cards were found for the %s slot", slot.Name)
+				continue
+			}
+			pool := g.pool(option)
+			card := pool[g.rng.IntN(len(pool))]
+			for attempt := 0; attempt < maxPickAttempts && opened[card.Name]; attempt++ {
+				card = pool[g.rng.IntN(len(pool))]
+			}
+			opened[card.Name] = true
+			g.opened = append(g.opened, card)
+			booster.Cards = append(booster.Cards, newBoosterCard(card, slot.Name, option))
+		}
+	}
+	return booster
+}
+
+// checkOpened returns an error explaining why no cards could be opened, so
+// tools report that rather than return empty boosters
+func (g *boosterGenerator) checkOpened() error {
+	if len(g.opened) > 0 {
+		return nil
+	}
+	if !g.offline {
+		return fmt.Errorf("no booster cards were found for %s", g.set)
+	}
+	if _, ok := loadedOfflineCards(); !ok {
+		return fmt.Errorf("can't open boosters of %s: Scryfall could not be reached, and %v", g.set, errNoOfflineCards)
+	}
+	return fmt.Errorf("can't open boosters of %s: Scryfall could not be reached, and the offline card data has no booster cards from the set", g.set)
+}
+
+// newBoosterCard describes a card opened in a slot
+func newBoosterCard(card scryfall.Card, slot string, option boosterSlotOption) BoosterCard {
+	boosterCard := BoosterCard{
+		Slot:            slot,
+		Name:            card.Name,
+		Set:             card.Set,
+		CollectorNumber: card.CollectorNumber,
+		Rarity:          card.Rarity,
+		Foil:            option.Foil,
+		ManaCost:        cardManaCost(card),
+		TypeLine:        card.TypeLine,
+		ColorIdentity:   colorIdentityString(card),
+	}
+	if boosterCard.Rarity == "" {
+		boosterCard.Rarity = option.Rarity
+	}
+	return boosterCard
+}
+
+// searchAllCards returns every card matching a query, following Scryfall's
+// pages. A search without results returns no cards.
+func searchAllCards(ctx context.Context, client *scryfall.Client, query string, opts scryfall.SearchCardsOptions) ([]scryfall.Card, error) {
+	opts.Page = 1
+	cards := []scryfall.Card{}
+	for {
+		result, err := client.SearchCards(ctx, query, opts)
+		if err != nil {
+			var scryfallErr *scryfall.Error
+			if errors.As(err, &scryfallErr) && scryfallErr.Status == 404 {
+				return cards, nil
+			}
+			return nil, err
+		}
+		cards = append(cards, result.Cards...)
+		if !result.HasMore {
+			return cards, nil
+		}
+		opts.Page++
+	}
+}
+
+// errNoRandomCardMatch is returned when no card matches random_card's query
+var errNoRandomCardMatch = errors.New("no cards match")
+
+// pickRandomCardOrOffline picks a card from Scryfall, or from the offline
+// card data when Scryfall can't be reached, and reports which it used.
+// Scryfall's answer about the query itself, such as a syntax error, is
+// returned as it is.
+func pickRandomCardOrOffline(ctx context.Context, client *scryfall.Client, query, offlineQuery string, seed int64) (scryfall.Card, int, string, error) {
+	err := errScryfallUnavailable
+	if client != nil {
+		var card scryfall.Card
+		var total int
+		card, total, err = pickRandomCard(ctx, client, query, seed)
+		var scryfallErr *scryfall.Error
+		if err == nil || errors.As(err, &scryfallErr) || errors.Is(err, errNoRandomCardMatch) {
+			return card, total, SetSourceScryfall, err
+		}
+	}
+
+	log.Printf("Error picking random card from Scryfall, using offline card data: %v", err)
+	card, total, offlineErr := pickOfflineRandomCard(offlineQuery, seed)
+	if errors.Is(offlineErr, errNoOfflineCards) {
+		return scryfall.Card{}, 0, "", fmt.Errorf("Scryfall could not be reached (%v), and %w", err, offlineErr)
+	}
+	return card, total, SetSourceOffline, offlineErr
+}
+
+// pickOfflineRandomCard picks one card matching a query from the offline
+// card data using the seed. Without a query, any paper card can be picked.
+func pickOfflineRandomCard(query string, seed int64) (scryfall.Card, int, error) {
+	cards, err := offlineSearch(query, true)
+	if err != nil {
+		return scryfall.Card{}, 0, err
+	}
+	if query == "" {
+		paper := []scryfall.Card{}
+		for _, card := range cards {
+			if !card.Digital {
+				paper = append(paper, card)
+			}
+		}
+		cards = paper
+	}
+	if len(cards) == 0 {
+		return scryfall.Card{}, 0, fmt.Errorf("no cards in the offline card data match '%s'", query)
+	}
+	return cards[newSeededRand(seed).IntN(len(cards))], len(cards), nil
+}
+
+// pickRandomCard picks one card matching a query using the seed. Scryfall's
+// random endpoint can't be seeded, so the card is chosen by its position in
+// the name-ordered results and only the page holding it is fetched.
+func pickRandomCard(ctx context.Context, client *scryfall.Client, query string, seed int64) (scryfall.Card, int, error) {
+	opts := scryfall.SearchCardsOptions{
+		Unique: scryfall.UniqueModeCards,
+		Order:  scryfall.OrderName,
+		Dir:    scryfall.DirAsc,
+		Page:   1,
+	}
+	result, err := client.SearchCards(ctx, query, opts)
+	if err != nil {
+		return scryfall.Card{}, 0, err
+	}
+	if result.TotalCards == 0 || len(result.Cards) == 0 {
+		return scryfall.Card{}, 0, fmt.Errorf("%w '%s'", errNoRandomCardMatch, query)
+	}
+
+	index := newSeededRand(seed).IntN(result.TotalCards)
+	if page := index/scryfallSearchPageSize + 1; page != 1 {
+		opts.Page = page
+		if result, err = client.SearchCards(ctx, query, opts); err != nil {
+			return scryfall.Card{}, 0, err
+		}
+	}
+	offset := index % scryfallSearchPageSize
+	if offset >= len(result.Cards) {
+		offset = len(result.Cards) - 1
+	}
+	return result.Cards[offset], result.TotalCards, nil
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// errNoOfflineCards is returned by offline lookups when no card data has been
+// loaded
+var errNoOfflineCards = errors.New("no offline card data is loaded; set MCP_CARD_DATA_FILE to a Scryfall bulk data file")
+
+// offlineCardIndex is the card data of a Scryfall bulk data file, used when
+// Scryfall can't be reached. "Default Cards" has every printing, so sets can
+// be browsed and boosters opened; "Oracle Cards" only has one printing of
+// each card, which is enough to look cards up by name.
+// https://scryfall.com/docs/api/bulk-data
+type offlineCardIndex struct {
+	byName map[string]scryfall.Card
+	bySet  map[string][]scryfall.Card
+	count  int
+}
+
+var (
+	offlineCardsMu sync.RWMutex
+	offlineCards   *offlineCardIndex
+)
+
+// slimOfflineCard keeps the fields the tools use, as bulk data files hold
+// over a hundred thousand printings
+func slimOfflineCard(card scryfall.Card) scryfall.Card {
+	return scryfall.Card{
+		ID:              card.ID,
+		OracleID:        card.OracleID,
+		Name:            card.Name,
+		Layout:          card.Layout,
+		CMC:             card.CMC,
+		TypeLine:        card.TypeLine,
+		OracleText:      card.OracleText,
+		ManaCost:        card.ManaCost,
+		Power:           card.Power,
+		Toughness:       card.Toughness,
+		Loyalty:         card.Loyalty,
+		Defense:         card.Defense,
+		Colors:          card.Colors,
+		ColorIdentity:   card.ColorIdentity,
+		CardFaces:       card.CardFaces,
+		Legalities:      card.Legalities,
+		Set:             card.Set,
+		SetName:         card.SetName,
+		CollectorNumber: card.CollectorNumber,
+		Rarity:          card.Rarity,
+		Keywords:        card.Keywords,
+		ProducedMana:    card.ProducedMana,
+		Booster:         card.Booster,
+		Digital:         card.Digital,
+		ReleasedAt:      card.ReleasedAt,
+		ScryfallURI:     card.ScryfallURI,
+	}
+}
+
+// collectorNumberLess orders collector numbers by their numeric part, then
+// as text, so that "9" comes before "10" and "10" before "10a"
+func collectorNumberLess(a, b string) bool {
+	numberA, errA := strconv.Atoi(strings.TrimRight(a, "abcdefghijklmnopqrstuvwxyz★†"))
+	numberB, errB := strconv.Atoi(strings.TrimRight(b, "abcdefghijklmnopqrstuvwxyz★†"))
+	if errA == nil && errB == nil && numberA != numberB {
+		return numberA < numberB
+	}
+	if (errA == nil) != (errB == nil) {
+		return errA == nil
+	}
+	return a < b
+}
+
+// decodeOfflineCards reads a bulk data file, a JSON array of cards, one card
+// at a time. Cards in other languages are skipped.
+func decodeOfflineCards(r io.Reader) (*offlineCardIndex, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("not a Scryfall bulk data file: expected a JSON array of cards")
+	}
+
+	index := &offlineCardIndex{
+		byName: map[string]scryfall.Card{},
+		bySet:  map[string][]scryfall.Card{},
+	}
+	for decoder.More() {
+		var card scryfall.Card
+		if err := decoder.Decode(&card); err != nil {
+			return nil, fmt.Errorf("card %d: %w", index.count+1, err)
+		}
+		if card.Name == "" || (card.Lang != "" && card.Lang != scryfall.LangEnglish) {
+			continue
+		}
+		card = slimOfflineCard(card)
+		index.count++
+		index.bySet[card.Set] = append(index.bySet[card.Set], card)
+
+		// Prefer a booster printing to promos and other oddities
+		names := []string{card.Name}
+		for _, face := range card.CardFaces {
+			names = append(names, face.Name)
+		}
+		for _, name := range names {
+			key := comboNameKey(name)
+			if existing, ok := index.byName[key]; !ok || (!existing.Booster && card.Booster) {
+				index.byName[key] = card
+			}
+		}
+	}
+
+	for _, cards := range index.bySet {
+		sort.SliceStable(cards, func(i, j int) bool {
+			return collectorNumberLess(cards[i].CollectorNumber, cards[j].CollectorNumber)
+		})
+	}
+	return index, nil
+}
+
+// loadOfflineCardFile replaces the offline card data with that in path
+func loadOfflineCardFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	index, err := decodeOfflineCards(file)
+	if err != nil {
+		return err
+	}
+
+	offlineCardsMu.Lock()
+	offlineCards = index
+	offlineCardsMu.Unlock()
+	markCardNamesStale()
+
+	log.Printf("Loaded %d offline cards in %d sets from %s", index.count, len(index.bySet), path)
+	return nil
+}
+
+// setupOfflineCards loads the configured bulk data file in the background,
+// as large files take a while to read
+func setupOfflineCards(config *Config) {
+	if config.CardDataFile == "" {
+		return
+	}
+	go func() {
+		if err := loadOfflineCardFile(config.CardDataFile); err != nil {
+			log.Printf("Error loading card data file %s: %v", config.CardDataFile, err)
+		}
+	}()
+}
+
+func loadedOfflineCards() (*offlineCardIndex, bool) {
+	offlineCardsMu.RLock()
+	defer offlineCardsMu.RUnlock()
+	return offlineCards, offlineCards != nil
+}
+
+// offlineCardNames returns the name of every card in the offline data
+func offlineCardNames() []string {
+	index, ok := loadedOfflineCards()
+	if !ok {
+		return nil
+	}
+	names := []string{}
+	for key, card := range index.byName {
+		if comboNameKey(card.Name) == key {
+			names = append(names, card.Name)
+		}
+	}
+	return names
+}
+
+// offlineCardByName looks a card up by its exact name, or the name of one of
+// its faces, ignoring case
+func offlineCardByName(name string) (scryfall.Card, bool) {
+	index, ok := loadedOfflineCards()
+	if !ok {
+		return scryfall.Card{}, false
+	}
+	card, ok := index.byName[comboNameKey(name)]
+	return card, ok
+}
+
+// offlineSetCards returns every printing in a set in collector number order
+func offlineSetCards(set string) []scryfall.Card {
+	index, ok := loadedOfflineCards()
+	if !ok {
+		return nil
+	}
+	return index.bySet[strings.ToLower(set)]
+}
+
+// offlineSearch runs a Scryfall search against the offline data, returning
+// matches in set and collector number order. With uniqueNames only the first
+// printing of each card is returned, like Scryfall's unique=cards. Only the
+// search syntax the tools themselves build is understood; see
+// parseOfflineQuery. An empty query matches every card.
+func offlineSearch(query string, uniqueNames bool) ([]scryfall.Card, error) {
+	index, ok := loadedOfflineCards()
+	if !ok {
+		return nil, errNoOfflineCards
+	}
+	match, set := offlineMatcher(func(scryfall.Card) bool { return true }), ""
+	if strings.TrimSpace(query) != "" {
+		var err error
+		if match, set, err = parseOfflineQuery(query); err != nil {
+			return nil, err
+		}
+	}
+
+	sets := []string{set}
+	if set == "" {
+		sets = sortedKeys(index.bySet)
+	}
+	cards := []scryfall.Card{}
+	seen := map[string]bool{}
+	for _, set := range sets {
+		for _, card := range index.bySet[set] {
+			if !match(card) || (uniqueNames && seen[card.Name]) {
+				continue
+			}
+			seen[card.Name] = true
+			cards = append(cards, card)
+		}
+	}
+	return cards, nil
+}
+
+// offlineMatcher reports whether a card matches part of a search
+type offlineMatcher func(card scryfall.Card) bool
+
+// offlineQueryParser parses the subset of Scryfall's search syntax that
+// booster definitions and browse_set use: e:, r:, t:, o:, c:, c>=, c=,
+// is:booster, words matched against the name, negation with a leading '-',
+// and parenthesized groups joined by OR.
+type offlineQueryParser struct {
+	tokens []string
+	pos    int
+	depth  int
+	set    string
+}
+
+// parseOfflineQuery compiles a search, returning its matcher and the set
+// code it is limited to, if any
+func parseOfflineQuery(query string) (offlineMatcher, string, error) {
+	parser := &offlineQueryParser{tokens: tokenizeOfflineQuery(query)}
+	match, err := parser.parseOr()
+	if err != nil {
+		return nil, "", err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, "", fmt.Errorf("unexpected '%s' in '%s'", parser.tokens[parser.pos], query)
+	}
+	return match, parser.set, nil
+}
+
+// tokenizeOfflineQuery splits a search into terms and parentheses, keeping
+// quoted values together
+func tokenizeOfflineQuery(query string) []string {
+	tokens := []string{}
+	current := strings.Builder{}
+	quoted := false
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+			current.WriteRune(r)
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (p *offlineQueryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *offlineQueryParser) parseOr() (offlineMatcher, error) {
+	alternatives := []offlineMatcher{}
+	for {
+		match, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, match)
+		if !strings.EqualFold(p.peek(), "or") {
+			break
+		}
+		p.pos++
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	if p.depth == 0 {
+		// A set in one alternative doesn't limit the others
+		p.set = ""
+	}
+	return func(card scryfall.Card) bool {
+		for _, match := range alternatives {
+			if match(card) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func (p *offlineQueryParser) parseAnd() (offlineMatcher, error) {
+	terms := []offlineMatcher{}
+	for token := p.peek(); token != "" && token != ")" && !strings.EqualFold(token, "or"); token = p.peek() {
+		match, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, match)
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("empty search")
+	}
+	return func(card scryfall.Card) bool {
+		for _, match := range terms {
+			if !match(card) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (p *offlineQueryParser) parseTerm() (offlineMatcher, error) {
+	token := p.peek()
+	p.pos++
+
+	negate := false
+	if strings.HasPrefix(token, "-") {
+		negate = true
+		token = strings.TrimPrefix(token, "-")
+		if token == "" {
+			token = p.peek()
+			p.pos++
+		}
+	}
+
+	var match offlineMatcher
+	var err error
+	if token == "(" {
+		p.depth++
+		if match, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing ')'")
+		}
+		p.depth--
+		p.pos++
+	} else if match, err = p.parseAtom(token, !negate && p.depth == 0); err != nil {
+		return nil, err
+	}
+
+	if negate {
+		return func(card scryfall.Card) bool { return !match(card) }, nil
+	}
+	return match, nil
+}
+
+// parseAtom compiles one keyword term, such as r:common or t:creature. Only
+// a top-level term, one every match must satisfy, narrows the search to a set.
+func (p *offlineQueryParser) parseAtom(token string, topLevel bool) (offlineMatcher, error) {
+	key, operator, value := "", "", ""
+	for _, op := range []string{">=", "<=", ":", "=", ">", "<"} {
+		if i := strings.Index(token, op); i > 0 {
+			key, operator, value = strings.ToLower(token[:i]), op, strings.ToLower(token[i+len(op):])
+			break
+		}
+	}
+	if key == "" {
+		word := strings.ToLower(token)
+		return func(card scryfall.Card) bool { return strings.Contains(strings.ToLower(card.Name), word) }, nil
+	}
+	equality := operator == ":" || operator == "="
+
+	switch {
+	case (key == "e" || key == "s" || key == "set" || key == "edition") && equality:
+		if topLevel {
+			p.set = value
+		}
+		return func(card scryfall.Card) bool { return card.Set == value }, nil
+	case (key == "r" || key == "rarity") && equality:
+		return func(card scryfall.Card) bool { return card.Rarity == value }, nil
+	case (key == "t" || key == "type") && equality:
+		return func(card scryfall.Card) bool { return strings.Contains(strings.ToLower(card.TypeLine), value) }, nil
+	case (key == "o" || key == "oracle") && equality:
+		return func(card scryfall.Card) bool { return strings.Contains(strings.ToLower(cardOracleText(card)), value) }, nil
+	case key == "is" && value == "booster" && equality:
+		return func(card scryfall.Card) bool { return card.Booster }, nil
+	case (key == "c" || key == "color") && (equality || operator == ">="):
+		colors, err := parseColorIdentity(value)
+		if err != nil {
+			return nil, err
+		}
+		if colors == "C" {
+			return func(card scryfall.Card) bool { return cardColors(card) == "" }, nil
+		}
+		exact := operator == "="
+		return func(card scryfall.Card) bool {
+			have := cardColors(card)
+			for _, color := range colors {
+				if !strings.ContainsRune(have, color) {
+					return false
+				}
+			}
+			return !exact || len(have) == len(colors)
+		}, nil
+	}
+	return nil, fmt.Errorf("'%s' can't be searched without Scryfall", token)
+}
+
+// cardColors is a card's colors in WUBRG order, including those of its faces
+func cardColors(card scryfall.Card) string {
+	colors := append([]scryfall.Color{}, card.Colors...)
+	for _, face := range card.CardFaces {
+		colors = append(colors, face.Colors...)
+	}
+	have := ""
+	for _, color := range "WUBRG" {
+		for _, c := range colors {
+			if string(c) == string(color) {
+				have += string(color)
+				break
+			}
+		}
+	}
+	return have
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the TLS certificate files are checked for
+// changes, such as a renewal by certbot or a mounted secret being rotated
+const certReloadInterval = 30 * time.Second
+
+// certReloader serves the TLS certificate in certFile and keyFile, loading it
+// again whenever either file changes, so renewed certificates are used
+// without restarting the server
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// certFilesSnapshot captures the size and modification time of the
+// certificate and key so that changes can be detected by polling
+func (r *certReloader) certFilesSnapshot() string {
+	snapshot := ""
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return ""
+		}
+		snapshot += fmt.Sprintf("%s|%d|%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return snapshot
+}
+
+// watch reloads the certificate whenever its files change, until ctx is
+// cancelled. A certificate that fails to load is logged and the previous one
+// stays in use; a key written just before its certificate is picked up on
+// the next change.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	last := r.certFilesSnapshot()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := r.certFilesSnapshot()
+			if current == "" || current == last {
+				continue
+			}
+			if err := r.load(); err != nil {
+				log.Printf("Error reloading TLS certificate, keeping the previous one: %v", err)
+				continue
+			}
+			last = current
+			log.Printf("TLS certificate %s changed, reloaded", r.certFile)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// collectionPool tracks how many copies of each printing are still available
+// while a decklist is matched against it, grouped by oracle ID so that any
+// printing of a card can be used.
+type collectionPool struct {
+	byPrinting map[string]int
+	printings  map[string][]string
+}
+
+func newCollectionPool(entries []DeckEntry, resolved map[int]scryfall.Card) *collectionPool {
+	pool := &collectionPool{
+		byPrinting: map[string]int{},
+		printings:  map[string][]string{},
+	}
+	for i, entry := range entries {
+		card, ok := resolved[i]
+		if !ok {
+			continue
+		}
+		if _, seen := pool.byPrinting[card.ID]; !seen {
+			pool.printings[card.OracleID] = append(pool.printings[card.OracleID], card.ID)
+		}
+		pool.byPrinting[card.ID] += entry.Quantity
+	}
+	return pool
+}
+
+// takePrinting consumes up to n copies of an exact printing.
+func (p *collectionPool) takePrinting(card scryfall.Card, n int) int {
+	taken := min(n, p.byPrinting[card.ID])
+	p.byPrinting[card.ID] -= taken
+	return taken
+}
+
+// takeAny consumes up to n copies of any printing of the card.
+func (p *collectionPool) takeAny(card scryfall.Card, n int) int {
+	taken := 0
+	for _, id := range p.printings[card.OracleID] {
+		if taken == n {
+			break
+		}
+		use := min(n-taken, p.byPrinting[id])
+		p.byPrinting[id] -= use
+		taken += use
+	}
+	return taken
+}
+
+// diffDeckAgainstCollection matches decklist entries against a collection.
+// Entries naming a specific printing are matched first so that a generic
+// entry for the same card cannot use up the copy the specific one needs.
+func diffDeckAgainstCollection(deck []DeckEntry, deckCards map[int]scryfall.Card, collection []DeckEntry, collectionCards map[int]scryfall.Card, allowAlternates bool) DeckCollectionDiffResult {
+	pool := newCollectionPool(collection, collectionCards)
+	owned := make([]int, len(deck))
+
+	for pass := 0; pass < 2; pass++ {
+		for i, entry := range deck {
+			card, ok := deckCards[i]
+			if !ok {
+				continue
+			}
+			specific := entry.Set != ""
+			if pass == 0 && specific {
+				owned[i] += pool.takePrinting(card, entry.Quantity)
+			}
+			if pass == 1 && (!specific || allowAlternates) {
+				owned[i] += pool.takeAny(card, entry.Quantity-owned[i])
+			}
+		}
+	}
+
+	result := DeckCollectionDiffResult{
+		Owned:         []DeckCardStatus{},
+		Missing:       []DeckCardStatus{},
+		NotFound:      []string{},
+		UnpricedCards: []string{},
+	}
+
+	for i, entry := range deck {
+		card, ok := deckCards[i]
+		if !ok {
+			continue
+		}
+
+		status := DeckCardStatus{
+			Name:            card.Name,
+			Set:             entry.Set,
+			CollectorNumber: entry.CollectorNumber,
+			Section:         entry.Section,
+			Needed:          entry.Quantity,
+			Owned:           owned[i],
+			Missing:         entry.Quantity - owned[i],
+		}
+		if price, ok := cardPriceUSD(card); ok {
+			status.UnitPriceUSD = price
+			status.MissingCostUSD = roundCents(price * float64(status.Missing))
+		}
+
+		result.TotalCards += status.Needed
+		result.OwnedCount += status.Owned
+		result.MissingCount += status.Missing
+
+		if status.Missing == 0 {
+			result.Owned = append(result.Owned, status)
+			continue
+		}
+
+		result.Missing = append(result.Missing, status)
+		result.MissingCostUSD += status.MissingCostUSD
+		if status.UnitPriceUSD == 0 && !contains(result.UnpricedCards, card.Name) {
+			result.UnpricedCards = append(result.UnpricedCards, card.Name)
+		}
+	}
+
+	result.MissingCostUSD = roundCents(result.MissingCostUSD)
+	return result
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Sources of a combo match
+const (
+	ComboSourceDataset   = "dataset"
+	ComboSourceHeuristic = "heuristic"
+)
+
+// comboDataset is the format of res/combos.json and of MCP_COMBO_FILE
+type comboDataset struct {
+	Version string  `json:"version"`
+	Combos  []Combo `json:"combos"`
+}
+
+var (
+	combosMu    sync.RWMutex
+	combosCache *comboDataset
+)
+
+func decodeComboDataset(data []byte) (comboDataset, error) {
+	var dataset comboDataset
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&dataset); err != nil {
+		return comboDataset{}, err
+	}
+	return dataset, nil
+}
+
+// validateComboDataset checks that every combo has an ID, two or three
+// distinct pieces and at least one result, and that no ID is used twice.
+func validateComboDataset(dataset comboDataset) error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, combo := range dataset.Combos {
+		label := combo.ID
+		if label == "" {
+			label = fmt.Sprintf("#%d", i)
+			errs = append(errs, fmt.Errorf("combo %s: missing id", label))
+		} else if seen[combo.ID] {
+			errs = append(errs, fmt.Errorf("combo %s: duplicate id", label))
+		}
+		seen[combo.ID] = true
+
+		if len(combo.Cards) < 2 || len(combo.Cards) > 3 {
+			errs = append(errs, fmt.Errorf("combo %s: must have two or three cards, has %d", label, len(combo.Cards)))
+		}
+		names := map[string]bool{}
+		for _, name := range combo.Cards {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key == "" {
+				errs = append(errs, fmt.Errorf("combo %s: empty card name", label))
+			} else if names[key] {
+				errs = append(errs, fmt.Errorf("combo %s: card '%s' listed twice", label, name))
+			}
+			names[key] = true
+		}
+		if len(combo.Results) == 0 {
+			errs = append(errs, fmt.Errorf("combo %s: missing results", label))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func loadEmbeddedComboDataset() (comboDataset, error) {
+	data, err := embeddedResources.ReadFile("res/combos.json")
+	if err != nil {
+		return comboDataset{}, err
+	}
+	return decodeComboDataset(data)
+}
+
+// loadComboDataset returns the combo dataset, loading the embedded one on
+// first use
+func loadComboDataset() comboDataset {
+	combosMu.RLock()
+	if combosCache != nil {
+		defer combosMu.RUnlock()
+		return *combosCache
+	}
+	combosMu.RUnlock()
+
+	dataset, err := loadEmbeddedComboDataset()
+	if err != nil {
+		log.Printf("Error loading combo dataset: %v", err)
+		return comboDataset{Combos: []Combo{}}
+	}
+
+	combosMu.Lock()
+	defer combosMu.Unlock()
+	if combosCache == nil {
+		combosCache = &dataset
+	}
+	return *combosCache
+}
+
+// validateEmbeddedComboDataset checks res/combos.json at startup so that a
+// broken dataset is caught before any tool is called
+func validateEmbeddedComboDataset() error {
+	dataset, err := loadEmbeddedComboDataset()
+	if err != nil {
+		return fmt.Errorf("res/combos.json: %w", err)
+	}
+	if err := validateComboDataset(dataset); err != nil {
+		return fmt.Errorf("res/combos.json:\n%w", err)
+	}
+	return nil
+}
+
+// loadComboFile merges the combos in path into the embedded dataset. A combo
+// with the same ID as an embedded one replaces it, and the file's version is
+// reported alongside the embedded one.
+func loadComboFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	extra, err := decodeComboDataset(data)
+	if err != nil {
+		return err
+	}
+	if err := validateComboDataset(extra); err != nil {
+		return err
+	}
+
+	dataset, err := loadEmbeddedComboDataset()
+	if err != nil {
+		return err
+	}
+	index := map[string]int{}
+	for i, combo := range dataset.Combos {
+		index[combo.ID] = i
+	}
+	for _, combo := range extra.Combos {
+		if i, ok := index[combo.ID]; ok {
+			dataset.Combos[i] = combo
+			continue
+		}
+		dataset.Combos = append(dataset.Combos, combo)
+	}
+	if extra.Version != "" {
+		dataset.Version = fmt.Sprintf("%s+%s", dataset.Version, extra.Version)
+	}
+
+	combosMu.Lock()
+	combosCache = &dataset
+	combosMu.Unlock()
+
+	log.Printf("Loaded %d combos from %s", len(extra.Combos), path)
+	return nil
+}
+
+// setupComboFile loads the configured combo file, if any
+func setupComboFile(config *Config) {
+	if config.ComboFile == "" {
+		return
+	}
+	if err := loadComboFile(config.ComboFile); err != nil {
+		log.Printf("Error loading combo file %s: %v", config.ComboFile, err)
+	}
+}
+
+// comboNameKey normalizes a card name for matching combo pieces, using the
+// front face of multi-faced cards
+func comboNameKey(name string) string {
+	front, _, _ := strings.Cut(name, " // ")
+	return strings.ToLower(strings.TrimSpace(front))
+}
+
+// comboCardName finds the dataset's name for a partial or differently cased
+// card name: an exact match, else the only name starting with it, else the
+// only name containing it
+func comboCardName(dataset comboDataset, input string) (string, bool) {
+	key := comboNameKey(input)
+	if key == "" {
+		return "", false
+	}
+	prefixed, containing := map[string]string{}, map[string]string{}
+	for _, combo := range dataset.Combos {
+		for _, piece := range combo.Cards {
+			pieceKey := comboNameKey(piece)
+			switch {
+			case pieceKey == key:
+				return piece, true
+			case strings.HasPrefix(pieceKey, key):
+				prefixed[pieceKey] = piece
+			case strings.Contains(pieceKey, key):
+				containing[pieceKey] = piece
+			}
+		}
+	}
+	for _, matches := range []map[string]string{prefixed, containing} {
+		if len(matches) == 1 {
+			for _, name := range matches {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveComboCard finds the card find_combos was asked about, so that a
+// partial or differently cased name matches the dataset's full names. It uses
+// Scryfall's fuzzy name lookup, or without Scryfall the offline card data and
+// then the dataset's own names. The card has rules text, for loop detection,
+// unless it only came from the dataset.
+func resolveComboCard(ctx context.Context, client *scryfall.Client, dataset comboDataset, name string) (scryfall.Card, bool, error) {
+	if client != nil {
+		card, err := client.GetCardByName(ctx, name, false, scryfall.GetCardByNameOptions{})
+		if err == nil {
+			return card, true, nil
+		}
+		var scryfallErr *scryfall.Error
+		if errors.As(err, &scryfallErr) && scryfallErr.Status == 404 {
+			return scryfall.Card{}, false, fmt.Errorf("card '%s' not found: %s", name, scryfallErr.Details)
+		}
+		log.Printf("Error looking up combo card '%s', using offline data: %v", name, err)
+	}
+
+	if card, ok := offlineCardByName(name); ok {
+		return card, true, nil
+	}
+	if canonical, ok := comboCardName(dataset, name); ok {
+		return scryfall.Card{Name: canonical}, false, nil
+	}
+	return scryfall.Card{}, false, fmt.Errorf("card '%s' could not be looked up without Scryfall", name)
+}
+
+// comboHasCard reports whether the combo uses the named card
+func comboHasCard(combo Combo, name string) bool {
+	key := comboNameKey(name)
+	for _, piece := range combo.Cards {
+		if comboNameKey(piece) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCombo checks a combo against a deck. When deck is nil the combo is
+// returned without deck information.
+func matchCombo(combo Combo, source string, deck map[string]bool) ComboMatch {
+	match := ComboMatch{Combo: combo, Source: source}
+	if deck == nil {
+		return match
+	}
+	for _, piece := range combo.Cards {
+		if !deck[comboNameKey(piece)] {
+			match.MissingPieces = append(match.MissingPieces, piece)
+		}
+	}
+	match.InDeck = len(match.MissingPieces) == 0
+	return match
+}
+
+// findDatasetCombos returns the known combos for a card, or for a deck when
+// cardName is empty. Deck searches return combos that are complete or
+// missing a single piece.
+func findDatasetCombos(dataset comboDataset, cardName string, deck map[string]bool) []ComboMatch {
+	matches := []ComboMatch{}
+	for _, combo := range dataset.Combos {
+		if cardName != "" {
+			if comboHasCard(combo, cardName) {
+				matches = append(matches, matchCombo(combo, ComboSourceDataset, deck))
+			}
+			continue
+		}
+		match := matchCombo(combo, ComboSourceDataset, deck)
+		if len(match.MissingPieces) <= 1 {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// sortComboMatches puts complete combos first, then those missing the fewest
+// pieces, keeping dataset combos ahead of heuristic ones
+func sortComboMatches(matches []ComboMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if len(a.MissingPieces) != len(b.MissingPieces) {
+			return len(a.MissingPieces) < len(b.MissingPieces)
+		}
+		return a.Source == ComboSourceDataset && b.Source != ComboSourceDataset
+	})
+}
+
+// deckNameSet returns the normalized names of every card in a decklist
+func deckNameSet(entries []DeckEntry) map[string]bool {
+	names := map[string]bool{}
+	for _, entry := range entries {
+		names[comboNameKey(entry.Name)] = true
+	}
+	return names
+}
+
+// sameComboCards reports whether two combos use the same pieces
+func sameComboCards(a, b Combo) bool {
+	if len(a.Cards) != len(b.Cards) {
+		return false
+	}
+	for _, piece := range a.Cards {
+		if !comboHasCard(b, piece) {
+			return false
+		}
+	}
+	return true
+}
+
+// addHeuristicCombos appends detected loops that are not already known
+func addHeuristicCombos(matches []ComboMatch, loops []Combo, deck map[string]bool) []ComboMatch {
+	for _, loop := range loops {
+		known := false
+		for _, match := range matches {
+			if match.ID == loop.ID || sameComboCards(match.Combo, loop) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			matches = append(matches, matchCombo(loop, ComboSourceHeuristic, deck))
+		}
+	}
+	return matches
+}
+
+func limitComboMatches(matches []ComboMatch, max int) []ComboMatch {
+	if max <= 0 || max > len(matches) {
+		return matches
+	}
+	return matches[:max]
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Weights of the signals that make up a commander score
+const (
+	commanderThemeWeight       = 3.0
+	commanderPayoffWeight      = 1.5
+	commanderExactColorsWeight = 2.0
+	commanderSeedWeight        = 3.0
+)
+
+// Number of themes taken from seed cards when no theme is given
+const maxSeedThemes = 3
+
+// Names of colors and of two-, three-, four- and five-color combinations
+var colorCombinationNames = map[string]string{
+	"azorius": "WU", "dimir": "UB", "rakdos": "BR", "gruul": "RG", "selesnya": "GW",
+	"orzhov": "WB", "izzet": "UR", "golgari": "BG", "boros": "RW", "simic": "GU",
+	"bant": "GWU", "esper": "WUB", "grixis": "UBR", "jund": "BRG", "naya": "RGW",
+	"abzan": "WBG", "jeskai": "URW", "sultai": "BGU", "mardu": "RWB", "temur": "GUR",
+	"glint": "UBRG", "dune": "WBRG", "ink": "WURG", "witch": "WUBG", "yore": "WUBR",
+	"white": "W", "blue": "U", "black": "B", "red": "R", "green": "G",
+	"colorless": "C", "five-color": "WUBRG", "five color": "WUBRG", "rainbow": "WUBRG",
+}
+
+// parseColorIdentity turns WUBRG letters, color names or the name of a color
+// combination such as "Selesnya" into a color identity in WUBRG order. "C"
+// stands for colorless.
+func parseColorIdentity(input string) (string, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return "", nil
+	}
+
+	letters := ""
+	for _, word := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '/' || r == '+' }) {
+		word = strings.TrimSpace(word)
+		if colors, ok := colorCombinationNames[word]; ok {
+			letters += colors
+			continue
+		}
+		for _, part := range strings.Fields(word) {
+			if colors, ok := colorCombinationNames[part]; ok {
+				letters += colors
+				continue
+			}
+			if strings.Trim(part, "wubrgc") != "" {
+				return "", fmt.Errorf("unknown color '%s'; use WUBRG letters, color names or names such as 'Selesnya'", part)
+			}
+			letters += strings.ToUpper(part)
+		}
+	}
+
+	identity := ""
+	for _, color := range "WUBRG" {
+		if strings.ContainsRune(letters, color) {
+			identity += string(color)
+		}
+	}
+	if identity == "" {
+		return "C", nil
+	}
+	return identity, nil
+}
+
+// themeSearchQuery combines a theme's queries into one Scryfall search, or
+// searches its patterns as regular expressions when it has no queries
+func themeSearchQuery(pattern ThemePattern) string {
+	parts := []string{}
+	for _, query := range []string{pattern.SynergyQuery, pattern.PayoffQuery, pattern.EnablerQuery} {
+		if query != "" && !contains(parts, query) {
+			parts = append(parts, query)
+		}
+	}
+	if len(parts) == 0 {
+		for _, p := range pattern.allPatterns() {
+			if p != "" {
+				parts = append(parts, fmt.Sprintf("oracle:/%s/", p))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// seedThemes returns the themes shared by the most seed cards, ignoring
+// keywords that are not themes of their own
+func seedThemes(seeds []scryfall.Card) []string {
+	patterns := loadThemePatterns()
+	counts := map[string]int{}
+	for _, seed := range seeds {
+		for _, theme := range extractThemesFromCard(seed) {
+			if _, ok := patterns[theme]; ok {
+				counts[theme]++
+			}
+		}
+	}
+
+	themes := sortedKeys(counts)
+	sort.SliceStable(themes, func(i, j int) bool { return counts[themes[i]] > counts[themes[j]] })
+	if len(themes) > maxSeedThemes {
+		themes = themes[:maxSeedThemes]
+	}
+	return themes
+}
+
+// commanderSearchQueries builds one search per theme, limited to legal
+// commanders within the color identity. Without themes a single search for
+// the most played commanders is returned.
+func commanderSearchQueries(themes []string, identity string, seedIdentity string) []string {
+	base := "is:commander legal:commander"
+	if identity != "" {
+		base += " id<=" + identity
+	}
+	if seedIdentity != "" && seedIdentity != "C" {
+		base += " id>=" + seedIdentity
+	}
+
+	patterns := loadThemePatterns()
+	queries := []string{}
+	for _, theme := range themes {
+		if query := themeSearchQuery(patterns[theme]); query != "" {
+			queries = append(queries, base+" "+query)
+		}
+	}
+	if len(queries) == 0 {
+		queries = append(queries, base)
+	}
+	return queries
+}
+
+// findCommanderCandidates runs the commander searches and merges the results
+func findCommanderCandidates(ctx context.Context, client *scryfall.Client, queries []string) []scryfall.Card {
+	opts := scryfall.SearchCardsOptions{
+		Unique: scryfall.UniqueModeCards,
+		Order:  scryfall.OrderEDHREC,
+	}
+
+	candidates := []scryfall.Card{}
+	seen := map[string]bool{}
+	for _, query := range queries {
+		log.Printf("Searching for commanders (Query: %s)", query)
+		result, err := client.SearchCards(ctx, query, opts)
+		if err != nil {
+			log.Printf("Error searching for commanders: %v", err)
+			continue
+		}
+		for _, card := range result.Cards {
+			if seen[cardKey(card)] || card.Legalities.Commander != scryfall.LegalityLegal {
+				continue
+			}
+			seen[cardKey(card)] = true
+			candidates = append(candidates, card)
+		}
+	}
+	return candidates
+}
+
+// scoreCommander scores a commander against the requested themes, colors and
+// seed cards. focusTheme, when set, counts double.
+func scoreCommander(candidate scryfall.Card, themes []string, focusTheme, identity string, seeds []scryfall.Card) CommanderSuggestion {
+	suggestion := CommanderSuggestion{
+		Card:          candidate,
+		MatchedThemes: []string{},
+		Signals:       []SynergySignal{},
+	}
+	addSignal := func(signal string, weight float64, detail string) {
+		suggestion.Signals = append(suggestion.Signals, SynergySignal{Signal: signal, Weight: weight, Detail: detail})
+		suggestion.Score += weight
+	}
+
+	candidateThemes := extractThemesFromCard(candidate)
+	roles := themeRoles(candidate)
+	for _, theme := range themes {
+		if !contains(candidateThemes, theme) {
+			continue
+		}
+		suggestion.MatchedThemes = append(suggestion.MatchedThemes, theme)
+		weight := commanderThemeWeight
+		if theme == focusTheme {
+			weight *= 2
+		}
+		addSignal("theme", weight, fmt.Sprintf("Fits the %s theme", theme))
+		if role := roles[theme]; role == ThemeRolePayoff || role == ThemeRoleBoth {
+			addSignal("theme_payoff", commanderPayoffWeight, fmt.Sprintf("Pays off the %s theme", theme))
+		}
+	}
+
+	if candidateIdentity, _ := parseColorIdentity(colorIdentityString(candidate)); identity != "" && candidateIdentity == identity {
+		addSignal("exact_colors", commanderExactColorsWeight, fmt.Sprintf("Color identity is exactly %s", identity))
+	}
+
+	if len(seeds) > 0 {
+		for _, seed := range seeds {
+			if colorIdentityWithin(seed.ColorIdentity, candidate.ColorIdentity) {
+				suggestion.SeedCardsInIdentity++
+			}
+		}
+		fraction := float64(suggestion.SeedCardsInIdentity) / float64(len(seeds))
+		addSignal("seed_cards", roundCents(commanderSeedWeight*fraction), fmt.Sprintf("Can play %d of %d seed cards", suggestion.SeedCardsInIdentity, len(seeds)))
+	}
+
+	if weight, ok := popularityBonus(candidate); ok {
+		addSignal("popularity", weight, fmt.Sprintf("EDHREC rank %d", *candidate.EDHRECRank))
+	}
+
+	suggestion.Score = roundCents(suggestion.Score)
+	explanations := []string{}
+	for _, signal := range suggestion.Signals {
+		explanations = append(explanations, signal.Detail)
+	}
+	suggestion.Explanation = strings.Join(explanations, "; ")
+	return suggestion
+}
+
+// rankCommanders scores every candidate and returns the best ones. When
+// themes were requested, commanders that fit none of them are dropped.
+func rankCommanders(candidates []scryfall.Card, themes []string, focusTheme, identity string, seeds []scryfall.Card, maxResults int) []CommanderSuggestion {
+	ranked := []CommanderSuggestion{}
+	for _, candidate := range candidates {
+		suggestion := scoreCommander(candidate, themes, focusTheme, identity, seeds)
+		if len(themes) > 0 && len(suggestion.MatchedThemes) == 0 {
+			continue
+		}
+		ranked = append(ranked, suggestion)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	if maxResults > 0 && len(ranked) > maxResults {
+		ranked = ranked[:maxResults]
+	}
+	return ranked
+}
+
+// seedColorIdentity returns the combined color identity of the seed cards
+func seedColorIdentity(seeds []scryfall.Card) string {
+	letters := ""
+	for _, seed := range seeds {
+		letters += colorIdentityString(seed)
+	}
+	identity, _ := parseColorIdentity(letters)
+	return identity
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Deck slot roles, in the order they are filled
+const (
+	DeckRoleRamp     = "ramp"
+	DeckRoleDraw     = "draw"
+	DeckRoleRemoval  = "removal"
+	DeckRoleWipes    = "wipes"
+	DeckRoleSynergy  = "synergy"
+	DeckRoleLands    = "lands"
+	commanderDeckLen = 99
+)
+
+// roleQuotas is the number of slots each role aims for in a 99-card deck.
+// Lands include basics, which fill whatever the other roles leave open.
+var roleQuotas = []struct {
+	role  string
+	quota int
+	query string
+}{
+	{DeckRoleRamp, 10, `(otag:ramp OR o:"search your library for a basic land") -t:land`},
+	{DeckRoleDraw, 10, `(otag:draw OR o:"draw two cards" OR o:"draw a card for each") -t:land`},
+	{DeckRoleRemoval, 8, `(otag:removal OR o:"destroy target" OR o:"exile target") -otag:sweeper -t:land`},
+	{DeckRoleWipes, 3, `(otag:sweeper OR o:"destroy all" OR o:"exile all") -t:land`},
+	{DeckRoleSynergy, 32, ""},
+	{DeckRoleLands, 36, `t:land -t:basic`},
+}
+
+// Nonbasic lands allowed per color in the commander's identity; the rest of
+// the land quota is basics
+const nonbasicLandsPerColor = 5
+
+// Weights used to order candidates within a role
+const (
+	ownedCardWeight    = 1.0
+	highManaValue      = 6
+	highManaPenalty    = -1.0
+	seedJitterWeight   = 0.5
+	synergyScoreWeight = 0.5
+)
+
+var basicLandNames = map[string]string{
+	"W": "Plains", "U": "Island", "B": "Swamp", "R": "Mountain", "G": "Forest", "C": "Wastes",
+}
+
+// deckBuilder accumulates the slots of a deck while enforcing singleton,
+// color identity, legality, budget and collection constraints.
+type deckBuilder struct {
+	commander      scryfall.Card
+	rng            *rand.Rand
+	owned          map[string]bool
+	collectionOnly bool
+	maxCardPrice   float64
+	budget         float64
+	used           map[string]bool
+	slots          []DeckSlot
+	notes          []string
+}
+
+func newDeckBuilder(commander scryfall.Card, seed int64, collection []DeckEntry, collectionOnly bool, maxCardPrice, budget float64) *deckBuilder {
+	owned := map[string]bool{}
+	for _, entry := range collection {
+		owned[comboNameKey(entry.Name)] = true
+	}
+	return &deckBuilder{
+		commander:      commander,
+		rng:            newSeededRand(seed),
+		owned:          owned,
+		collectionOnly: collectionOnly,
+		maxCardPrice:   maxCardPrice,
+		budget:         budget,
+		used:           map[string]bool{comboNameKey(commander.Name): true},
+	}
+}
+
+// deckCandidate is a card considered for a role with the reasons it ranks
+// where it does
+type deckCandidate struct {
+	card    scryfall.Card
+	score   float64
+	reasons []string
+}
+
+// allows reports whether a card may still go in the deck
+func (b *deckBuilder) allows(card scryfall.Card) bool {
+	if b.used[comboNameKey(card.Name)] {
+		return false
+	}
+	if card.Legalities.Commander != scryfall.LegalityLegal {
+		return false
+	}
+	if !colorIdentityWithin(card.ColorIdentity, b.commander.ColorIdentity) {
+		return false
+	}
+	if b.collectionOnly && !b.owned[comboNameKey(card.Name)] {
+		return false
+	}
+	price, priced := cardPriceUSD(card)
+	if b.maxCardPrice > 0 && priced && price > b.maxCardPrice {
+		return false
+	}
+	return true
+}
+
+// rankCandidates orders candidates by popularity, ownership, mana value and
+// any extra score, with a small seeded jitter so that different seeds give
+// different but reproducible decks.
+func (b *deckBuilder) rankCandidates(cards []scryfall.Card, extra map[string]float64, extraReason map[string]string) []deckCandidate {
+	candidates := []deckCandidate{}
+	for _, card := range cards {
+		if !b.allows(card) {
+			continue
+		}
+		candidate := deckCandidate{card: card}
+		if weight, ok := popularityBonus(card); ok {
+			candidate.score += weight
+			candidate.reasons = append(candidate.reasons, fmt.Sprintf("EDHREC rank %d", *card.EDHRECRank))
+		}
+		if b.owned[comboNameKey(card.Name)] {
+			candidate.score += ownedCardWeight
+			candidate.reasons = append(candidate.reasons, "in your collection")
+		}
+		if card.CMC >= highManaValue && !strings.Contains(card.TypeLine, "Land") {
+			candidate.score += highManaPenalty
+		}
+		if weight, ok := extra[cardKey(card)]; ok {
+			candidate.score += weight * synergyScoreWeight
+			candidate.reasons = append(candidate.reasons, extraReason[cardKey(card)])
+		}
+		candidate.score += b.rng.Float64() * seedJitterWeight
+		candidates = append(candidates, candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return candidates
+}
+
+// fill adds up to quota candidates to a role, spending at most roleBudget
+// when a total budget is set, and returns how many were added and spent.
+func (b *deckBuilder) fill(role string, candidates []deckCandidate, quota int, roleBudget float64) (int, float64) {
+	added, spent := 0, 0.0
+	for _, candidate := range candidates {
+		if added == quota {
+			break
+		}
+		if !b.allows(candidate.card) {
+			continue
+		}
+		price, _ := cardPriceUSD(candidate.card)
+		if b.budget > 0 && spent+price > roleBudget {
+			continue
+		}
+
+		reason := fmt.Sprintf("%s for %s", roleDescriptions[role], b.commander.Name)
+		if len(candidate.reasons) > 0 {
+			reason += ": " + strings.Join(candidate.reasons, "; ")
+		}
+		b.addSlot(DeckSlot{
+			Role:      role,
+			Name:      candidate.card.Name,
+			Quantity:  1,
+			ManaValue: candidate.card.CMC,
+			ManaCost:  cardManaCost(candidate.card),
+			TypeLine:  candidate.card.TypeLine,
+			PriceUSD:  price,
+			Owned:     b.owned[comboNameKey(candidate.card.Name)],
+			Reason:    reason,
+		})
+		added++
+		spent += price
+	}
+	return added, spent
+}
+
+func (b *deckBuilder) addSlot(slot DeckSlot) {
+	b.used[comboNameKey(slot.Name)] = true
+	b.slots = append(b.slots, slot)
+}
+
+func (b *deckBuilder) cardCount() int {
+	count := 0
+	for _, slot := range b.slots {
+		count += slot.Quantity
+	}
+	return count
+}
+
+var roleDescriptions = map[string]string{
+	DeckRoleRamp:    "Ramp",
+	DeckRoleDraw:    "Card draw",
+	DeckRoleRemoval: "Targeted removal",
+	DeckRoleWipes:   "Board wipe",
+	DeckRoleSynergy: "Synergy",
+	DeckRoleLands:   "Land",
+}
+
+// splitBasicLands divides count basic lands among colors in proportion to
+// the colored mana symbols in costs, or evenly when there are none. It also
+// returns the symbols counted for each color and in total.
+func splitBasicLands(costs []string, colors []string, count int) (map[string]int, map[string]int, int) {
+	pips := map[string]int{}
+	total := 0
+	for _, cost := range costs {
+		for _, symbol := range manaSymbolRegex.FindAllString(cost, -1) {
+			for _, color := range colors {
+				if strings.Contains(symbol, color) {
+					pips[color]++
+					total++
+				}
+			}
+		}
+	}
+
+	counts := map[string]int{}
+	assigned := 0
+	for _, color := range colors {
+		share := count / len(colors)
+		if total > 0 {
+			share = count * pips[color] / total
+		}
+		counts[color] = share
+		assigned += share
+	}
+	// Hand out what rounding left over in WUBRG order
+	for i := 0; assigned < count; i++ {
+		counts[colors[i%len(colors)]]++
+		assigned++
+	}
+	return counts, pips, total
+}
+
+// addBasicLands fills the remaining slots with basics, split by how many
+// mana symbols of each color the commander and the chosen spells use.
+func (b *deckBuilder) addBasicLands() {
+	remaining := commanderDeckLen - b.cardCount()
+	if remaining <= 0 {
+		return
+	}
+
+	colors := []string{}
+	for _, color := range "WUBRG" {
+		if strings.Contains(colorIdentityString(b.commander), string(color)) {
+			colors = append(colors, string(color))
+		}
+	}
+	if len(colors) == 0 {
+		colors = []string{"C"}
+	}
+
+	costs := []string{cardManaCost(b.commander)}
+	for _, slot := range b.slots {
+		costs = append(costs, slot.ManaCost)
+	}
+	counts, pips, total := splitBasicLands(costs, colors, remaining)
+
+	for _, color := range colors {
+		if counts[color] == 0 {
+			continue
+		}
+		reason := "Basic land; split evenly across the commander's colors"
+		if total > 0 {
+			reason = fmt.Sprintf("Basic land; %d of %d colored mana symbols among the commander and spells are this color", pips[color], total)
+		}
+		b.slots = append(b.slots, DeckSlot{
+			Role:     DeckRoleLands,
+			Name:     basicLandNames[color],
+			Quantity: counts[color],
+			TypeLine: "Basic Land",
+			Reason:   reason,
+		})
+	}
+}
+
+// cardManaCost returns a card's mana cost, joining the faces of multi-faced
+// cards
+func cardManaCost(card scryfall.Card) string {
+	if card.ManaCost != "" || len(card.CardFaces) == 0 {
+		return card.ManaCost
+	}
+	costs := []string{}
+	for _, face := range card.CardFaces {
+		if face.ManaCost != "" {
+			costs = append(costs, face.ManaCost)
+		}
+	}
+	return strings.Join(costs, " // ")
+}
+
+// nonbasicLandQuota returns how many nonbasic lands the deck should run
+func nonbasicLandQuota(commander scryfall.Card, landQuota int) int {
+	colors := len(commander.ColorIdentity)
+	if colors == 0 {
+		colors = 1
+	}
+	return min(landQuota, colors*nonbasicLandsPerColor)
+}
+
+// build fills every role from Scryfall searches within the commander's color
+// identity, then pads the deck to 99 cards with basic lands. Searches are
+// ordered by EDHREC rank so that, with the seed fixing the jitter, the same
+// inputs always produce the same deck.
+func (b *deckBuilder) build(ctx context.Context, client *scryfall.Client, theme string) {
+	base := fmt.Sprintf(`id<=%s legal:commander -name:"%s"`, colorIdentityString(b.commander), b.commander.Name)
+	opts := scryfall.SearchCardsOptions{
+		Unique: scryfall.UniqueModeCards,
+		Order:  scryfall.OrderEDHREC,
+	}
+
+	budgetedSlots := 0
+	for _, role := range roleQuotas {
+		if role.role == DeckRoleLands {
+			budgetedSlots += nonbasicLandQuota(b.commander, role.quota)
+		} else {
+			budgetedSlots += role.quota
+		}
+	}
+
+	carrySlots, carryBudget := 0, 0.0
+	for _, role := range roleQuotas {
+		quota := role.quota
+		if role.role == DeckRoleLands {
+			quota = nonbasicLandQuota(b.commander, role.quota)
+		} else {
+			quota += carrySlots
+			carrySlots = 0
+		}
+		roleBudget := carryBudget
+		if b.budget > 0 {
+			roleBudget += b.budget * float64(quota) / float64(budgetedSlots)
+		}
+
+		var candidates []deckCandidate
+		if role.role == DeckRoleSynergy {
+			candidates = b.synergyCandidates(ctx, client, opts, theme)
+		} else {
+			query := base + " " + role.query
+			log.Printf("Searching for %s cards (Query: %s)", role.role, query)
+			result, err := client.SearchCards(ctx, query, opts)
+			if err != nil {
+				log.Printf("Error searching for %s cards: %v", role.role, err)
+				b.notes = append(b.notes, fmt.Sprintf("Search for %s cards failed: %v", role.role, err))
+			} else {
+				candidates = b.rankCandidates(result.Cards, nil, nil)
+			}
+		}
+
+		added, spent := b.fill(role.role, candidates, quota, roleBudget)
+		if b.budget > 0 {
+			carryBudget = roleBudget - spent
+		}
+		if added < quota {
+			if role.role == DeckRoleLands {
+				b.notes = append(b.notes, fmt.Sprintf("Found %d of %d nonbasic lands; the rest are basics", added, quota))
+			} else {
+				b.notes = append(b.notes, fmt.Sprintf("Found %d of %d %s cards; the remaining slots go to the next role", added, quota, role.role))
+				carrySlots = quota - added
+			}
+		}
+	}
+	if carrySlots > 0 {
+		b.notes = append(b.notes, fmt.Sprintf("%d unfilled slots were filled with basic lands", carrySlots))
+	}
+
+	b.addBasicLands()
+}
+
+// synergyCandidates runs the find_card_synergies searches for the commander
+// and ranks the results by their synergy score
+func (b *deckBuilder) synergyCandidates(ctx context.Context, client *scryfall.Client, opts scryfall.SearchCardsOptions, theme string) []deckCandidate {
+	searchThemes := extractThemesFromCard(b.commander)
+	if theme != "" {
+		searchThemes = []string{theme}
+	}
+
+	keywords, themes := synergySearches(b.commander, searchThemes)
+	synergies := []SynergyCategory{}
+	synergies = findKeywordSynergies(ctx, client, b.commander, opts, keywords, synergies)
+	synergies = findThemeSynergies(ctx, client, b.commander, opts, themes, synergies)
+	synergies = findColorIdentitySynergies(ctx, client, b.commander, opts, synergies)
+
+	profile := newSynergyProfile(b.commander, searchThemes, theme)
+	_, ranked := rankSynergies(profile, synergies, 0)
+
+	cards := make([]scryfall.Card, 0, len(ranked))
+	scores := map[string]float64{}
+	reasons := map[string]string{}
+	for _, scored := range ranked {
+		cards = append(cards, scored.Card)
+		scores[cardKey(scored.Card)] = scored.Score
+		reasons[cardKey(scored.Card)] = scored.Explanation
+	}
+	return b.rankCandidates(cards, scores, reasons)
+}
+
+// result summarizes the built deck
+func (b *deckBuilder) result(seed int64) BuildCommanderDeckResult {
+	result := BuildCommanderDeckResult{
+		Commander:     b.commander.Name,
+		ColorIdentity: colorIdentityString(b.commander),
+		Seed:          seed,
+		Slots:         b.slots,
+		RoleCounts:    map[string]int{},
+		CardCount:     b.cardCount(),
+		Notes:         b.notes,
+	}
+	if identity, err := parseColorIdentity(result.ColorIdentity); err == nil {
+		result.ColorIdentity = identity
+	}
+
+	lines := []string{"Commander", "1 " + b.commander.Name, "", "Deck"}
+	for _, slot := range b.slots {
+		result.RoleCounts[slot.Role] += slot.Quantity
+		result.TotalPriceUSD += slot.PriceUSD * float64(slot.Quantity)
+		lines = append(lines, fmt.Sprintf("%d %s", slot.Quantity, slot.Name))
+	}
+	result.TotalPriceUSD = roundCents(result.TotalPriceUSD)
+	result.Decklist = strings.Join(lines, "\n")
+	return result
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Limits on the number of cards compare_cards accepts
+const (
+	minCompareCards = 2
+	maxCompareCards = 6
+)
+
+// Operations in an oracle text diff
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// comparisonText returns a card's rules text for comparison: self-references
+// replaced with CARDNAME and reminder text dropped, one ability per line.
+func comparisonText(card scryfall.Card) string {
+	lines := []string{}
+	for _, ability := range parseCardAbilities(card) {
+		lines = append(lines, ability.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffTokens splits text into words, keeping line breaks as their own token
+func diffTokens(text string) []string {
+	tokens := []string{}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			tokens = append(tokens, "\n")
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+	return tokens
+}
+
+// wordDiff computes a word-level diff from a to b using the longest common
+// subsequence, merging runs of the same operation.
+func wordDiff(a, b string) []TextDiffOp {
+	from, to := diffTokens(a), diffTokens(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []TextDiffOp{}
+	emit := func(op, token string) {
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			if token == "\n" || strings.HasSuffix(ops[n-1].Text, "\n") {
+				ops[n-1].Text += token
+			} else {
+				ops[n-1].Text += " " + token
+			}
+			return
+		}
+		ops = append(ops, TextDiffOp{Op: op, Text: token})
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			emit(DiffEqual, from[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			emit(DiffDelete, from[i])
+			i++
+		default:
+			emit(DiffInsert, to[j])
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		emit(DiffDelete, from[i])
+	}
+	for ; j < len(to); j++ {
+		emit(DiffInsert, to[j])
+	}
+	return ops
+}
+
+// cardLegalities returns a card's legality in every format Scryfall reports
+func cardLegalities(card scryfall.Card) map[string]string {
+	legalities := map[string]string{}
+	data, err := json.Marshal(card.Legalities)
+	if err != nil {
+		return legalities
+	}
+	if err := json.Unmarshal(data, &legalities); err != nil {
+		log.Printf("Error reading legalities of %s: %v", card.Name, err)
+	}
+	return legalities
+}
+
+// countPrintings returns how many printings of a card exist
+func countPrintings(ctx context.Context, client *scryfall.Client, card scryfall.Card) (int, error) {
+	opts := scryfall.SearchCardsOptions{
+		Unique:            scryfall.UniqueModePrints,
+		IncludeExtras:     true,
+		IncludeVariations: true,
+	}
+	query := fmt.Sprintf(`!"%s"`, card.Name)
+	if card.OracleID != "" {
+		query = fmt.Sprintf("oracleid:%s", card.OracleID)
+	}
+	result, err := client.SearchCards(ctx, query, opts)
+	if err != nil {
+		return 0, err
+	}
+	return result.TotalCards, nil
+}
+
+// cardStat returns a stat such as power from the card, or from its first face
+// that has one for multi-faced cards
+func cardStat(card scryfall.Card, stat func(power, toughness, loyalty *string) *string) string {
+	if value := stat(card.Power, card.Toughness, card.Loyalty); value != nil {
+		return *value
+	}
+	for _, face := range card.CardFaces {
+		if value := stat(face.Power, face.Toughness, face.Loyalty); value != nil {
+			return *value
+		}
+	}
+	return ""
+}
+
+// newCardComparison collects the attributes compared for one card
+func newCardComparison(card scryfall.Card, printings int) CardComparison {
+	comparison := CardComparison{
+		Name:       card.Name,
+		ManaCost:   cardManaCost(card),
+		ManaValue:  card.CMC,
+		TypeLine:   card.TypeLine,
+		Power:      cardStat(card, func(p, _, _ *string) *string { return p }),
+		Toughness:  cardStat(card, func(_, t, _ *string) *string { return t }),
+		Loyalty:    cardStat(card, func(_, _, l *string) *string { return l }),
+		Keywords:   abilityKeywords(parseCardAbilities(card)),
+		Themes:     extractThemesFromCard(card),
+		Legalities: cardLegalities(card),
+		Rarity:     card.Rarity,
+		Printings:  printings,
+		OracleText: comparisonText(card),
+	}
+	if printings > 0 {
+		reprints := printings - 1
+		comparison.Reprints = &reprints
+	}
+	if price, ok := cardPriceUSD(card); ok {
+		comparison.PriceUSD = price
+	}
+	return comparison
+}
+
+// differingAttributes lists the attributes that are not the same on every card
+func differingAttributes(cards []CardComparison) []string {
+	attributes := []struct {
+		name  string
+		value func(CardComparison) string
+	}{
+		{"mana_cost", func(c CardComparison) string { return c.ManaCost }},
+		{"mana_value", func(c CardComparison) string { return fmt.Sprint(c.ManaValue) }},
+		{"type_line", func(c CardComparison) string { return c.TypeLine }},
+		{"power", func(c CardComparison) string { return c.Power }},
+		{"toughness", func(c CardComparison) string { return c.Toughness }},
+		{"loyalty", func(c CardComparison) string { return c.Loyalty }},
+		{"keywords", func(c CardComparison) string { return strings.Join(c.Keywords, ",") }},
+		{"themes", func(c CardComparison) string { return strings.Join(c.Themes, ",") }},
+		{"legalities", func(c CardComparison) string { return fmt.Sprint(c.Legalities) }},
+		{"price_usd", func(c CardComparison) string { return fmt.Sprint(c.PriceUSD) }},
+		{"rarity", func(c CardComparison) string { return c.Rarity }},
+		{"printings", func(c CardComparison) string { return fmt.Sprint(c.Printings) }},
+		{"oracle_text", func(c CardComparison) string { return c.OracleText }},
+	}
+
+	differing := []string{}
+	for _, attribute := range attributes {
+		first := attribute.value(cards[0])
+		for _, card := range cards[1:] {
+			if attribute.value(card) != first {
+				differing = append(differing, attribute.name)
+				break
+			}
+		}
+	}
+	return differing
+}
+
+// legalityDifferences lists the formats where the cards' legality differs
+func legalityDifferences(cards []CardComparison) []string {
+	formats := sortedKeys(cards[0].Legalities)
+	differing := []string{}
+	for _, format := range formats {
+		for _, card := range cards[1:] {
+			if card.Legalities[format] != cards[0].Legalities[format] {
+				differing = append(differing, format)
+				break
+			}
+		}
+	}
+	return differing
+}
+
+// oracleTextDiffs diffs the rules text of every card against the first card's
+func oracleTextDiffs(cards []CardComparison) []OracleTextDiff {
+	diffs := []OracleTextDiff{}
+	for _, card := range cards[1:] {
+		diffs = append(diffs, OracleTextDiff{
+			Base:    cards[0].Name,
+			Against: card.Name,
+			Ops:     wordDiff(cards[0].OracleText, card.OracleText),
+		})
+	}
+	return diffs
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Limits for completion/complete. MCP allows at most 100 values per response.
+const (
+	maxCompletionValues    = 100
+	cardCompletionTimeout  = 3 * time.Second
+	minOnlineCompletionLen = 2
+)
+
+// Argument names completed with card names and set codes, covering every
+// such prompt argument and resource template variable
+var (
+	cardNameArguments = []string{"card", "commander"}
+	setCodeArguments  = []string{"set", "code"}
+)
+
+// The local card name index answers card name completion when Scryfall can't
+// be reached. It holds the names in the combo dataset, in imported ratings,
+// in the offline card data and every name Scryfall's autocomplete has
+// returned, sorted by their lowercase form for prefix search.
+var (
+	cardNamesMu      sync.Mutex
+	cardNameIndex    []string
+	cardNamesLearned = map[string]string{}
+	cardNamesStale   = true
+)
+
+// markCardNamesStale rebuilds the card name index on its next use, after the
+// names it is built from have changed
+func markCardNamesStale() {
+	cardNamesMu.Lock()
+	cardNamesStale = true
+	cardNamesMu.Unlock()
+}
+
+// learnCardNames adds names returned by Scryfall to the card name index
+func learnCardNames(names []string) {
+	cardNamesMu.Lock()
+	defer cardNamesMu.Unlock()
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := cardNamesLearned[key]; !ok {
+			cardNamesLearned[key] = name
+			cardNamesStale = true
+		}
+	}
+}
+
+// localCardNames returns the card name index, rebuilding it when stale
+func localCardNames() []string {
+	cardNamesMu.Lock()
+	defer cardNamesMu.Unlock()
+	if !cardNamesStale {
+		return cardNameIndex
+	}
+
+	names := map[string]string{}
+	add := func(name string) {
+		if name = strings.TrimSpace(name); name != "" {
+			names[strings.ToLower(name)] = name
+		}
+	}
+	for _, combo := range loadComboDataset().Combos {
+		for _, name := range combo.Cards {
+			add(name)
+		}
+	}
+	if ratingsDir != "" {
+		files, _ := filepath.Glob(filepath.Join(ratingsDir, "*.json"))
+		for _, file := range files {
+			if ratings, ok := storedSetRatings(strings.TrimSuffix(filepath.Base(file), ".json")); ok {
+				for _, card := range ratings.Cards {
+					add(card.Name)
+				}
+			}
+		}
+	}
+	for _, name := range cardNamesLearned {
+		add(name)
+	}
+	for _, name := range offlineCardNames() {
+		add(name)
+	}
+
+	index := make([]string, 0, len(names))
+	for _, name := range names {
+		index = append(index, name)
+	}
+	sort.Slice(index, func(i, j int) bool { return strings.ToLower(index[i]) < strings.ToLower(index[j]) })
+	cardNameIndex = index
+	cardNamesStale = false
+	return cardNameIndex
+}
+
+// searchCardNameIndex returns the indexed names starting with prefix, also
+// matching the back face of multi-faced cards
+func searchCardNameIndex(index []string, prefix string) []string {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	start := sort.Search(len(index), func(i int) bool { return strings.ToLower(index[i]) >= prefix })
+	matches := []string{}
+	for _, name := range index[start:] {
+		if !strings.HasPrefix(strings.ToLower(name), prefix) {
+			break
+		}
+		matches = append(matches, name)
+	}
+	for _, name := range index {
+		if _, back, ok := strings.Cut(name, " // "); ok && strings.HasPrefix(strings.ToLower(back), prefix) && !contains(matches, name) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
+// completeCardName asks Scryfall's autocomplete for names starting with
+// value, and falls back to the local index when Scryfall can't be reached
+func completeCardName(ctx context.Context, value string) []string {
+	if len(strings.TrimSpace(value)) >= minOnlineCompletionLen {
+		client, err := newScryfallClient()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(ctx, cardCompletionTimeout)
+			defer cancel()
+			var names []string
+			names, err = client.AutocompleteCard(ctx, value)
+			if err == nil {
+				learnCardNames(names)
+				return names
+			}
+		}
+		log.Printf("Error completing card name online, using local index: %v", err)
+	}
+	return searchCardNameIndex(localCardNames(), value)
+}
+
+// completeFromList returns the values starting with prefix, ignoring case
+func completeFromList(values []string, prefix string) []string {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	matches := []string{}
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(value), prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
+
+// completeSetCode returns the codes of known sets whose code starts with
+// value, followed by those whose name does
+func completeSetCode(value string) []string {
+	prefix := strings.ToLower(strings.TrimSpace(value))
+	codes := []string{}
+	byName := []string{}
+	snapshot, _ := offlineSets()
+	for _, set := range snapshot.Sets {
+		switch {
+		case strings.HasPrefix(set.Code, prefix):
+			codes = append(codes, set.Code)
+		case prefix != "" && strings.HasPrefix(strings.ToLower(set.Name), prefix):
+			byName = append(byName, set.Code)
+		}
+	}
+	return append(codes, byName...)
+}
+
+// sortedThemeNames returns the names of every loaded theme
+func sortedThemeNames() []string {
+	names := []string{}
+	for name := range loadThemePatterns() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionValues returns the completions of one argument. An enum argument
+// of a prompt completes from its options; anything else by argument name.
+func completionValues(ctx context.Context, ref *mcp.CompleteReference, name, value string) []string {
+	if ref != nil && ref.Type == "ref/prompt" {
+		if definition, ok := promptDefinitions[ref.Name]; ok {
+			for _, argument := range definition.Arguments {
+				if argument.Name == name && argument.Type == PromptArgEnum {
+					return completeFromList(argument.Options, value)
+				}
+			}
+		}
+	}
+
+	switch {
+	case contains(cardNameArguments, name):
+		return completeCardName(ctx, value)
+	case contains(setCodeArguments, name):
+		return completeSetCode(value)
+	case name == "theme":
+		return completeFromList(sortedThemeNames(), value)
+	}
+	return nil
+}
+
+// completeArgument answers completion/complete for prompt arguments and
+// resource template variables
+func completeArgument(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	argument := req.Params.Argument
+	values := completionValues(ctx, req.Params.Ref, argument.Name, argument.Value)
+	if values == nil {
+		values = []string{}
+	}
+
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: values, Total: len(values)}}
+	if len(values) > maxCompletionValues {
+		result.Completion.Values = values[:maxCompletionValues]
+		result.Completion.HasMore = true
+	}
+	return result, nil
+}
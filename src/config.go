@@ -3,6 +3,8 @@ package main
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type TransportType string
@@ -10,19 +12,40 @@ type TransportType string
 const (
 	TransportStdio TransportType = "stdio"
 	TransportSSE   TransportType = "sse"
+	TransportHTTP  TransportType = "http"
 )
 
 type Config struct {
-	ServerName    string
-	ServerVersion string
-	LogToFile     bool
-	LogFilePath   string
-	Transport     TransportType
-	SSEHost       string
-	SSEPort       string
-	SSEPath       string
-	SSLCertFile   string
-	SSLKeyFile    string
+	ServerName          string
+	ServerVersion       string
+	LogToFile           bool
+	LogFilePath         string
+	Transport           TransportType
+	SSEHost             string
+	SSEPort             string
+	SSEPath             string
+	HTTPPath            string
+	HTTPStateless       bool
+	HTTPEnableSSE       bool
+	SSLCertFile         string
+	SSLKeyFile          string
+	BasePath            string
+	TrustedProxies      []string
+	ThemeDir            string
+	ComboFile           string
+	LegalitySnapshotDir string
+	BoosterFile         string
+	RatingsDir          string
+	PromptDir           string
+	CardDataFile        string
+	SetSnapshotFile     string
+	PageSize            int
+	ToolTimeout         time.Duration
+	Debug               bool
+	ScryfallRate        float64
+	ScryfallBurst       int
+	ScryfallRetries     int
+	ScryfallCacheMB     int
 }
 
 func LoadConfig() *Config {
@@ -61,6 +84,9 @@ func LoadConfig() *Config {
 		if val == "sse" || val == "SSE" {
 			transport = TransportSSE
 		}
+		if val == "http" || val == "HTTP" {
+			transport = TransportHTTP
+		}
 	}
 
 	sseHost := "0.0.0.0"
@@ -73,21 +99,157 @@ func LoadConfig() *Config {
 		ssePort = val
 	}
 
+	httpPath := "/mcp"
+	if val := os.Getenv("MCP_HTTP_PATH"); val != "" {
+		httpPath = val
+	}
+
+	basePath := ""
+	if val := strings.Trim(os.Getenv("MCP_BASE_PATH"), "/"); val != "" {
+		basePath = "/" + val
+	}
+
+	var trustedProxies []string
+	if val := os.Getenv("MCP_TRUSTED_PROXIES"); val != "" {
+		for _, proxy := range strings.Split(val, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				trustedProxies = append(trustedProxies, proxy)
+			}
+		}
+	}
+
+	httpStateless := false
+	if val := os.Getenv("MCP_HTTP_STATELESS"); val != "" {
+		httpStateless, _ = strconv.ParseBool(val)
+	}
+
+	httpEnableSSE := false
+	if val := os.Getenv("MCP_HTTP_ENABLE_SSE"); val != "" {
+		httpEnableSSE, _ = strconv.ParseBool(val)
+	}
+
+	themeDir := ""
+	if val := os.Getenv("MCP_THEME_DIR"); val != "" {
+		themeDir = val
+	}
+
+	comboFile := ""
+	if val := os.Getenv("MCP_COMBO_FILE"); val != "" {
+		comboFile = val
+	}
+
+	legalitySnapshotDir := "legality"
+	if val := os.Getenv("MCP_LEGALITY_SNAPSHOT_DIR"); val != "" {
+		legalitySnapshotDir = val
+	}
+
+	boosterFile := ""
+	if val := os.Getenv("MCP_BOOSTER_FILE"); val != "" {
+		boosterFile = val
+	}
+
+	ratingsDir := "ratings"
+	if val := os.Getenv("MCP_RATINGS_DIR"); val != "" {
+		ratingsDir = val
+	}
+
+	promptDir := ""
+	if val := os.Getenv("MCP_PROMPT_DIR"); val != "" {
+		promptDir = val
+	}
+
+	cardDataFile := ""
+	if val := os.Getenv("MCP_CARD_DATA_FILE"); val != "" {
+		cardDataFile = val
+	}
+
+	setSnapshotFile := "set-snapshot.json"
+	if val := os.Getenv("MCP_SET_SNAPSHOT_FILE"); val != "" {
+		setSnapshotFile = val
+	}
+
+	pageSize := 100
+	if val := os.Getenv("MCP_PAGE_SIZE"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size > 0 {
+			pageSize = size
+		}
+	}
+
+	toolTimeout := 60 * time.Second
+	if val := os.Getenv("MCP_TOOL_TIMEOUT"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+			toolTimeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	debug := false
+	if val := os.Getenv("MCP_DEBUG"); val != "" {
+		debug, _ = strconv.ParseBool(val)
+	}
+
+	scryfallRate := float64(defaultScryfallRate)
+	if val := os.Getenv("MCP_SCRYFALL_RATE"); val != "" {
+		if rate, err := strconv.ParseFloat(val, 64); err == nil && rate > 0 {
+			scryfallRate = rate
+		}
+	}
+
+	scryfallBurst := defaultScryfallBurst
+	if val := os.Getenv("MCP_SCRYFALL_BURST"); val != "" {
+		if burst, err := strconv.Atoi(val); err == nil && burst > 0 {
+			scryfallBurst = burst
+		}
+	}
+
+	scryfallRetries := defaultScryfallRetries
+	if val := os.Getenv("MCP_SCRYFALL_RETRIES"); val != "" {
+		if retries, err := strconv.Atoi(val); err == nil && retries >= 0 {
+			scryfallRetries = retries
+		}
+	}
+
+	scryfallCacheMB := defaultScryfallCacheBytes >> 20
+	if val := os.Getenv("MCP_SCRYFALL_CACHE_MB"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			scryfallCacheMB = size
+		}
+	}
+
 	ssePath := "/sse"
 	if val := os.Getenv("MCP_SSE_PATH"); val != "" {
 		ssePath = val
 	}
 
 	return &Config{
-		ServerName:    serverName,
-		ServerVersion: serverVersion,
-		LogToFile:     logToFile,
-		LogFilePath:   logFilePath,
-		Transport:     transport,
-		SSEHost:       sseHost,
-		SSEPort:       ssePort,
-		SSEPath:       ssePath,
-		SSLCertFile:   SSLCertFile,
-		SSLKeyFile:    SSLKeyFile,
+		ServerName:          serverName,
+		ServerVersion:       serverVersion,
+		LogToFile:           logToFile,
+		LogFilePath:         logFilePath,
+		Transport:           transport,
+		SSEHost:             sseHost,
+		SSEPort:             ssePort,
+		SSEPath:             ssePath,
+		HTTPPath:            httpPath,
+		HTTPStateless:       httpStateless,
+		HTTPEnableSSE:       httpEnableSSE,
+		SSLCertFile:         SSLCertFile,
+		SSLKeyFile:          SSLKeyFile,
+		BasePath:            basePath,
+		TrustedProxies:      trustedProxies,
+		ThemeDir:            themeDir,
+		ComboFile:           comboFile,
+		LegalitySnapshotDir: legalitySnapshotDir,
+		BoosterFile:         boosterFile,
+		RatingsDir:          ratingsDir,
+		PromptDir:           promptDir,
+		CardDataFile:        cardDataFile,
+		SetSnapshotFile:     setSnapshotFile,
+		PageSize:            pageSize,
+		ToolTimeout:         toolTimeout,
+		Debug:               debug,
+		ScryfallRate:        scryfallRate,
+		ScryfallBurst:       scryfallBurst,
+		ScryfallRetries:     scryfallRetries,
+		ScryfallCacheMB:     scryfallCacheMB,
 	}
 }
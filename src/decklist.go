@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// DeckEntry is a single line of a parsed decklist or collection export
+type DeckEntry struct {
+	Quantity        int    `json:"quantity" jsonschema:"Number of copies"`
+	Name            string `json:"name" jsonschema:"Card name as written in the list"`
+	Set             string `json:"set,omitempty" jsonschema:"Set code of the requested printing, if one was given"`
+	CollectorNumber string `json:"collector_number,omitempty" jsonschema:"Collector number of the requested printing, if one was given"`
+	Section         string `json:"section,omitempty" jsonschema:"Deck section the card belongs to (e.g., 'Commander', 'Mainboard', 'Sideboard')"`
+}
+
+// Maximum number of identifiers Scryfall accepts per /cards/collection request
+const scryfallCollectionBatchSize = 75
+
+var (
+	// "4 Lightning Bolt", "4x Lightning Bolt", "1 Sol Ring (CMR) 472", "1 Sol Ring [CMR:472]"
+	deckLineRegex       = regexp.MustCompile(`^(\d+)\s*[xX]?\s+(.+?)\s*$`)
+	arenaPrintingRegex  = regexp.MustCompile(`^(.+?)\s+\(([A-Za-z0-9]+)\)(?:\s+([A-Za-z0-9\-★]+))?(?:\s+\*[A-Z]+\*)?$`)
+	squarePrintingRegex = regexp.MustCompile(`^(.+?)\s+\[([A-Za-z0-9]+)(?::([A-Za-z0-9\-★]+))?\]$`)
+)
+
+var deckSectionHeaders = map[string]string{
+	"commander":  "Commander",
+	"companion":  "Companion",
+	"deck":       "Mainboard",
+	"main":       "Mainboard",
+	"mainboard":  "Mainboard",
+	"maindeck":   "Mainboard",
+	"sideboard":  "Sideboard",
+	"maybeboard": "Maybeboard",
+}
+
+// parseDecklist parses a plain-text decklist in the common Arena, MTGO and
+// Moxfield export formats. Duplicate lines for the same printing in the same
+// section are merged.
+func parseDecklist(text string) ([]DeckEntry, error) {
+	entries := []DeckEntry{}
+	index := map[string]int{}
+	section := "Mainboard"
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		header := strings.ToLower(strings.TrimRight(strings.TrimLeft(line, "/ "), ":"))
+		if name, ok := deckSectionHeaders[header]; ok {
+			section = name
+			continue
+		}
+
+		lineSection := section
+		if strings.HasPrefix(strings.ToUpper(line), "SB:") {
+			lineSection = "Sideboard"
+			line = strings.TrimSpace(line[3:])
+		}
+
+		match := deckLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: could not parse %q (expected '<quantity> <card name>')", lineNumber, line)
+		}
+
+		quantity, err := strconv.Atoi(match[1])
+		if err != nil || quantity <= 0 {
+			return nil, fmt.Errorf("line %d: invalid quantity %q", lineNumber, match[1])
+		}
+
+		entry := DeckEntry{Quantity: quantity, Name: match[2], Section: lineSection}
+		if m := arenaPrintingRegex.FindStringSubmatch(entry.Name); m != nil {
+			entry.Name, entry.Set, entry.CollectorNumber = m[1], strings.ToLower(m[2]), m[3]
+		} else if m := squarePrintingRegex.FindStringSubmatch(entry.Name); m != nil {
+			entry.Name, entry.Set, entry.CollectorNumber = m[1], strings.ToLower(m[2]), m[3]
+		}
+
+		key := strings.ToLower(fmt.Sprintf("%s|%s|%s|%s", entry.Section, entry.Name, entry.Set, entry.CollectorNumber))
+		if i, ok := index[key]; ok {
+			entries[i].Quantity += entry.Quantity
+			continue
+		}
+		index[key] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// deckEntryIdentifier builds the Scryfall collection identifier for an entry,
+// preferring the exact printing when a set and collector number are present.
+func deckEntryIdentifier(entry DeckEntry) scryfall.CardIdentifier {
+	if entry.Set != "" && entry.CollectorNumber != "" {
+		return scryfall.CardIdentifier{Set: entry.Set, CollectorNumber: entry.CollectorNumber}
+	}
+	if entry.Set != "" {
+		return scryfall.CardIdentifier{Name: entry.Name, Set: entry.Set}
+	}
+	return scryfall.CardIdentifier{Name: entry.Name}
+}
+
+// resolveDeckEntries looks up every entry on Scryfall in batches and returns
+// the resolved card for each entry index, along with the names that could not
+// be found.
+func resolveDeckEntries(ctx context.Context, client *scryfall.Client, entries []DeckEntry) (map[int]scryfall.Card, []string, error) {
+	resolved := map[int]scryfall.Card{}
+	notFound := []string{}
+
+	for start := 0; start < len(entries); start += scryfallCollectionBatchSize {
+		end := start + scryfallCollectionBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		identifiers := make([]scryfall.CardIdentifier, 0, end-start)
+		for _, entry := range entries[start:end] {
+			identifiers = append(identifiers, deckEntryIdentifier(entry))
+		}
+
+		log.Printf("Resolving %d decklist entries on Scryfall", len(identifiers))
+		response, err := client.GetCardsByIdentifiers(ctx, identifiers)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Scryfall omits cards it could not find from Data, so match results
+		// back to entries by identifier rather than by position.
+		for i := start; i < end; i++ {
+			card, ok := matchIdentifiedCard(entries[i], response.Data)
+			if !ok {
+				notFound = append(notFound, entries[i].Name)
+				continue
+			}
+			resolved[i] = card
+		}
+	}
+
+	return resolved, notFound, nil
+}
+
+// fetchCardsByID looks up cards by Scryfall ID in batched collection
+// requests, keeping the order of ids and skipping any that aren't found
+func fetchCardsByID(ctx context.Context, client *scryfall.Client, ids []string) []scryfall.Card {
+	byID := map[string]scryfall.Card{}
+	for start := 0; start < len(ids); start += scryfallCollectionBatchSize {
+		end := min(start+scryfallCollectionBatchSize, len(ids))
+		identifiers := make([]scryfall.CardIdentifier, 0, end-start)
+		for _, id := range ids[start:end] {
+			identifiers = append(identifiers, scryfall.CardIdentifier{ID: id})
+		}
+		response, err := client.GetCardsByIdentifiers(ctx, identifiers)
+		if err != nil {
+			log.Printf("Error looking up %d cards by ID: %v", len(identifiers), err)
+			break
+		}
+		for _, card := range response.Data {
+			byID[card.ID] = card
+		}
+	}
+
+	cards := []scryfall.Card{}
+	for _, id := range ids {
+		if card, ok := byID[id]; ok {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+func matchIdentifiedCard(entry DeckEntry, cards []scryfall.Card) (scryfall.Card, bool) {
+	for _, card := range cards {
+		if entry.Set != "" && !strings.EqualFold(card.Set, entry.Set) {
+			continue
+		}
+		if entry.CollectorNumber != "" {
+			if card.CollectorNumber == entry.CollectorNumber {
+				return card, true
+			}
+			continue
+		}
+		if cardNameMatches(card, entry.Name) {
+			return card, true
+		}
+	}
+	return scryfall.Card{}, false
+}
+
+// cardNameMatches compares names case-insensitively, also accepting the name
+// of either face of a multi-faced card.
+func cardNameMatches(card scryfall.Card, name string) bool {
+	if strings.EqualFold(card.Name, name) {
+		return true
+	}
+	for _, face := range card.CardFaces {
+		if strings.EqualFold(face.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cardPriceUSD returns the cheapest available USD price for a printing.
+func cardPriceUSD(card scryfall.Card) (float64, bool) {
+	best := 0.0
+	found := false
+	for _, raw := range []string{card.Prices.USD, card.Prices.USDFoil, card.Prices.USDEtched} {
+		if raw == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+		if !found || price < best {
+			best = price
+			found = true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+func TestParseDecklist(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []DeckEntry
+	}{
+		{
+			name: "quantities",
+			text: "4 Lightning Bolt\n4x Counterspell\n1X Sol Ring",
+			want: []DeckEntry{
+				{Quantity: 4, Name: "Lightning Bolt", Section: "Mainboard"},
+				{Quantity: 4, Name: "Counterspell", Section: "Mainboard"},
+				{Quantity: 1, Name: "Sol Ring", Section: "Mainboard"},
+			},
+		},
+		{
+			name: "printings",
+			text: "1 Sol Ring (CMR) 472\n1 Arcane Signet [M3C:283]\n1 Command Tower (CMM)\n2 Forest (NEO) 292 *F*",
+			want: []DeckEntry{
+				{Quantity: 1, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472", Section: "Mainboard"},
+				{Quantity: 1, Name: "Arcane Signet", Set: "m3c", CollectorNumber: "283", Section: "Mainboard"},
+				{Quantity: 1, Name: "Command Tower", Set: "cmm", Section: "Mainboard"},
+				{Quantity: 2, Name: "Forest", Set: "neo", CollectorNumber: "292", Section: "Mainboard"},
+			},
+		},
+		{
+			name: "sections",
+			text: "Commander\n1 Atraxa, Praetors' Voice\n\nDeck\n1 Sol Ring\n\nSideboard:\n2 Negate\n// Maybeboard\n1 Cyclonic Rift",
+			want: []DeckEntry{
+				{Quantity: 1, Name: "Atraxa, Praetors' Voice", Section: "Commander"},
+				{Quantity: 1, Name: "Sol Ring", Section: "Mainboard"},
+				{Quantity: 2, Name: "Negate", Section: "Sideboard"},
+				{Quantity: 1, Name: "Cyclonic Rift", Section: "Maybeboard"},
+			},
+		},
+		{
+			name: "MTGO sideboard lines",
+			text: "4 Lightning Bolt\nSB: 2 Pyroblast\n# a comment\nSB: 1 Pyroblast",
+			want: []DeckEntry{
+				{Quantity: 4, Name: "Lightning Bolt", Section: "Mainboard"},
+				{Quantity: 3, Name: "Pyroblast", Section: "Sideboard"},
+			},
+		},
+		{
+			name: "duplicates merge per printing and section",
+			text: "2 Island\n3 Island\n1 Island (NEO) 294\nSideboard\n1 Island",
+			want: []DeckEntry{
+				{Quantity: 5, Name: "Island", Section: "Mainboard"},
+				{Quantity: 1, Name: "Island", Set: "neo", CollectorNumber: "294", Section: "Mainboard"},
+				{Quantity: 1, Name: "Island", Section: "Sideboard"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDecklist(tt.text)
+			if err != nil {
+				t.Fatalf("parseDecklist(%q) returned error: %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDecklist(%q) =\n%+v\nwant\n%+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecklistErrors(t *testing.T) {
+	for _, text := range []string{"Lightning Bolt", "0 Lightning Bolt", "4 Lightning Bolt\nfour Counterspell"} {
+		if entries, err := parseDecklist(text); err == nil {
+			t.Errorf("parseDecklist(%q) = %+v, want an error", text, entries)
+		}
+	}
+}
+
+func TestDiffDeckAgainstCollection(t *testing.T) {
+	solRingCMR := scryfall.Card{ID: "sol-ring-cmr", OracleID: "sol-ring", Name: "Sol Ring", Set: "cmr", Prices: scryfall.Prices{USD: "1.50"}}
+	solRingC21 := scryfall.Card{ID: "sol-ring-c21", OracleID: "sol-ring", Name: "Sol Ring", Set: "c21", Prices: scryfall.Prices{USD: "1.00", USDFoil: "0.75"}}
+	rift := scryfall.Card{ID: "rift-rtr", OracleID: "rift", Name: "Cyclonic Rift", Set: "rtr"}
+
+	deck := []DeckEntry{
+		{Quantity: 2, Name: "Sol Ring", Section: "Mainboard"},
+		{Quantity: 1, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472", Section: "Commander"},
+		{Quantity: 1, Name: "Cyclonic Rift", Section: "Mainboard"},
+		{Quantity: 1, Name: "Missing Card", Section: "Mainboard"},
+	}
+	deckCards := map[int]scryfall.Card{0: solRingC21, 1: solRingCMR, 2: rift}
+	collection := []DeckEntry{
+		{Quantity: 1, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472"},
+		{Quantity: 1, Name: "Sol Ring", Set: "c21"},
+	}
+	collectionCards := map[int]scryfall.Card{0: solRingCMR, 1: solRingC21}
+
+	t.Run("specific printings are matched first", func(t *testing.T) {
+		got := diffDeckAgainstCollection(deck, deckCards, collection, collectionCards, false)
+		want := DeckCollectionDiffResult{
+			Owned: []DeckCardStatus{
+				{Name: "Sol Ring", Set: "cmr", CollectorNumber: "472", Section: "Commander", Needed: 1, Owned: 1, Missing: 0, UnitPriceUSD: 1.5},
+			},
+			Missing: []DeckCardStatus{
+				{Name: "Sol Ring", Section: "Mainboard", Needed: 2, Owned: 1, Missing: 1, UnitPriceUSD: 0.75, MissingCostUSD: 0.75},
+				{Name: "Cyclonic Rift", Section: "Mainboard", Needed: 1, Owned: 0, Missing: 1},
+			},
+			NotFound:       []string{},
+			UnpricedCards:  []string{"Cyclonic Rift"},
+			TotalCards:     4,
+			OwnedCount:     2,
+			MissingCount:   2,
+			MissingCostUSD: 0.75,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("diffDeckAgainstCollection() =\n%+v\nwant\n%+v", got, want)
+		}
+	})
+
+	t.Run("alternate printings", func(t *testing.T) {
+		deck := []DeckEntry{{Quantity: 2, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472"}}
+		deckCards := map[int]scryfall.Card{0: solRingCMR}
+
+		exact := diffDeckAgainstCollection(deck, deckCards, collection, collectionCards, false)
+		if exact.OwnedCount != 1 || exact.MissingCount != 1 {
+			t.Errorf("without alternates owned %d and missing %d, want 1 and 1", exact.OwnedCount, exact.MissingCount)
+		}
+		alternates := diffDeckAgainstCollection(deck, deckCards, collection, collectionCards, true)
+		if alternates.OwnedCount != 2 || alternates.MissingCount != 0 {
+			t.Errorf("with alternates owned %d and missing %d, want 2 and 0", alternates.OwnedCount, alternates.MissingCount)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// A draft is three packs of fourteen picks; commitment to colors grows until
+// draftCommitPicks cards have been taken
+const (
+	draftTotalPicks  = 42
+	draftCommitPicks = 15
+	maxDraftPackSize = 20
+)
+
+// Draft pick score weights. A rated card scores draftRatingBase plus
+// draftRatingScale per standard deviation its win rate is above the set's
+// average, which puts ratings on the same scale as the fallback scores.
+const (
+	draftRatingBase       = 2.5
+	draftRatingScale      = 1.0
+	draftColorWeight      = 1.5
+	draftOffColorWeight   = 1.0
+	draftColorlessBonus   = 0.25
+	draftCurveWeight      = 0.5
+	draftCreatureWeight   = 0.5
+	draftMainColorsNeeded = 2
+)
+
+// Sources of the base score of a draft pick
+const (
+	DraftRatingSet      = "ratings"
+	DraftRatingFallback = "fallback"
+)
+
+// draftCard is a pack card or pick with its base score
+type draftCard struct {
+	card    scryfall.Card
+	score   float64
+	source  string
+	rating  *CardRating
+	reasons []string
+}
+
+// ratingCard stands in for a card that is neither on Scryfall, which could
+// not be asked, nor in the offline card data, using the name, colors and
+// rarity from the ratings
+func ratingCard(rating CardRating) scryfall.Card {
+	card := scryfall.Card{Name: rating.Name, Rarity: rating.Rarity}
+	for _, color := range rating.Color {
+		if strings.ContainsRune("WUBRG", color) {
+			card.ColorIdentity = append(card.ColorIdentity, scryfall.Color(string(color)))
+		}
+	}
+	return card
+}
+
+// rateDraftCard gives a card its base score: its standardized win rate when
+// the set's ratings have one, otherwise the rarity, removal and evasion score
+// build_limited_deck uses
+func rateDraftCard(card scryfall.Card, ratings *setRatings, mean, deviation float64, patterns []string) draftCard {
+	if ratings != nil && deviation > 0 {
+		if rating, ok := findCardRating(ratings, card.Name); ok && rating.WinRate != nil {
+			z := (*rating.WinRate - mean) / deviation
+			return draftCard{
+				card:    card,
+				score:   draftRatingBase + z*draftRatingScale,
+				source:  DraftRatingSet,
+				rating:  &rating,
+				reasons: []string{fmt.Sprintf("%s %.1f%% (set average %.1f%%)", ratings.Metric, *rating.WinRate, mean)},
+			}
+		}
+	}
+
+	rated := rateLimitedCard(card, patterns)
+	reasons := []string{}
+	if card.Rarity != "" {
+		reasons = append(reasons, "no win rate; rated as "+card.Rarity)
+	}
+	return draftCard{
+		card:    card,
+		score:   rated.score,
+		source:  DraftRatingFallback,
+		reasons: append(reasons, rated.reasons...),
+	}
+}
+
+// draftColorWeights sums the base scores of the picks in each color, so
+// strong picks count for more than filler
+func draftColorWeights(picks []draftCard) map[string]float64 {
+	weights := map[string]float64{}
+	for _, pick := range picks {
+		if isLandCard(pick.card) {
+			continue
+		}
+		for _, color := range pick.card.ColorIdentity {
+			weights[string(color)] += max(pick.score, 0)
+		}
+	}
+	return weights
+}
+
+// draftMainColors returns up to two colors the picks lean towards, heaviest
+// first
+func draftMainColors(weights map[string]float64) string {
+	colors := []string{}
+	for _, color := range []string{"W", "U", "B", "R", "G"} {
+		if weights[color] > 0 {
+			colors = append(colors, color)
+		}
+	}
+	sort.SliceStable(colors, func(i, j int) bool { return weights[colors[i]] > weights[colors[j]] })
+	if len(colors) > draftMainColorsNeeded {
+		colors = colors[:draftMainColorsNeeded]
+	}
+	return strings.Join(colors, "")
+}
+
+// draftCommitment is how settled the drafter's colors are, from 0 at the
+// first pick to 1 once draftCommitPicks cards have been taken
+func draftCommitment(pickCount int) float64 {
+	return min(float64(pickCount)/draftCommitPicks, 1)
+}
+
+// rankDraftPack scores each pack card for the drafter: its base score,
+// adjusted for how well it fits the colors of the picks and for the creatures
+// and mana values the picks are short of
+func rankDraftPack(pack, picks []draftCard) ([]DraftPickRanking, string, float64) {
+	commitment := draftCommitment(len(picks))
+	mainColors := draftMainColors(draftColorWeights(picks))
+	progress := min(float64(len(picks))/draftTotalPicks, 1)
+
+	curve := map[string]int{}
+	creatures := 0
+	for _, pick := range picks {
+		if pick.card.TypeLine == "" || isLandCard(pick.card) || !withinColors(pick.card, mainColors) {
+			continue
+		}
+		curve[limitedCurveBucket(pick.card.CMC)]++
+		if isCreatureCard(pick.card) {
+			creatures++
+		}
+	}
+
+	rankings := []DraftPickRanking{}
+	for _, candidate := range pack {
+		card := candidate.card
+		score := candidate.score
+		reasons := append([]string{}, candidate.reasons...)
+
+		identity := ""
+		for _, color := range card.ColorIdentity {
+			identity += string(color)
+		}
+		fit := ""
+		switch {
+		case mainColors == "" || commitment == 0:
+		case isLandCard(card):
+		case identity == "":
+			fit = "colorless"
+			score += draftColorlessBonus
+			reasons = append(reasons, "colorless, fits any deck")
+		case withinColors(card, mainColors):
+			fit = "on-color"
+			score += draftColorWeight * commitment
+			reasons = append(reasons, fmt.Sprintf("in your colors (%s)", mainColors))
+		default:
+			fit = "off-color"
+			offColors := 0
+			for _, color := range identity {
+				if !strings.ContainsRune(mainColors, color) {
+					offColors++
+				}
+			}
+			score -= draftOffColorWeight * commitment * float64(offColors)
+			reasons = append(reasons, fmt.Sprintf("outside your colors (%s)", mainColors))
+		}
+
+		// Curve and creature needs only matter once the picks settle on colors
+		if card.TypeLine != "" && !isLandCard(card) && fit != "off-color" && commitment > 0 {
+			bucket := limitedCurveBucket(card.CMC)
+			expected := float64(limitedCurveTargets[bucket]) * progress
+			if float64(curve[bucket])+1 <= expected {
+				score += draftCurveWeight * commitment
+				reasons = append(reasons, fmt.Sprintf("fills your curve at %s mana", bucket))
+			}
+			if isCreatureCard(card) && float64(creatures)+1 <= minLimitedCreatures*progress {
+				score += draftCreatureWeight * commitment
+				reasons = append(reasons, "you need creatures")
+			}
+		}
+
+		ranking := DraftPickRanking{
+			Name:          card.Name,
+			Score:         roundCents(score),
+			BaseScore:     roundCents(candidate.score),
+			RatingSource:  candidate.source,
+			Rarity:        card.Rarity,
+			ColorIdentity: identity,
+			ManaCost:      cardManaCost(card),
+			TypeLine:      card.TypeLine,
+			ColorFit:      fit,
+			Reasons:       reasons,
+		}
+		if candidate.rating != nil {
+			ranking.WinRate = candidate.rating.WinRate
+			ranking.ALSA = candidate.rating.ALSA
+		}
+		rankings = append(rankings, ranking)
+	}
+
+	sort.SliceStable(rankings, func(i, j int) bool { return rankings[i].Score > rankings[j].Score })
+	for i := range rankings {
+		rankings[i].Rank = i + 1
+	}
+	return rankings, mainColors, commitment
+}
+
+// resolveDraftCards looks up pack or pick names on Scryfall. When Scryfall
+// cannot be reached, cards come from the offline card data, which has their
+// rules text, or failing that are made up from the set's ratings. The names
+// only found in the ratings are returned too, and an error only when there is
+// no data to look the cards up in at all.
+func resolveDraftCards(ctx context.Context, client *scryfall.Client, names []string, ratings *setRatings) ([]scryfall.Card, []string, []string, bool, error) {
+	entries := []DeckEntry{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			entries = append(entries, DeckEntry{Quantity: 1, Name: name})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil, nil, false, nil
+	}
+
+	err := errors.New("no Scryfall client")
+	if client != nil {
+		var resolved map[int]scryfall.Card
+		var notFound []string
+		resolved, notFound, err = resolveDeckEntries(ctx, client, entries)
+		if err == nil {
+			cards := []scryfall.Card{}
+			for i := range entries {
+				if card, ok := resolved[i]; ok {
+					cards = append(cards, card)
+				}
+			}
+			return cards, notFound, nil, false, nil
+		}
+	}
+	if _, ok := loadedOfflineCards(); !ok && ratings == nil {
+		return nil, nil, nil, false, fmt.Errorf("Scryfall could not be reached (%v), and there is no offline card data (MCP_CARD_DATA_FILE) or imported ratings for the set to look cards up in", err)
+	}
+
+	log.Printf("Error looking up draft cards, using offline data instead: %v", err)
+	cards := []scryfall.Card{}
+	notFound := []string{}
+	ratingsOnly := []string{}
+	for _, entry := range entries {
+		if card, ok := offlineCardByName(entry.Name); ok {
+			cards = append(cards, card)
+			continue
+		}
+		if ratings != nil {
+			if rating, ok := findCardRating(ratings, entry.Name); ok {
+				cards = append(cards, ratingCard(rating))
+				ratingsOnly = append(ratingsOnly, rating.Name)
+				continue
+			}
+		}
+		notFound = append(notFound, entry.Name)
+	}
+	return cards, notFound, ratingsOnly, true, nil
+}
+
+// rateDraftCards gives every card its base score, using the set's ratings
+// when there are any
+func rateDraftCards(cards []scryfall.Card, ratings *setRatings) []draftCard {
+	mean, deviation := 0.0, 0.0
+	if ratings != nil {
+		mean, deviation = ratingStats(ratings)
+	}
+	patterns := limitedRemovalPatterns()
+	rated := []draftCard{}
+	for _, card := range cards {
+		rated = append(rated, rateDraftCard(card, ratings, mean, deviation, patterns))
+	}
+	return rated
+}
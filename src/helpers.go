@@ -5,26 +5,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/BlueMonday/go-scryfall"
 )
 
-// ThemePattern represents a theme pattern loaded from JSON
+// ThemePattern represents a theme pattern loaded from JSON. Patterns detect
+// the theme in general; EnablerPatterns and PayoffPatterns tell apart cards
+// that feed the theme (a sacrifice outlet) from cards that reward it (a death
+// trigger), and EnablerQuery and PayoffQuery search for each side.
 type ThemePattern struct {
-	Patterns            []string `json:"patterns"`
-	SynergyQuery        string   `json:"synergyQuery"`
-	SynergyDescription  string   `json:"synergyDescription"`
-	SynergyType         string   `json:"synergyType"`
+	Patterns           []string `json:"patterns"`
+	SynergyQuery       string   `json:"synergyQuery"`
+	SynergyDescription string   `json:"synergyDescription"`
+	SynergyType        string   `json:"synergyType"`
+	EnablerPatterns    []string `json:"enablerPatterns,omitempty"`
+	EnablerQuery       string   `json:"enablerQuery,omitempty"`
+	PayoffPatterns     []string `json:"payoffPatterns,omitempty"`
+	PayoffQuery        string   `json:"payoffQuery,omitempty"`
+}
+
+// allPatterns returns every pattern that identifies the theme
+func (p ThemePattern) allPatterns() []string {
+	patterns := append([]string{}, p.Patterns...)
+	patterns = append(patterns, p.EnablerPatterns...)
+	return append(patterns, p.PayoffPatterns...)
 }
 
 // Global cache for resources
 var (
-	creatureTypesCache  []string
-	themePatternsCache  map[string]ThemePattern
+	creatureTypesCache    []string
+	creatureTypeSetCache  map[string]bool
+	multiWordSubtypeCache [][]string
+	creatureTypesOnce     sync.Once
+	themePatternsCache    map[string]ThemePattern
+	themeSourcesCache     map[string]string
+	themePatternsMu       sync.RWMutex
 	keywordAbilitiesCache []string
 )
 
@@ -44,83 +67,30 @@ func limitCards(cards []scryfall.Card, max int) []scryfall.Card {
 	return cards[:max]
 }
 
+// extractKeywordsFromText returns the keyword abilities in rules text and
+// the keywords its abilities grant. Keywords that are only mentioned, as in
+// "creatures without flying", are ignored.
 func extractKeywordsFromText(text string) []string {
-	keywords := []string{}
-	keywordList := loadKeywordAbilities()
-
-	textLower := fmt.Sprintf(" %s ", text)
-	// Convert to lowercase for case-insensitive comparison
-	for i := 0; i < len(textLower); i++ {
-		if textLower[i] >= 'A' && textLower[i] <= 'Z' {
-			textLower = textLower[:i] + string(textLower[i]+32) + textLower[i+1:]
-		}
-	}
-
-	for _, kw := range keywordList {
-		if len(kw) > 0 {
-			kwLower := kw
-			// Convert keyword to lowercase
-			for i := 0; i < len(kwLower); i++ {
-				if kwLower[i] >= 'A' && kwLower[i] <= 'Z' {
-					kwLower = kwLower[:i] + string(kwLower[i]+32) + kwLower[i+1:]
-				}
-			}
-
-			// Check for keyword followed by space or punctuation
-			searchStr := fmt.Sprintf(" %s ", kwLower)
-			if len(textLower) >= len(searchStr) {
-				for i := 0; i <= len(textLower)-len(searchStr); i++ {
-					if textLower[i:i+len(searchStr)] == searchStr {
-						if !contains(keywords, kwLower) {
-							keywords = append(keywords, kwLower)
-						}
-						break
-					}
-				}
-			}
-		}
-	}
-
-	return keywords
+	return abilityKeywords(parseOracleText(text, nil, "", false))
 }
 
+// extractThemesFromCard returns the card's keywords followed by every theme
+// whose patterns match one of its abilities
 func extractThemesFromCard(card scryfall.Card) []string {
-	themes := []string{}
-	oracleText := ""
-	if card.OracleText != "" {
-		oracleText = card.OracleText
-	}
-
-	// Extract keywords
-	keywords := extractKeywordsFromText(oracleText)
-	themes = append(themes, keywords...)
+	abilities := parseCardAbilities(card)
+	themes := abilityKeywords(abilities)
 
 	// Load theme patterns from resource file
 	themePatterns := loadThemePatterns()
 
-	for theme, pattern := range themePatterns {
-		for _, patternStr := range pattern.Patterns {
-			if len(oracleText) > 0 && patternStr != "" {
-				regexPattern := "(?i)" + patternStr
-				re, err := regexp.Compile(regexPattern)
-				if err != nil {
-					// If regex compilation fails, fall back to simple substring matching
-					log.Printf("Invalid regex pattern '%s' for theme '%s', using substring match: %v", patternStr, theme, err)
-					if strings.Contains(strings.ToLower(oracleText), strings.ToLower(patternStr)) {
-						if !contains(themes, theme) {
-							themes = append(themes, theme)
-						}
-					}
-					continue
-				}
-
-				// Check if pattern matches oracle text
-				if re.MatchString(oracleText) {
-					if !contains(themes, theme) {
-						themes = append(themes, theme)
-					}
-					break // Found a match for this, move on
-				}
+	for _, theme := range sortedKeys(themePatterns) {
+		if contains(themes, theme) {
+			continue
+		}
+		for _, ability := range abilities {
+			if anyPatternMatches(themePatterns[theme].allPatterns(), abilityThemeText(ability)) {
+				themes = append(themes, theme)
+				break
 			}
 		}
 	}
@@ -128,12 +98,41 @@ func extractThemesFromCard(card scryfall.Card) []string {
 	return themes
 }
 
-// loadCreatureTypes loads creature types from the embedded resource file
+// loadCreatureTypes returns the creature types, loading them once along with
+// the lowercase set used to recognize them and the multi-word subtypes that
+// type line parsing keeps together
 func loadCreatureTypes() []string {
-	if creatureTypesCache != nil {
-		return creatureTypesCache
-	}
+	creatureTypesOnce.Do(func() {
+		creatureTypesCache = readCreatureTypes()
+		creatureTypeSetCache = map[string]bool{}
+		multiWordSubtypeCache = [][]string{}
+		for _, subtype := range append(append([]string{}, multiWordPlaneTypes...), creatureTypesCache...) {
+			if words := strings.Fields(subtype); len(words) > 1 {
+				multiWordSubtypeCache = append(multiWordSubtypeCache, words)
+			}
+		}
+		for _, creatureType := range creatureTypesCache {
+			creatureTypeSetCache[strings.ToLower(creatureType)] = true
+		}
+	})
+	return creatureTypesCache
+}
+
+// isCreatureType reports whether a subtype is a creature type, ignoring case
+func isCreatureType(subtype string) bool {
+	loadCreatureTypes()
+	return creatureTypeSetCache[strings.ToLower(subtype)]
+}
 
+// multiWordSubtypes returns the words of each subtype made of more than one
+// word
+func multiWordSubtypes() [][]string {
+	loadCreatureTypes()
+	return multiWordSubtypeCache
+}
+
+// readCreatureTypes reads creature types from the embedded resource file
+func readCreatureTypes() []string {
 	data, err := embeddedResources.ReadFile("res/creature-types.txt")
 	if err != nil {
 		log.Printf("Error loading creature types: %v, using defaults", err)
@@ -153,31 +152,112 @@ func loadCreatureTypes() []string {
 		log.Printf("Error reading creature types: %v", err)
 		return []string{"Human", "Elf", "Goblin", "Zombie", "Vampire"}
 	}
-
-	creatureTypesCache = types
 	return types
 }
 
-// loadThemePatterns loads theme patterns from the embedded resource file
+// loadThemePatterns loads theme patterns from the embedded resource file,
+// merged with any theme packs loaded by reloadThemePatterns
 func loadThemePatterns() map[string]ThemePattern {
-	if themePatternsCache != nil {
-		return themePatternsCache
+	themePatternsMu.RLock()
+	cached := themePatternsCache
+	themePatternsMu.RUnlock()
+	if cached != nil {
+		return cached
 	}
 
-	data, err := embeddedResources.ReadFile("res/themepatterns.json")
+	patterns, err := loadEmbeddedThemePatterns()
 	if err != nil {
 		log.Printf("Error loading theme patterns: %v, using defaults", err)
 		return getDefaultThemePatterns()
 	}
 
+	themePatternsMu.Lock()
+	themePatternsCache = patterns
+	themeSourcesCache = themeSourceMap(patterns, embeddedThemeSource)
+	themePatternsMu.Unlock()
+	return patterns
+}
+
+func loadEmbeddedThemePatterns() (map[string]ThemePattern, error) {
+	data, err := embeddedResources.ReadFile("res/themepatterns.json")
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := decodeThemePatterns(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding res/themepatterns.json: %w", err)
+	}
+	return patterns, nil
+}
+
+// decodeThemePatterns strictly decodes a theme patterns file, rejecting
+// unknown fields so that misspelled keys are reported instead of ignored.
+func decodeThemePatterns(data []byte) (map[string]ThemePattern, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
 	var patterns map[string]ThemePattern
-	if err := json.Unmarshal(data, &patterns); err != nil {
-		log.Printf("Error decoding theme patterns: %v", err)
-		return getDefaultThemePatterns()
+	if err := decoder.Decode(&patterns); err != nil {
+		return nil, err
 	}
+	return patterns, nil
+}
 
-	themePatternsCache = patterns
-	return patterns
+// validateThemePatterns checks every theme for a synergy type, at least one
+// pattern, regular expressions that compile, and a query for the
+// complementary side of each enabler/payoff pattern list. All problems are
+// reported together.
+func validateThemePatterns(patterns map[string]ThemePattern) error {
+	errs := []error{}
+	themes := make([]string, 0, len(patterns))
+	for theme := range patterns {
+		themes = append(themes, theme)
+	}
+	sort.Strings(themes)
+
+	for _, theme := range themes {
+		pattern := patterns[theme]
+		if pattern.SynergyType == "" {
+			errs = append(errs, fmt.Errorf("theme %q: synergyType is required", theme))
+		}
+		if len(pattern.allPatterns()) == 0 {
+			errs = append(errs, fmt.Errorf("theme %q: at least one of patterns, enablerPatterns or payoffPatterns is required", theme))
+		}
+		if len(pattern.EnablerPatterns) > 0 && pattern.PayoffQuery == "" {
+			errs = append(errs, fmt.Errorf("theme %q: enablerPatterns requires a payoffQuery to find payoffs for enablers", theme))
+		}
+		if len(pattern.PayoffPatterns) > 0 && pattern.EnablerQuery == "" {
+			errs = append(errs, fmt.Errorf("theme %q: payoffPatterns requires an enablerQuery to find enablers for payoffs", theme))
+		}
+
+		for field, list := range map[string][]string{
+			"patterns":        pattern.Patterns,
+			"enablerPatterns": pattern.EnablerPatterns,
+			"payoffPatterns":  pattern.PayoffPatterns,
+		} {
+			for i, patternStr := range list {
+				if patternStr == "" {
+					errs = append(errs, fmt.Errorf("theme %q: %s[%d] is empty", theme, field, i))
+					continue
+				}
+				if _, err := regexp.Compile("(?i)" + patternStr); err != nil {
+					errs = append(errs, fmt.Errorf("theme %q: %s[%d] %q is not a valid regular expression: %v", theme, field, i, patternStr, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateEmbeddedThemePatterns decodes and validates the embedded theme
+// patterns so that a bad edit to res/themepatterns.json fails at startup
+// rather than silently falling back to the defaults.
+func validateEmbeddedThemePatterns() error {
+	patterns, err := loadEmbeddedThemePatterns()
+	if err != nil {
+		return err
+	}
+	return validateThemePatterns(patterns)
 }
 
 // getDefaultThemePatterns returns default theme patterns as fallback
@@ -266,15 +346,13 @@ func findTokenCards(ctx context.Context, client *scryfall.Client, mainCard scryf
 	}
 
 	log.Printf("Searching for tokens created by %s", mainCard.Name)
-	tokenCards := []scryfall.Card{}
+	tokenIDs := []string{}
 	for _, part := range mainCard.AllParts {
 		if part.Component == "token" {
-			token, err := client.GetCard(ctx, part.ID)
-			if err == nil {
-				tokenCards = append(tokenCards, token)
-			}
+			tokenIDs = append(tokenIDs, part.ID)
 		}
 	}
+	tokenCards := fetchCardsByID(ctx, client, tokenIDs)
 	if len(tokenCards) > 0 {
 		log.Printf("Found %d tokens", len(tokenCards))
 		return &RelatedCardCategory{
@@ -298,6 +376,9 @@ func findMechanicCards(ctx context.Context, client *scryfall.Client, mainCard sc
 		}
 
 		for _, kw := range searchKeywords {
+			if ctx.Err() != nil {
+				break
+			}
 			mechanicQuery := fmt.Sprintf(`oracle:"%s" -name:"%s"`, kw, mainCard.Name)
 			mechanics, err := client.SearchCards(ctx, mechanicQuery, opts)
 			if err == nil && len(mechanics.Cards) > 0 {
@@ -313,6 +394,26 @@ func findMechanicCards(ctx context.Context, client *scryfall.Client, mainCard sc
 	return nil
 }
 
+// findSameSetCards searches for other cards from the card's set
+func findSameSetCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, maxResults int) *RelatedCardCategory {
+	if mainCard.Set == "" {
+		return nil
+	}
+
+	log.Printf("Searching for cards from set %s", mainCard.Set)
+	setQuery := fmt.Sprintf(`set:%s -name:"%s"`, mainCard.Set, mainCard.Name)
+	setCards, err := client.SearchCards(ctx, setQuery, opts)
+	if err == nil && len(setCards.Cards) > 0 {
+		log.Printf("Found %d cards from same set", len(setCards.Cards))
+		return &RelatedCardCategory{
+			CategoryName: fmt.Sprintf("Same Set (%s)", mainCard.SetName),
+			Cards:        limitCards(setCards.Cards, maxResults),
+			Count:        len(setCards.Cards),
+		}
+	}
+	return nil
+}
+
 // findArtistCards searches for cards by the same artist
 func findArtistCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, maxResults int) *RelatedCardCategory {
 	if mainCard.Artist == nil || *mainCard.Artist == "" {
@@ -333,26 +434,76 @@ func findArtistCards(ctx context.Context, client *scryfall.Client, mainCard scry
 	return nil
 }
 
-// findKeywordSynergies searches for cards with shared keywords
-func findKeywordSynergies(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, synergies []SynergyCategory) []SynergyCategory {
+// synergySearches picks the keywords and themes to search for synergies
+// with: the themes that have patterns first, then keywords, maxSynergySearches
+// in all
+func synergySearches(mainCard scryfall.Card, searchThemes []string) ([]string, []string) {
+	themePatterns := loadThemePatterns()
+	themes := []string{}
+	for _, theme := range searchThemes {
+		if _, ok := themePatterns[theme]; ok && len(themes) < maxSynergySearches {
+			themes = append(themes, theme)
+		}
+	}
 	keywords := extractKeywordsFromText(mainCard.OracleText)
-	if len(keywords) > 0 {
-		for _, keyword := range keywords {
-			if len(synergies) >= 3 { // Limit to avoid too many categories
-				break
+	if room := maxSynergySearches - len(themes); len(keywords) > room {
+		keywords = keywords[:room]
+	}
+	return keywords, themes
+}
+
+// searchSynergyCandidates returns the cards matching a synergy query,
+// following Scryfall's pages until synergyCandidateBudget cards are found. A
+// failed later page keeps the cards found so far.
+func searchSynergyCandidates(ctx context.Context, client *scryfall.Client, query string, opts scryfall.SearchCardsOptions) ([]scryfall.Card, error) {
+	opts.Page = 1
+	cards := []scryfall.Card{}
+	for {
+		result, err := client.SearchCards(ctx, query, opts)
+		if err != nil {
+			if len(cards) > 0 {
+				return cards, nil
 			}
-			keywordQuery := fmt.Sprintf(`oracle:"%s" -name:"%s"`, keyword, mainCard.Name)
-			log.Printf("Searching for keyword synergy: %s", keyword)
-			keywordCards, err := client.SearchCards(ctx, keywordQuery, opts)
-			if err == nil && len(keywordCards.Cards) > 0 {
-				synergies = append(synergies, SynergyCategory{
-					SynergyType: "Keyword Synergy",
-					Description: fmt.Sprintf("Cards that share the '%s' keyword ability", keyword),
-					Cards:       limitCards(keywordCards.Cards, 5),
-					Count:       len(keywordCards.Cards),
-				})
-				log.Printf("Found %d cards with '%s' keyword", len(keywordCards.Cards), keyword)
+			return nil, err
+		}
+		cards = append(cards, result.Cards...)
+		if len(cards) >= synergyCandidateBudget {
+			return cards[:synergyCandidateBudget], nil
+		}
+		if !result.HasMore {
+			return cards, nil
+		}
+		opts.Page++
+	}
+}
+
+// findKeywordSynergies searches for cards with shared keywords
+func findKeywordSynergies(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, keywords []string, synergies []SynergyCategory) []SynergyCategory {
+	found := make([]*SynergyCategory, len(keywords))
+	runParallel(ctx, len(keywords), maxParallelSearches, func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		keyword := keywords[i]
+		keywordQuery := fmt.Sprintf(`oracle:"%s" -name:"%s"`, keyword, mainCard.Name)
+		log.Printf("Searching for keyword synergy: %s", keyword)
+		reportProgress(ctx, fmt.Sprintf("Searching for cards with %s", keyword))
+		start := time.Now()
+		keywordCards, err := searchSynergyCandidates(ctx, client, keywordQuery, opts)
+		debugf("keyword synergy %s for '%s' in %s", keyword, mainCard.Name, time.Since(start).Round(time.Millisecond))
+		if err == nil && len(keywordCards) > 0 {
+			found[i] = &SynergyCategory{
+				SynergyType: "Keyword Synergy",
+				Description: fmt.Sprintf("Cards that share the '%s' keyword ability", keyword),
+				Cards:       keywordCards,
+				Count:       len(keywordCards),
 			}
+			log.Printf("Found %d cards with '%s' keyword", len(keywordCards), keyword)
+		}
+	})
+	for _, category := range found {
+		if category != nil {
+			synergies = append(synergies, *category)
 		}
 	}
 	return synergies
@@ -361,83 +512,92 @@ func findKeywordSynergies(ctx context.Context, client *scryfall.Client, mainCard
 // findThemeSynergies searches for theme-based synergies
 func findThemeSynergies(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, searchThemes []string, synergies []SynergyCategory) []SynergyCategory {
 	themePatterns := loadThemePatterns()
-	creatureTypes := loadCreatureTypes()
-	themesSearched := 0
-
-	for _, theme := range searchThemes {
-		if themesSearched >= 2 { // Limit theme searches
-			break
-		}
-		if pattern, ok := themePatterns[theme]; ok {
-			if pattern.SynergyQuery == "" && theme == "tribal" {
-				// Handle tribal synergy specially
-				if mainCard.TypeLine != "" {
-					for _, cType := range creatureTypes {
-						typeLower := mainCard.TypeLine
-						ctLower := cType
-						found := false
-						for i := 0; i <= len(typeLower)-len(ctLower); i++ {
-							match := true
-							for j := 0; j < len(ctLower); j++ {
-								c1 := typeLower[i+j]
-								c2 := ctLower[j]
-								if c1 >= 'A' && c1 <= 'Z' {
-									c1 = c1 + 32
-								}
-								if c2 >= 'A' && c2 <= 'Z' {
-									c2 = c2 + 32
-								}
-								if c1 != c2 {
-									match = false
-									break
-								}
-							}
-							if match {
-								found = true
-								break
-							}
-						}
-						if found {
-							tribalQuery := fmt.Sprintf(`type:%s -name:"%s"`, cType, mainCard.Name)
-							log.Printf("Searching for tribal synergy: %s", cType)
-							tribalCards, err := client.SearchCards(ctx, tribalQuery, opts)
-							if err == nil && len(tribalCards.Cards) > 0 {
-								synergies = append(synergies, SynergyCategory{
-									SynergyType: pattern.SynergyType,
-									Description: fmt.Sprintf("Cards that share the %s creature type", cType),
-									Cards:       limitCards(tribalCards.Cards, 5),
-									Count:       len(tribalCards.Cards),
-								})
-								log.Printf("Found %d cards with %s type", len(tribalCards.Cards), cType)
-								themesSearched++
-								break
-							}
-						}
-					}
-				}
-			} else if pattern.SynergyQuery != "" {
-				themeQuery := fmt.Sprintf(`%s -name:"%s"`, pattern.SynergyQuery, mainCard.Name)
-				log.Printf("Searching for theme synergy: %s", theme)
-				themeCards, err := client.SearchCards(ctx, themeQuery, opts)
-				if err == nil && len(themeCards.Cards) > 0 {
-					synergies = append(synergies, SynergyCategory{
-						SynergyType: pattern.SynergyType,
-						Description: pattern.SynergyDescription,
-						Cards:       limitCards(themeCards.Cards, 5),
-						Count:       len(themeCards.Cards),
-					})
-					log.Printf("Found %d cards for %s theme", len(themeCards.Cards), theme)
-					themesSearched++
-				}
+	mainRoles := themeRoles(mainCard)
+
+	// Themes are searched side by side; each fills its own slot so the
+	// categories keep the order of searchThemes
+	found := make([][]SynergyCategory, len(searchThemes))
+	runParallel(ctx, len(searchThemes), maxParallelSearches, func(i int) {
+		theme := searchThemes[i]
+		pattern, ok := themePatterns[theme]
+		if !ok || ctx.Err() != nil {
+			return
+		}
+		start := time.Now()
+		defer func() {
+			debugf("theme synergy %s for '%s' in %s", theme, mainCard.Name, time.Since(start).Round(time.Millisecond))
+		}()
+		if pattern.SynergyQuery == "" && theme == "tribal" {
+			// Handle tribal synergy specially
+			found[i] = findTribalSynergies(ctx, client, mainCard, opts, pattern)
+		} else if pattern.SynergyQuery != "" || pattern.EnablerQuery != "" || pattern.PayoffQuery != "" {
+			category := complementarySynergyCategory(theme, pattern, mainRoles[theme])
+			if category.query == "" {
+				return
+			}
+			themeQuery := fmt.Sprintf(`%s -name:"%s"`, category.query, mainCard.Name)
+			log.Printf("Searching for theme synergy: %s (%s)", theme, category.SynergyType)
+			reportProgress(ctx, fmt.Sprintf("Searching for %s synergies", theme))
+			themeCards, err := searchSynergyCandidates(ctx, client, themeQuery, opts)
+			if err == nil && len(themeCards) > 0 {
+				category.Cards = themeCards
+				category.Count = len(themeCards)
+				found[i] = []SynergyCategory{category.SynergyCategory}
+				log.Printf("Found %d cards for %s theme", len(themeCards), theme)
 			}
 		}
+	})
+	for _, categories := range found {
+		synergies = append(synergies, categories...)
 	}
 	return synergies
 }
 
+// themeSearch is a synergy category together with the query that fills it
+type themeSearch struct {
+	SynergyCategory
+	query string
+}
+
+// complementarySynergyCategory picks which side of a theme to search for:
+// payoffs when the main card is an enabler, enablers when it is a payoff,
+// and the general synergy query otherwise.
+func complementarySynergyCategory(theme string, pattern ThemePattern, mainRole string) themeSearch {
+	switch {
+	case mainRole == ThemeRoleEnabler && pattern.PayoffQuery != "":
+		return themeSearch{
+			SynergyCategory: SynergyCategory{
+				SynergyType: pattern.SynergyType + " (Payoffs)",
+				Description: fmt.Sprintf("Cards that pay off the %s theme this card enables", theme),
+				Role:        ThemeRolePayoff,
+			},
+			query: pattern.PayoffQuery,
+		}
+	case mainRole == ThemeRolePayoff && pattern.EnablerQuery != "":
+		return themeSearch{
+			SynergyCategory: SynergyCategory{
+				SynergyType: pattern.SynergyType + " (Enablers)",
+				Description: fmt.Sprintf("Cards that enable the %s theme this card pays off", theme),
+				Role:        ThemeRoleEnabler,
+			},
+			query: pattern.EnablerQuery,
+		}
+	}
+	return themeSearch{
+		SynergyCategory: SynergyCategory{
+			SynergyType: pattern.SynergyType,
+			Description: pattern.SynergyDescription,
+		},
+		query: pattern.SynergyQuery,
+	}
+}
+
 // findColorIdentitySynergies searches for cards with matching color identity
 func findColorIdentitySynergies(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, synergies []SynergyCategory) []SynergyCategory {
-	if mainCard.Colors != nil && len(mainCard.Colors) > 0 && len(synergies) < 4 {
+	if ctx.Err() != nil {
+		return synergies
+	}
+	if mainCard.Colors != nil && len(mainCard.Colors) > 0 {
 		colorStr := ""
 		for _, color := range mainCard.Colors {
 			colorStr += string(color)
@@ -445,18 +605,350 @@ func findColorIdentitySynergies(ctx context.Context, client *scryfall.Client, ma
 		if colorStr != "" {
 			colorQuery := fmt.Sprintf(`color:%s -name:"%s"`, colorStr, mainCard.Name)
 			log.Printf("Searching for color identity synergy: %s", colorStr)
-			colorCards, err := client.SearchCards(ctx, colorQuery, opts)
-			if err == nil && len(colorCards.Cards) > 0 {
+			reportProgress(ctx, "Searching for cards in the same colors")
+			colorCards, err := searchSynergyCandidates(ctx, client, colorQuery, opts)
+			if err == nil && len(colorCards) > 0 {
 				synergies = append(synergies, SynergyCategory{
 					SynergyType: "Color Identity Synergy",
 					Description: fmt.Sprintf("Cards that share the same color identity"),
-					Cards:       limitCards(colorCards.Cards, 5),
-					Count:       len(colorCards.Cards),
+					Cards:       colorCards,
+					Count:       len(colorCards),
 				})
-				log.Printf("Found %d cards with matching colors", len(colorCards.Cards))
+				log.Printf("Found %d cards with matching colors", len(colorCards))
 			}
 		}
 	}
 	return synergies
 }
 
+// allRelationTypes lists every relation type supported by find_related_cards
+var allRelationTypes = []string{
+	"reprints", "tokens", "mechanics", "same_set", "same_artist", "same_name_variants",
+	"meld_and_combo_parts", "functional_reprints", "same_cycle", "referenced_cards",
+}
+
+// Longest "named ..." phrase split into name candidates; a longer list of
+// names is cut short rather than multiplying the lookups
+const maxReferenceSegments = 6
+
+var (
+	colorWordRegex      = regexp.MustCompile(`(?i)\b(white|blue|black|red|green)\b`)
+	colorSymbolRegex    = regexp.MustCompile(`(?i)\{[wubrg]\}`)
+	basicLandRegex      = regexp.MustCompile(`(?i)\b(plains|island|swamp|mountain|forest)s?\b`)
+	whitespaceRegex     = regexp.MustCompile(`\s+`)
+	namedCardRegex      = regexp.MustCompile(`named ([A-Z][^.;:()\n]*)`)
+	referenceSplitRegex = regexp.MustCompile(`,? (?:and|or) |, `)
+	reminderTextRegex   = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// normalizeOracleText replaces self-references with CARDNAME, drops reminder
+// text and collapses whitespace so that cards can be compared by rules text.
+func normalizeOracleText(card scryfall.Card) string {
+	text := cardOracleText(card)
+	for _, name := range cardSelfNames(card) {
+		text = strings.ReplaceAll(text, name, "CARDNAME")
+	}
+	text = reminderTextRegex.ReplaceAllString(text, "")
+	text = whitespaceRegex.ReplaceAllString(text, " ")
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// cardOracleText returns the oracle text of a card, joining the faces of
+// multi-faced cards.
+func cardOracleText(card scryfall.Card) string {
+	if card.OracleText != "" || len(card.CardFaces) == 0 {
+		return card.OracleText
+	}
+	faces := []string{}
+	for _, face := range card.CardFaces {
+		if face.OracleText != nil && *face.OracleText != "" {
+			faces = append(faces, *face.OracleText)
+		}
+	}
+	return strings.Join(faces, "\n")
+}
+
+// cardSelfNames returns the names a card uses to refer to itself, longest
+// first: the full name, each face name and the short name of legends
+// ("Urza" for "Urza, Lord High Artificer").
+func cardSelfNames(card scryfall.Card) []string {
+	names := []string{card.Name}
+	for _, face := range card.CardFaces {
+		if face.Name != "" && !contains(names, face.Name) {
+			names = append(names, face.Name)
+		}
+	}
+	for _, name := range names {
+		if i := strings.Index(name, ","); i > 0 && !contains(names, name[:i]) {
+			names = append(names, name[:i])
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return names
+}
+
+// cycleTemplate normalizes the color-specific parts of a card's rules text so
+// that members of a cycle produce the same template.
+func cycleTemplate(card scryfall.Card) string {
+	text := normalizeOracleText(card)
+	text = colorSymbolRegex.ReplaceAllString(text, "{color}")
+	text = basicLandRegex.ReplaceAllString(text, "landtype")
+	return colorWordRegex.ReplaceAllString(text, "color")
+}
+
+// longestOracleLine returns the longest line of the normalized oracle text,
+// which is the most selective fragment to search on.
+func longestOracleLine(card scryfall.Card) string {
+	longest := ""
+	for _, line := range strings.Split(cardOracleText(card), "\n") {
+		line = reminderTextRegex.ReplaceAllString(line, "")
+		if len(line) > len(longest) {
+			longest = line
+		}
+	}
+	for _, name := range cardSelfNames(card) {
+		longest = strings.ReplaceAll(longest, name, "~")
+	}
+	return strings.TrimSpace(strings.ReplaceAll(longest, `"`, ""))
+}
+
+// findNameVariantCards searches for printings of the card with different artwork or frames
+func findNameVariantCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, maxResults int) *RelatedCardCategory {
+	log.Printf("Searching for art and frame variants of %s", mainCard.Name)
+	variantQuery := fmt.Sprintf(`!"%s"`, mainCard.Name)
+	variantOpts := scryfall.SearchCardsOptions{
+		Unique:            scryfall.UniqueModePrints,
+		IncludeVariations: true,
+	}
+	variants, err := client.SearchCards(ctx, variantQuery, variantOpts)
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{variantKey(mainCard): true}
+	variantCards := []scryfall.Card{}
+	for _, card := range variants.Cards {
+		key := variantKey(card)
+		if card.ID == mainCard.ID || seen[key] {
+			continue
+		}
+		seen[key] = true
+		variantCards = append(variantCards, card)
+	}
+
+	if len(variantCards) > 0 {
+		log.Printf("Found %d art or frame variants", len(variantCards))
+		return &RelatedCardCategory{
+			CategoryName: "Art and Frame Variants",
+			Cards:        limitCards(variantCards, maxResults),
+			Count:        len(variantCards),
+		}
+	}
+	return nil
+}
+
+// variantKey identifies the visual treatment of a printing
+func variantKey(card scryfall.Card) string {
+	illustration := ""
+	if card.IllustrationID != nil {
+		illustration = *card.IllustrationID
+	}
+	effects := []string{}
+	for _, effect := range card.FrameEffects {
+		effects = append(effects, string(effect))
+	}
+	sort.Strings(effects)
+	return fmt.Sprintf("%s|%s|%s|%s|%t", illustration, card.Frame, strings.Join(effects, ","), card.BorderColor, card.FullArt)
+}
+
+// findMeldAndComboParts fetches the meld partners, meld result and combo pieces listed on a card
+func findMeldAndComboParts(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, maxResults int) *RelatedCardCategory {
+	if mainCard.AllParts == nil {
+		return nil
+	}
+
+	log.Printf("Searching for meld and combo parts of %s", mainCard.Name)
+	partIDs := []string{}
+	for _, part := range mainCard.AllParts {
+		if part.ID == mainCard.ID || part.Name == mainCard.Name {
+			continue
+		}
+		switch part.Component {
+		case scryfall.ComponentMeldPart, scryfall.ComponentMeldResult, scryfall.ComponentComboPiece:
+			partIDs = append(partIDs, part.ID)
+		}
+	}
+	partCards := fetchCardsByID(ctx, client, partIDs)
+	if len(partCards) > 0 {
+		log.Printf("Found %d meld or combo parts", len(partCards))
+		return &RelatedCardCategory{
+			CategoryName: "Meld and Combo Parts",
+			Cards:        limitCards(partCards, maxResults),
+			Count:        len(partCards),
+		}
+	}
+	return nil
+}
+
+// findFunctionalReprintCards searches for differently named cards with identical rules text
+func findFunctionalReprintCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, maxResults int) *RelatedCardCategory {
+	line := longestOracleLine(mainCard)
+	if line == "" {
+		return nil
+	}
+
+	log.Printf("Searching for functional reprints of %s", mainCard.Name)
+	query := fmt.Sprintf(`oracle:"%s" -oracleid:%s`, line, mainCard.OracleID)
+	candidates, err := client.SearchCards(ctx, query, opts)
+	if err != nil {
+		return nil
+	}
+
+	target := normalizeOracleText(mainCard)
+	reprints := []scryfall.Card{}
+	for _, card := range candidates.Cards {
+		if card.OracleID != mainCard.OracleID && normalizeOracleText(card) == target {
+			reprints = append(reprints, card)
+		}
+	}
+	if len(reprints) > 0 {
+		log.Printf("Found %d functional reprints", len(reprints))
+		return &RelatedCardCategory{
+			CategoryName: "Functional Reprints",
+			Cards:        limitCards(reprints, maxResults),
+			Count:        len(reprints),
+		}
+	}
+	return nil
+}
+
+// findCycleCards searches the card's set for cards of the same type and rarity
+// whose rules text matches once colors and basic land types are abstracted.
+func findCycleCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, maxResults int) *RelatedCardCategory {
+	if mainCard.Set == "" || cardOracleText(mainCard) == "" {
+		return nil
+	}
+
+	log.Printf("Searching for cycle members of %s in set %s", mainCard.Name, mainCard.Set)
+	query := fmt.Sprintf(`set:%s rarity:%s -oracleid:%s`, mainCard.Set, mainCard.Rarity, mainCard.OracleID)
+	candidates, err := client.SearchCards(ctx, query, opts)
+	if err != nil {
+		return nil
+	}
+
+	template := cycleTemplate(mainCard)
+	cycleCards := []scryfall.Card{}
+	for _, card := range candidates.Cards {
+		if !sameCardTypes(card.TypeLine, mainCard.TypeLine) {
+			continue
+		}
+		if cycleTemplate(card) == template {
+			cycleCards = append(cycleCards, card)
+		}
+	}
+	if len(cycleCards) > 0 {
+		log.Printf("Found %d cycle members", len(cycleCards))
+		return &RelatedCardCategory{
+			CategoryName: fmt.Sprintf("Same Cycle (%s)", mainCard.SetName),
+			Cards:        limitCards(cycleCards, maxResults),
+			Count:        len(cycleCards),
+		}
+	}
+	return nil
+}
+
+// findReferencedCards looks up cards mentioned by name in the oracle text ("a card named ...")
+func findReferencedCards(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, maxResults int) *RelatedCardCategory {
+	matches := namedCardRegex.FindAllStringSubmatch(cardOracleText(mainCard), -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	log.Printf("Searching for cards referenced by %s", mainCard.Name)
+	referenced := []scryfall.Card{}
+	seen := map[string]bool{}
+	for _, card := range lookupReferencedCards(ctx, client, matches) {
+		if seen[card.OracleID] || card.OracleID == mainCard.OracleID {
+			continue
+		}
+		seen[card.OracleID] = true
+		referenced = append(referenced, card)
+	}
+	if len(referenced) > 0 {
+		log.Printf("Found %d referenced cards", len(referenced))
+		return &RelatedCardCategory{
+			CategoryName: "Referenced Cards",
+			Cards:        limitCards(referenced, maxResults),
+			Count:        len(referenced),
+		}
+	}
+	return nil
+}
+
+// lookupReferencedCards splits "named ..." phrases into card names. Names can
+// themselves contain commas ("Urza, Lord High Artificer") and phrases can
+// list several names ("Rat Colony and Relentless Rats"), so the longest run
+// of capitalized segments that is exactly a card's name wins. Every run is
+// looked up at once: in the offline card data, and the rest in one batched
+// Scryfall collection request.
+func lookupReferencedCards(ctx context.Context, client *scryfall.Client, matches [][]string) []scryfall.Card {
+	phrases := [][]string{}
+	candidates := []string{}
+	seen := map[string]bool{}
+	for _, match := range matches {
+		segments := referenceSplitRegex.Split(match[1], -1)
+		if len(segments) > maxReferenceSegments {
+			segments = segments[:maxReferenceSegments]
+		}
+		phrases = append(phrases, segments)
+		for i := range segments {
+			for j := i + 1; j <= len(segments); j++ {
+				candidate := strings.Join(segments[i:j], ", ")
+				if startsUpper(segments[i]) && startsUpper(segments[j-1]) && !seen[candidate] {
+					seen[candidate] = true
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+
+	found := map[string]scryfall.Card{}
+	entries := []DeckEntry{}
+	for _, candidate := range candidates {
+		if card, ok := offlineCardByName(candidate); ok && cardNameMatches(card, candidate) {
+			found[candidate] = card
+		} else {
+			entries = append(entries, DeckEntry{Quantity: 1, Name: candidate})
+		}
+	}
+	if len(entries) > 0 && client != nil {
+		resolved, _, err := resolveDeckEntries(ctx, client, entries)
+		if err != nil {
+			log.Printf("Error looking up referenced cards: %v", err)
+		}
+		for i, card := range resolved {
+			found[entries[i].Name] = card
+		}
+	}
+
+	cards := []scryfall.Card{}
+	for _, segments := range phrases {
+		for i := 0; i < len(segments); i++ {
+			if !startsUpper(segments[i]) {
+				break
+			}
+			for j := len(segments); j > i; j-- {
+				if card, ok := found[strings.Join(segments[i:j], ", ")]; ok {
+					cards = append(cards, card)
+					i = j - 1
+					break
+				}
+			}
+		}
+	}
+	return cards
+}
+
+func startsUpper(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z'
+}
@@ -56,6 +56,16 @@ func init() {
 	relatedCardsSchema = relatedSchema
 	log.Println("Related cards output schema generated.")
 
+	// relation_type only accepts the relation types find_related_cards knows
+	relatedInputSchema, err := jsonschema.For[FindRelatedCardsArgs](nil)
+	if err != nil {
+		log.Fatalf("Failed to generate related cards input schema: %v", err)
+	}
+	for _, relation := range allRelationTypes {
+		relatedInputSchema.Properties["relation_type"].Items.Enum = append(relatedInputSchema.Properties["relation_type"].Items.Enum, relation)
+	}
+	relatedCardsInputSchema = relatedInputSchema
+
 	synergiesSchemaGen, err := jsonschema.For[FindCardSynergiesResult](&jsonschema.ForOptions{
 		TypeSchemas: typeSchemas,
 	})
@@ -66,4 +76,37 @@ func init() {
 
 	synergiesSchema = synergiesSchemaGen
 	log.Println("Card synergies output schema generated.")
-}
\ No newline at end of file
+
+	alternativesSchemaGen, err := jsonschema.For[FindFunctionalAlternativesResult](&jsonschema.ForOptions{
+		TypeSchemas: typeSchemas,
+	})
+
+	if err != nil {
+		log.Fatalf("Failed to generate functional alternatives schema: %v", err)
+	}
+
+	alternativesSchema = alternativesSchemaGen
+	log.Println("Functional alternatives output schema generated.")
+
+	commandersSchemaGen, err := jsonschema.For[SuggestCommandersResult](&jsonschema.ForOptions{
+		TypeSchemas: typeSchemas,
+	})
+
+	if err != nil {
+		log.Fatalf("Failed to generate commander suggestions schema: %v", err)
+	}
+
+	commandersSchema = commandersSchemaGen
+	log.Println("Commander suggestions output schema generated.")
+
+	randomCardSchemaGen, err := jsonschema.For[RandomCardResult](&jsonschema.ForOptions{
+		TypeSchemas: typeSchemas,
+	})
+
+	if err != nil {
+		log.Fatalf("Failed to generate random card schema: %v", err)
+	}
+
+	randomCardSchema = randomCardSchemaGen
+	log.Println("Random card output schema generated.")
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Legality statuses reported by Scryfall, plus the status used in snapshots
+// for cards on neither list
+const (
+	LegalityLegal      = "legal"
+	LegalityNotLegal   = "not_legal"
+	LegalityBanned     = "banned"
+	LegalityRestricted = "restricted"
+	LegalityUnlisted   = "unlisted"
+)
+
+// Snapshots are taken once a day; this is how often to check whether
+// today's snapshot is due
+const legalitySnapshotCheckInterval = time.Hour
+
+// Every card's legality comes from Scryfall's Oracle Cards bulk data file,
+// which has one entry per card. The latest statuses are kept in
+// legalityStatusesFile, so that each snapshot records what changed since.
+const (
+	legalityBulkDataType = "oracle_cards"
+	legalityStatusesFile = "legality-statuses.json"
+)
+
+// legalitySnapshotDir is the directory snapshots are kept in, if configured
+var legalitySnapshotDir string
+
+// legalityFormatNames are the display names of the formats Scryfall reports
+var legalityFormatNames = map[string]string{
+	"standard":  "Standard",
+	"future":    "Future Standard",
+	"pioneer":   "Pioneer",
+	"modern":    "Modern",
+	"legacy":    "Legacy",
+	"pauper":    "Pauper",
+	"vintage":   "Vintage",
+	"penny":     "Penny Dreadful",
+	"commander": "Commander",
+	"duel":      "Duel Commander",
+}
+
+// Rotating formats, where a card that is not legal usually was never printed
+// in or has rotated out of the format's sets
+var rotatingFormats = []string{"standard", "future", "pioneer", "modern"}
+
+// legalitySnapshot is the format of the files in MCP_LEGALITY_SNAPSHOT_DIR:
+// the banned and restricted lists of every format on one day, and when the
+// previous day's statuses of every card were known, every card whose status
+// in a format changed since
+type legalitySnapshot struct {
+	Date             string                   `json:"date"`
+	Formats          map[string]FormatBanList `json:"formats"`
+	StatusesCompared bool                     `json:"statuses_compared,omitempty"`
+	StatusChanges    []LegalityChange         `json:"status_changes,omitempty"`
+}
+
+// legalityStatuses is the format of legalityStatusesFile: each card's status
+// in every format where it is not not_legal, by card name
+type legalityStatuses struct {
+	Date  string                       `json:"date"`
+	Cards map[string]map[string]string `json:"cards"`
+}
+
+// legalityFormats returns the formats Scryfall reports, in alphabetical order
+func legalityFormats() []string {
+	return sortedKeys(legalityFormatNames)
+}
+
+// parseLegalityFormat matches a format by key or display name
+func parseLegalityFormat(input string) (string, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	for format, name := range legalityFormatNames {
+		if input == format || input == strings.ToLower(name) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format '%s'; use one of %s", input, strings.Join(legalityFormats(), ", "))
+}
+
+// searchCardNames returns the sorted names of every card matching a query
+func searchCardNames(ctx context.Context, client *scryfall.Client, query string) ([]string, error) {
+	cards, err := searchAllCards(ctx, client, query, scryfall.SearchCardsOptions{Unique: scryfall.UniqueModeCards})
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, card := range cards {
+		names = append(names, card.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// fetchFormatBanList looks up the banned and restricted cards of a format
+func fetchFormatBanList(ctx context.Context, client *scryfall.Client, format string) (FormatBanList, error) {
+	log.Printf("Fetching ban list for %s", format)
+	banned, err := searchCardNames(ctx, client, "banned:"+format)
+	if err != nil {
+		return FormatBanList{}, err
+	}
+	restricted, err := searchCardNames(ctx, client, "restricted:"+format)
+	if err != nil {
+		return FormatBanList{}, err
+	}
+	return FormatBanList{Format: format, Banned: banned, Restricted: restricted}, nil
+}
+
+// legalityExplanation describes why a card has its legality in a format
+func legalityExplanation(card scryfall.Card, format, status string) string {
+	name := legalityFormatNames[format]
+	switch status {
+	case LegalityLegal:
+		return fmt.Sprintf("Legal in %s", name)
+	case LegalityBanned:
+		return fmt.Sprintf("On the %s banned list", name)
+	case LegalityRestricted:
+		return fmt.Sprintf("Restricted to one copy in %s", name)
+	}
+
+	notLegalAnywhere := true
+	for _, other := range cardLegalities(card) {
+		if other != LegalityNotLegal {
+			notLegalAnywhere = false
+			break
+		}
+	}
+	switch {
+	case notLegalAnywhere:
+		return "Not legal in any format, for example because it is a silver-bordered, acorn or other non-tournament card"
+	case format == "pauper":
+		return "Never printed at common, so not legal in Pauper"
+	case format == "penny":
+		return "Too expensive on Magic Online to be in the current Penny Dreadful season"
+	case contains(rotatingFormats, format):
+		return fmt.Sprintf("Not printed in a set that is legal in %s", name)
+	case (format == "commander" || format == "duel") && strings.Contains(card.TypeLine, "Conspiracy"):
+		return fmt.Sprintf("Conspiracy cards are not legal in %s", name)
+	}
+	return fmt.Sprintf("Not legal in %s", name)
+}
+
+// cardFormatLegalities lists a card's legality in every format with an
+// explanation of each
+func cardFormatLegalities(card scryfall.Card) []FormatLegality {
+	legalities := cardLegalities(card)
+	result := []FormatLegality{}
+	for _, format := range legalityFormats() {
+		status, ok := legalities[format]
+		if !ok || status == "" {
+			continue
+		}
+		result = append(result, FormatLegality{
+			Format:      format,
+			Status:      status,
+			Explanation: legalityExplanation(card, format, status),
+		})
+	}
+	return result
+}
+
+// snapshotStatus returns a card's status in a format in a snapshot: banned,
+// restricted, or unlisted when it is on neither list
+func snapshotStatus(list FormatBanList, cardName string) string {
+	for _, name := range list.Banned {
+		if strings.EqualFold(name, cardName) {
+			return LegalityBanned
+		}
+	}
+	for _, name := range list.Restricted {
+		if strings.EqualFold(name, cardName) {
+			return LegalityRestricted
+		}
+	}
+	return LegalityUnlisted
+}
+
+// legalityChanges reports each time the card's legality changed. Snapshots
+// that were compared against the previous day's statuses of every card
+// record each change, including rotation; for older snapshots, consecutive
+// ban lists are compared, which only shows the card moving on or off a
+// banned or restricted list. format limits the changes to one format when
+// set.
+func legalityChanges(snapshots []legalitySnapshot, cardName, format string) []LegalityChange {
+	changes := []LegalityChange{}
+	last := map[string]string{}
+	for _, snapshot := range snapshots {
+		if snapshot.StatusesCompared {
+			for _, change := range snapshot.StatusChanges {
+				if strings.EqualFold(change.Card, cardName) && (format == "" || change.Format == format) {
+					changes = append(changes, change)
+				}
+			}
+		}
+		for _, f := range sortedKeys(snapshot.Formats) {
+			if format != "" && f != format {
+				continue
+			}
+			status := snapshotStatus(snapshot.Formats[f], cardName)
+			if previous, ok := last[f]; ok && previous != status && !snapshot.StatusesCompared {
+				changes = append(changes, LegalityChange{Date: snapshot.Date, Format: f, Card: cardName, From: previous, To: status})
+			}
+			last[f] = status
+		}
+	}
+	return changes
+}
+
+// decodeLegalityStatuses reads each card's status in every known format from
+// a Scryfall bulk data file, leaving out not_legal
+func decodeLegalityStatuses(r io.Reader) (map[string]map[string]string, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("not a Scryfall bulk data file: expected a JSON array of cards")
+	}
+
+	statuses := map[string]map[string]string{}
+	for decoder.More() {
+		var card struct {
+			Name       string            `json:"name"`
+			Legalities map[string]string `json:"legalities"`
+		}
+		if err := decoder.Decode(&card); err != nil {
+			return nil, fmt.Errorf("card %d: %w", len(statuses)+1, err)
+		}
+		formats := map[string]string{}
+		for format, status := range card.Legalities {
+			if _, ok := legalityFormatNames[format]; ok && status != LegalityNotLegal {
+				formats[format] = status
+			}
+		}
+		statuses[card.Name] = formats
+	}
+	return statuses, nil
+}
+
+// fetchLegalityStatuses downloads Scryfall's Oracle Cards bulk data file and
+// reads every card's legality from it
+func fetchLegalityStatuses(ctx context.Context, client *scryfall.Client) (map[string]map[string]string, error) {
+	bulk, err := client.GetBulkDataByType(ctx, legalityBulkDataType)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bulk.DownloadURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Downloading card legalities from %s", bulk.DownloadURI)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", bulk.DownloadURI, resp.Status)
+	}
+	return decodeLegalityStatuses(resp.Body)
+}
+
+// statusBanLists builds the banned and restricted lists of every format from
+// each card's statuses
+func statusBanLists(statuses map[string]map[string]string) map[string]FormatBanList {
+	lists := map[string]FormatBanList{}
+	for _, format := range legalityFormats() {
+		list := FormatBanList{Format: format, Banned: []string{}, Restricted: []string{}}
+		for _, name := range sortedKeys(statuses) {
+			switch statuses[name][format] {
+			case LegalityBanned:
+				list.Banned = append(list.Banned, name)
+			case LegalityRestricted:
+				list.Restricted = append(list.Restricted, name)
+			}
+		}
+		lists[format] = list
+	}
+	return lists
+}
+
+// statusChanges reports every card whose status in a format differs between
+// two days' statuses. Cards missing from either day, such as newly released
+// ones, are left out.
+func statusChanges(previous, current map[string]map[string]string, date string) []LegalityChange {
+	changes := []LegalityChange{}
+	for _, name := range sortedKeys(current) {
+		before, ok := previous[name]
+		if !ok {
+			continue
+		}
+		for _, format := range legalityFormats() {
+			from, to := before[format], current[name][format]
+			if from == "" {
+				from = LegalityNotLegal
+			}
+			if to == "" {
+				to = LegalityNotLegal
+			}
+			if from != to {
+				changes = append(changes, LegalityChange{Date: date, Format: format, Card: name, From: from, To: to})
+			}
+		}
+	}
+	return changes
+}
+
+// loadLegalityStatuses reads the statuses saved by the last snapshot
+func loadLegalityStatuses(dir string) (legalityStatuses, error) {
+	var statuses legalityStatuses
+	data, err := os.ReadFile(filepath.Join(dir, legalityStatusesFile))
+	if err != nil {
+		return statuses, err
+	}
+	err = json.Unmarshal(data, &statuses)
+	return statuses, err
+}
+
+// banListChanges reports every card that moved on or off the format's banned
+// or restricted list, from the changes a snapshot recorded or else by
+// comparing consecutive ban lists
+func banListChanges(snapshots []legalitySnapshot, format string) []LegalityChange {
+	changes := []LegalityChange{}
+	var previous *FormatBanList
+	for _, snapshot := range snapshots {
+		list, ok := snapshot.Formats[format]
+		if !ok {
+			continue
+		}
+		if snapshot.StatusesCompared {
+			for _, change := range snapshot.StatusChanges {
+				banList := []string{LegalityBanned, LegalityRestricted}
+				if change.Format == format && (contains(banList, change.From) || contains(banList, change.To)) {
+					changes = append(changes, change)
+				}
+			}
+		} else if previous != nil {
+			names := map[string]bool{}
+			for _, l := range []FormatBanList{*previous, list} {
+				for _, name := range append(append([]string{}, l.Banned...), l.Restricted...) {
+					names[name] = true
+				}
+			}
+			for _, name := range sortedKeys(names) {
+				from, to := snapshotStatus(*previous, name), snapshotStatus(list, name)
+				if from != to {
+					changes = append(changes, LegalityChange{Date: snapshot.Date, Format: format, Card: name, From: from, To: to})
+				}
+			}
+		}
+		previous = &list
+	}
+	return changes
+}
+
+// legalitySnapshotPath returns the file a snapshot for date is stored in
+func legalitySnapshotPath(dir, date string) string {
+	return filepath.Join(dir, "legality-"+date+".json")
+}
+
+// loadLegalitySnapshots reads every snapshot in dir, oldest first
+func loadLegalitySnapshots(dir string) ([]legalitySnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "legality-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	snapshots := []legalitySnapshot{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading legality snapshot %s: %v", file, err)
+			continue
+		}
+		var snapshot legalitySnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Printf("Error parsing legality snapshot %s: %v", file, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Date < snapshots[j].Date })
+	return snapshots, nil
+}
+
+// takeLegalitySnapshot fetches every card's legality and stores today's ban
+// lists in dir, with every status change since the statuses saved last
+// time. When the bulk data can't be downloaded, the ban lists are searched
+// for instead and the snapshot records no other changes.
+func takeLegalitySnapshot(ctx context.Context, dir string) error {
+	client, err := newScryfallClient()
+	if err != nil {
+		return err
+	}
+
+	snapshot := legalitySnapshot{
+		Date:    time.Now().UTC().Format(time.DateOnly),
+		Formats: map[string]FormatBanList{},
+	}
+	statuses, err := fetchLegalityStatuses(ctx, client)
+	if err != nil {
+		log.Printf("Error fetching card legalities, taking a ban-list snapshot only: %v", err)
+		for _, format := range legalityFormats() {
+			list, err := fetchFormatBanList(ctx, client, format)
+			if err != nil {
+				return fmt.Errorf("fetching %s ban list: %w", format, err)
+			}
+			snapshot.Formats[format] = list
+		}
+	} else {
+		snapshot.Formats = statusBanLists(statuses)
+		if previous, err := loadLegalityStatuses(dir); err == nil {
+			snapshot.StatusesCompared = true
+			snapshot.StatusChanges = statusChanges(previous.Cards, statuses, snapshot.Date)
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := legalitySnapshotPath(dir, snapshot.Date)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	log.Printf("Saved legality snapshot %s with %d status changes", path, len(snapshot.StatusChanges))
+
+	if statuses != nil {
+		data, err := json.Marshal(legalityStatuses{Date: snapshot.Date, Cards: statuses})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, legalityStatusesFile), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legalitySnapshotDue reports whether today's snapshot has not been taken yet
+func legalitySnapshotDue(dir string) bool {
+	_, err := os.Stat(legalitySnapshotPath(dir, time.Now().UTC().Format(time.DateOnly)))
+	return err != nil
+}
+
+// snapshotLegalities takes a legality snapshot once a day, checking every
+// interval whether one is due, until ctx is cancelled
+func snapshotLegalities(ctx context.Context, dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if legalitySnapshotDue(dir) {
+			if err := takeLegalitySnapshot(ctx, dir); err != nil {
+				log.Printf("Error taking legality snapshot: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setupLegalitySnapshots starts taking daily legality snapshots in the
+// configured directory, unless it is set to off
+func setupLegalitySnapshots(config *Config) {
+	if config.LegalitySnapshotDir == "" || config.LegalitySnapshotDir == "off" {
+		return
+	}
+
+	legalitySnapshotDir = config.LegalitySnapshotDir
+	log.Printf("Taking legality snapshots in %s", config.LegalitySnapshotDir)
+	go snapshotLegalities(context.Background(), config.LegalitySnapshotDir, legalitySnapshotCheckInterval)
+}
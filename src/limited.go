@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Sealed and limited deck sizes
+const (
+	sealedBoosterCount   = 6
+	limitedSpellCount    = 23
+	limitedLandCount     = 17
+	maxPoolNonbasics     = 3
+	defaultLimitedBuilds = 3
+	minLimitedBuilds     = 2
+)
+
+// Creature count a limited deck aims for
+const (
+	minLimitedCreatures = 14
+	maxLimitedCreatures = 18
+)
+
+// Card and build score weights
+const (
+	limitedRemovalWeight    = 2.0
+	limitedEvasionWeight    = 0.5
+	limitedCardDrawWeight   = 0.5
+	limitedExpensivePenalty = 1.0
+	limitedCurveWeight      = 0.75
+	limitedCreatureWeight   = 1.0
+	limitedMissingSpell     = 3.0
+)
+
+// limitedRarityScores is the base score of a card by rarity, standing in for
+// pick ratings
+var limitedRarityScores = map[string]float64{
+	"common": 1.0, "uncommon": 1.5, "rare": 2.5, "mythic": 3.0, "special": 2.0, "bonus": 2.0,
+}
+
+// limitedCurveBuckets are the mana values a limited curve is grouped by, with
+// the number of spells of each a 23-spell deck aims for
+var (
+	limitedCurveBuckets = []string{"1", "2", "3", "4", "5", "6+"}
+	limitedCurveTargets = map[string]int{"1": 1, "2": 6, "3": 5, "4": 4, "5": 3, "6+": 2}
+)
+
+// limitedEvasionKeywords are keywords that make a creature better in limited
+var limitedEvasionKeywords = []string{"flying", "menace", "trample", "skulk", "shadow"}
+
+// limitedCard is a pool card with its limited rating
+type limitedCard struct {
+	card     scryfall.Card
+	score    float64
+	removal  bool
+	creature bool
+	reasons  []string
+}
+
+// limitedRemovalPatterns returns the patterns of the removal and burn themes
+// that identify cards dealing with opposing creatures
+func limitedRemovalPatterns() []string {
+	patterns := []string{}
+	themes := loadThemePatterns()
+	for _, theme := range []string{"removal", "burn"} {
+		patterns = append(patterns, themes[theme].Patterns...)
+		patterns = append(patterns, themes[theme].EnablerPatterns...)
+	}
+	return patterns
+}
+
+func isBasicLand(card scryfall.Card) bool {
+	faces := parseTypeLine(card.TypeLine)
+	return len(faces) > 0 && faces[0].HasType("Land") && contains(faces[0].Supertypes, "Basic")
+}
+
+// isLandCard reports whether a card's front face is a land
+func isLandCard(card scryfall.Card) bool {
+	faces := parseTypeLine(card.TypeLine)
+	return len(faces) > 0 && faces[0].HasType("Land")
+}
+
+// limitedCurveBucket groups a mana value into the limited curve
+func limitedCurveBucket(manaValue float64) string {
+	switch {
+	case manaValue <= 1:
+		return "1"
+	case manaValue >= 6:
+		return "6+"
+	}
+	return fmt.Sprint(int(manaValue))
+}
+
+// rateLimitedCard scores a card for limited: its rarity, plus bonuses for
+// removal, evasion and card draw, minus a penalty for very expensive cards
+func rateLimitedCard(card scryfall.Card, removalPatterns []string) limitedCard {
+	rated := limitedCard{
+		card:     card,
+		creature: isCreatureCard(card),
+		score:    limitedRarityScores[card.Rarity],
+	}
+	if rated.score == 0 {
+		rated.score = limitedRarityScores["common"]
+	}
+
+	text := cardOracleText(card)
+	if anyPatternMatches(removalPatterns, text) {
+		rated.removal = true
+		rated.score += limitedRemovalWeight
+		rated.reasons = append(rated.reasons, "removal")
+	}
+	if rated.creature {
+		keywords := extractKeywordsFromText(text)
+		for _, keyword := range limitedEvasionKeywords {
+			if contains(keywords, keyword) {
+				rated.score += limitedEvasionWeight
+				rated.reasons = append(rated.reasons, keyword)
+				break
+			}
+		}
+	}
+	if contains(extractThemesFromCard(card), "card draw") {
+		rated.score += limitedCardDrawWeight
+		rated.reasons = append(rated.reasons, "card advantage")
+	}
+	if card.CMC >= 7 {
+		rated.score -= limitedExpensivePenalty
+		rated.reasons = append(rated.reasons, "expensive")
+	}
+	return rated
+}
+
+// limitedColorOptions returns every single color and two-color pair
+func limitedColorOptions() []string {
+	colors := []string{"W", "U", "B", "R", "G"}
+	options := append([]string{}, colors...)
+	for i := range colors {
+		for j := i + 1; j < len(colors); j++ {
+			options = append(options, colors[i]+colors[j])
+		}
+	}
+	return options
+}
+
+// withinColors reports whether every color of a card's identity is in colors
+func withinColors(card scryfall.Card, colors string) bool {
+	for _, color := range card.ColorIdentity {
+		if !strings.Contains(colors, string(color)) {
+			return false
+		}
+	}
+	return true
+}
+
+// limitedColorDepth summarizes how many playables, how much quality and how
+// much removal the pool has in each color
+func limitedColorDepth(pool []limitedCard) []ColorDepth {
+	depth := []ColorDepth{}
+	for _, color := range "WUBRG" {
+		entry := ColorDepth{Color: string(color)}
+		for _, rated := range pool {
+			if isLandCard(rated.card) || !strings.ContainsRune(colorIdentityString(rated.card), color) {
+				continue
+			}
+			entry.Playables++
+			entry.Score += rated.score
+			if rated.removal {
+				entry.Removal++
+			}
+			if rated.creature {
+				entry.Creatures++
+			}
+		}
+		entry.Score = roundCents(entry.Score)
+		depth = append(depth, entry)
+	}
+	sort.SliceStable(depth, func(i, j int) bool { return depth[i].Score > depth[j].Score })
+	return depth
+}
+
+// pickLimitedSpells chooses up to 23 spells greedily, adjusting each card's
+// score for how much the deck still needs creatures and cards at its mana
+// value
+func pickLimitedSpells(candidates []limitedCard) []limitedCard {
+	remaining := append([]limitedCard{}, candidates...)
+	picked := []limitedCard{}
+	curve := map[string]int{}
+	creatures := 0
+
+	for len(picked) < limitedSpellCount && len(remaining) > 0 {
+		best, bestScore := -1, 0.0
+		for i, rated := range remaining {
+			score := rated.score
+			bucket := limitedCurveBucket(rated.card.CMC)
+			if curve[bucket] < limitedCurveTargets[bucket] {
+				score += limitedCurveWeight
+			} else if curve[bucket] >= limitedCurveTargets[bucket]+2 {
+				score -= limitedCurveWeight
+			}
+			if rated.creature && creatures < minLimitedCreatures+1 {
+				score += limitedCreatureWeight
+			} else if rated.creature && creatures >= maxLimitedCreatures {
+				score -= limitedCreatureWeight
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		rated := remaining[best]
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		picked = append(picked, rated)
+		curve[limitedCurveBucket(rated.card.CMC)]++
+		if rated.creature {
+			creatures++
+		}
+	}
+	return picked
+}
+
+// limitedDeckCards groups picked cards by name, in the order first picked
+func limitedDeckCards(picked []limitedCard) []LimitedDeckCard {
+	cards := []LimitedDeckCard{}
+	index := map[string]int{}
+	for _, rated := range picked {
+		if i, ok := index[rated.card.Name]; ok {
+			cards[i].Quantity++
+			continue
+		}
+		index[rated.card.Name] = len(cards)
+		cards = append(cards, LimitedDeckCard{
+			Name:      rated.card.Name,
+			Quantity:  1,
+			ManaValue: rated.card.CMC,
+			ManaCost:  cardManaCost(rated.card),
+			TypeLine:  rated.card.TypeLine,
+			Rarity:    rated.card.Rarity,
+			Score:     roundCents(rated.score),
+			Notes:     rated.reasons,
+		})
+	}
+	return cards
+}
+
+// buildLimitedColors builds a 40-card deck in colors from the pool and scores
+// it, explaining the choices
+func buildLimitedColors(pool []limitedCard, colors string) LimitedDeckBuild {
+	candidates := []limitedCard{}
+	lands := []limitedCard{}
+	for _, rated := range pool {
+		switch {
+		case isBasicLand(rated.card):
+		case isLandCard(rated.card):
+			if colorIdentityString(rated.card) != "" && withinColors(rated.card, colors) {
+				lands = append(lands, rated)
+			}
+		case withinColors(rated.card, colors):
+			candidates = append(candidates, rated)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	spells := pickLimitedSpells(candidates)
+	build := LimitedDeckBuild{
+		Colors:    colors,
+		Curve:     map[string]int{},
+		Playables: len(candidates),
+	}
+
+	removal := []string{}
+	for _, rated := range spells {
+		build.Score += rated.score
+		build.Curve[limitedCurveBucket(rated.card.CMC)]++
+		if rated.creature {
+			build.CreatureCount++
+		}
+		if rated.removal {
+			build.RemovalCount++
+			removal = append(removal, rated.card.Name)
+		}
+	}
+	build.Spells = limitedDeckCards(spells)
+
+	reasoning := []string{fmt.Sprintf("%d playables in %s", len(candidates), colors)}
+	if missing := limitedSpellCount - len(spells); missing > 0 {
+		build.Score -= float64(missing) * limitedMissingSpell
+		reasoning = append(reasoning, fmt.Sprintf("Only %d playable spells; %d extra basic lands fill the deck", len(spells), missing))
+	}
+	switch {
+	case build.CreatureCount < minLimitedCreatures:
+		build.Score -= float64(minLimitedCreatures-build.CreatureCount) * limitedCreatureWeight
+		reasoning = append(reasoning, fmt.Sprintf("Only %d creatures; %d to %d is usual", build.CreatureCount, minLimitedCreatures, maxLimitedCreatures))
+	case build.CreatureCount > maxLimitedCreatures:
+		reasoning = append(reasoning, fmt.Sprintf("%d creatures, an aggressive build", build.CreatureCount))
+	default:
+		reasoning = append(reasoning, fmt.Sprintf("%d creatures", build.CreatureCount))
+	}
+	if len(removal) > 0 {
+		reasoning = append(reasoning, fmt.Sprintf("%d removal spells: %s", len(removal), strings.Join(removal, ", ")))
+	} else {
+		reasoning = append(reasoning, "No removal")
+	}
+	if build.Curve["2"] < 4 {
+		build.Score -= float64(4-build.Curve["2"]) * limitedCurveWeight
+		reasoning = append(reasoning, fmt.Sprintf("Light on two-drops (%d)", build.Curve["2"]))
+	}
+	if top := build.Curve["5"] + build.Curve["6+"]; top > 6 {
+		build.Score -= float64(top-6) * limitedCurveWeight
+		reasoning = append(reasoning, fmt.Sprintf("Top-heavy: %d cards cost five or more", top))
+	}
+	curve := []string{}
+	for _, bucket := range limitedCurveBuckets {
+		curve = append(curve, fmt.Sprintf("%s: %d", bucket, build.Curve[bucket]))
+	}
+	reasoning = append(reasoning, "Curve "+strings.Join(curve, ", "))
+
+	// Lands: on-color nonbasics from the pool, then basics split by the
+	// spells' colored mana symbols
+	landCount := limitedLandCount + max(limitedSpellCount-len(spells), 0)
+	sort.SliceStable(lands, func(i, j int) bool { return lands[i].score > lands[j].score })
+	if len(lands) > maxPoolNonbasics {
+		lands = lands[:maxPoolNonbasics]
+	}
+	build.Lands = limitedDeckCards(lands)
+
+	deckColors := []string{}
+	for _, color := range colors {
+		deckColors = append(deckColors, string(color))
+	}
+	costs := []string{}
+	for _, rated := range spells {
+		costs = append(costs, cardManaCost(rated.card))
+	}
+	basics, _, _ := splitBasicLands(costs, deckColors, landCount-len(lands))
+	for _, color := range deckColors {
+		if basics[color] > 0 {
+			build.Lands = append(build.Lands, LimitedDeckCard{
+				Name:     basicLandNames[color],
+				Quantity: basics[color],
+				TypeLine: "Basic Land",
+			})
+		}
+	}
+
+	build.Score = roundCents(build.Score)
+	build.Reasoning = reasoning
+	build.Decklist = limitedDecklist(build)
+	return build
+}
+
+// limitedDecklist writes a build as a plain-text decklist
+func limitedDecklist(build LimitedDeckBuild) string {
+	lines := []string{}
+	for _, card := range append(append([]LimitedDeckCard{}, build.Spells...), build.Lands...) {
+		lines = append(lines, fmt.Sprintf("%d %s", card.Quantity, card.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rankLimitedBuilds builds a deck in every color option and returns the best
+func rankLimitedBuilds(cards []scryfall.Card, count int) ([]LimitedDeckBuild, []ColorDepth) {
+	patterns := limitedRemovalPatterns()
+	pool := []limitedCard{}
+	for _, card := range cards {
+		pool = append(pool, rateLimitedCard(card, patterns))
+	}
+
+	builds := []LimitedDeckBuild{}
+	for _, colors := range limitedColorOptions() {
+		builds = append(builds, buildLimitedColors(pool, colors))
+	}
+	sort.SliceStable(builds, func(i, j int) bool { return builds[i].Score > builds[j].Score })
+	if len(builds) > count {
+		builds = builds[:count]
+	}
+	return builds, limitedColorDepth(pool)
+}
+
+// sealedPoolCards groups pool cards by name and summarizes them by color and
+// rarity
+func sealedPoolCards(cards []scryfall.Card) ([]PoolCard, map[string]int, map[string]int) {
+	pool := []PoolCard{}
+	index := map[string]int{}
+	colors := map[string]int{}
+	rarities := map[string]int{}
+	for _, card := range cards {
+		identity := colorIdentityString(card)
+		if card.TypeLine != "" || identity != "" {
+			if identity == "" {
+				colors["C"]++
+			}
+			for _, color := range identity {
+				colors[string(color)]++
+			}
+		}
+		if card.Rarity != "" {
+			rarities[card.Rarity]++
+		}
+
+		if i, ok := index[card.Name]; ok {
+			pool[i].Quantity++
+			continue
+		}
+		index[card.Name] = len(pool)
+		pool = append(pool, PoolCard{
+			Name:          card.Name,
+			Quantity:      1,
+			Rarity:        card.Rarity,
+			ColorIdentity: identity,
+			ManaCost:      cardManaCost(card),
+			TypeLine:      card.TypeLine,
+		})
+	}
+	return pool, colors, rarities
+}
+
+// poolDecklist writes a pool as a plain-text list that build_limited_deck
+// accepts
+func poolDecklist(pool []PoolCard) string {
+	lines := []string{}
+	for _, card := range pool {
+		lines = append(lines, fmt.Sprintf("%d %s", card.Quantity, card.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolvePoolCards parses a pasted pool and looks up its cards, repeating each
+// card once per copy
+func resolvePoolCards(ctx context.Context, client *scryfall.Client, text string) ([]scryfall.Card, []string, error) {
+	entries, err := parseDecklist(text)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, notFound, err := resolveDeckEntries(ctx, client, entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	cards := []scryfall.Card{}
+	for i, entry := range entries {
+		card, ok := resolved[i]
+		if !ok {
+			continue
+		}
+		for range entry.Quantity {
+			cards = append(cards, card)
+		}
+	}
+	return cards, notFound, nil
+}
+
+// findBoosterSet looks up the set to open boosters of and its definition for
+// the booster type. Sets missing from Scryfall and the bundled snapshot can
+// still be opened when they have a definition of their own.
+func findBoosterSet(ctx context.Context, client *scryfall.Client, setArg, boosterType string) (string, string, boosterDefinition, error) {
+	code, name := strings.ToLower(strings.TrimSpace(setArg)), ""
+	set, _, err := findSet(ctx, client, setArg)
+	if err == nil {
+		code, name = set.Code, set.Name
+	}
+
+	definition, ok := findBoosterDefinition(code, boosterType)
+	if !ok {
+		return "", "", boosterDefinition{}, fmt.Errorf("no %s booster definition for %s; use play, draft or set", boosterType, code)
+	}
+	if err != nil && definition.Set == defaultBoosterSet {
+		return "", "", boosterDefinition{}, err
+	}
+	return code, name, definition, nil
+}
+
+// openSealedPool opens six boosters of a set for a sealed pool
+func openSealedPool(ctx context.Context, client *scryfall.Client, setArg, boosterType string, seed int64) (SealedPoolResult, []scryfall.Card, error) {
+	code, name, definition, err := findBoosterSet(ctx, client, setArg, boosterType)
+	if err != nil {
+		return SealedPoolResult{}, nil, err
+	}
+
+	generator := newBoosterGenerator(ctx, client, code, seed)
+	result := SealedPoolResult{Set: code, SetName: name, Type: boosterType, Seed: &seed}
+	for i := range sealedBoosterCount {
+		result.Boosters = append(result.Boosters, generator.openBooster(definition, i+1))
+	}
+	if err := generator.checkOpened(); err != nil {
+		return SealedPoolResult{}, nil, err
+	}
+	result.Notes = generator.notes
+	return result, generator.opened, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+var (
+	untapEffectRegex  = regexp.MustCompile(`(?i)\buntap (target|another target|enchanted|equipped) (\w+)`)
+	manaEffectRegex   = regexp.MustCompile(`(?i)\badd ([^.]*)`)
+	manaAmountRegex   = regexp.MustCompile(`(?i)\b(one|two|three|four|five|six|seven|eight|nine|ten|\d+) mana\b`)
+	copyTokenRegex    = regexp.MustCompile(`(?i)create a token that's a copy of`)
+	pureManaCostRegex = regexp.MustCompile(`^(\{[0-9WUBRGCSX/]+\})+$`)
+)
+
+// comboPiece is a card with its parsed abilities
+type comboPiece struct {
+	card      scryfall.Card
+	abilities []CardAbility
+}
+
+func newComboPiece(card scryfall.Card) comboPiece {
+	return comboPiece{card: card, abilities: parseCardAbilities(card)}
+}
+
+// manaProduced estimates the mana a mana ability adds, e.g. 2 for
+// "Add {G}{G}." and 3 for "Add three mana of any one color."
+func manaProduced(effect string) int {
+	match := manaEffectRegex.FindStringSubmatch(effect)
+	if match == nil {
+		return 0
+	}
+	if symbols := manaSymbolRegex.FindAllString(match[1], -1); len(symbols) > 0 {
+		return len(symbols)
+	}
+	if amount := manaAmountRegex.FindStringSubmatch(match[1]); amount != nil {
+		word := strings.ToLower(amount[1])
+		if n, ok := numberWords[word]; ok {
+			word = n
+		}
+		n, _ := strconv.Atoi(word)
+		return n
+	}
+	return 0
+}
+
+// manaCostValue returns the total mana in a cost made up only of mana
+// symbols, such as 3 for "{2}{U}". Costs with other parts, such as tapping
+// or sacrificing, are reported as not payable with mana alone.
+func manaCostValue(cost string) (int, bool) {
+	cost = strings.ReplaceAll(cost, " ", "")
+	if !pureManaCostRegex.MatchString(cost) {
+		return 0, false
+	}
+	total := 0
+	for _, symbol := range manaSymbolRegex.FindAllString(cost, -1) {
+		inner := strings.Trim(symbol, "{}")
+		if n, err := strconv.Atoi(inner); err == nil {
+			total += n
+		} else if inner != "X" {
+			total++
+		}
+	}
+	return total, true
+}
+
+// untapReaches reports whether an untap effect can untap the given card,
+// e.g. "untap target artifact" reaches mana rocks but not mana creatures.
+func untapReaches(match []string, card scryfall.Card) bool {
+	target := strings.ToLower(strings.TrimSuffix(match[2], "s"))
+	if target == "permanent" {
+		return true
+	}
+	for _, face := range parseTypeLine(card.TypeLine) {
+		for _, cardType := range face.Types {
+			if strings.ToLower(cardType) == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectComboLoops checks a pair of cards, in both orders, for loops that
+// can be repeated any number of times
+func detectComboLoops(a, b comboPiece) []Combo {
+	combos := []Combo{}
+	for _, pair := range [][2]comboPiece{{a, b}, {b, a}} {
+		if combo, ok := detectManaUntapLoop(pair[0], pair[1]); ok {
+			combos = append(combos, combo)
+		}
+		if combo, ok := detectCopyUntapLoop(pair[0], pair[1]); ok {
+			combos = append(combos, combo)
+		}
+	}
+	return combos
+}
+
+// detectManaUntapLoop finds a permanent that taps for mana and a card that
+// untaps it for no more mana than it makes.
+func detectManaUntapLoop(tapper, untapper comboPiece) (Combo, bool) {
+	for _, tap := range tapper.abilities {
+		if tap.Kind != AbilityActivated || !strings.Contains(tap.Cost, "{T}") {
+			continue
+		}
+		produced := manaProduced(tap.Effect)
+		if produced == 0 {
+			continue
+		}
+		for _, untap := range untapper.abilities {
+			if untap.Kind != AbilityActivated {
+				continue
+			}
+			cost, ok := manaCostValue(untap.Cost)
+			match := untapEffectRegex.FindStringSubmatch(untap.Effect)
+			if !ok || match == nil || cost > produced || !untapReaches(match, tapper.card) {
+				continue
+			}
+
+			combo := Combo{
+				ID:            fmt.Sprintf("heuristic-untap:%s+%s", comboNameKey(tapper.card.Name), comboNameKey(untapper.card.Name)),
+				Cards:         []string{tapper.card.Name, untapper.card.Name},
+				Prerequisites: []string{fmt.Sprintf("%s can be tapped this turn", tapper.card.Name)},
+				Results:       []string{"infinite untap", "infinite tap triggers"},
+				Description: fmt.Sprintf("%s taps for %d mana (%s) and %s untaps it for %s.",
+					tapper.card.Name, produced, tap.Text, untapper.card.Name, untap.Cost),
+			}
+			// Abilities such as "add one mana for each ..." can make more
+			// than the minimum counted here
+			variable := strings.Contains(strings.ToLower(tap.Effect), "for each")
+			if cost < produced || variable {
+				combo.Results = append([]string{"infinite mana"}, combo.Results...)
+			}
+			if strings.EqualFold(match[1], "enchanted") || strings.EqualFold(match[1], "equipped") {
+				combo.Prerequisites = append(combo.Prerequisites, fmt.Sprintf("%s is attached to %s", untapper.card.Name, tapper.card.Name))
+			}
+			if variable {
+				combo.Prerequisites = append(combo.Prerequisites, fmt.Sprintf("%s makes more mana than %s", tapper.card.Name, untap.Cost))
+			} else if cost == produced {
+				combo.Prerequisites = append(combo.Prerequisites, "The mana must be usable for the untap cost; the loop nets no mana")
+			}
+			return combo, true
+		}
+	}
+	return Combo{}, false
+}
+
+// detectCopyUntapLoop finds a creature that taps to copy a creature and a
+// creature whose enters trigger untaps it, as with Kiki-Jiki and Pestermite.
+func detectCopyUntapLoop(copier, untapper comboPiece) (Combo, bool) {
+	if !isCreatureCard(untapper.card) {
+		return Combo{}, false
+	}
+	for _, copyAbility := range copier.abilities {
+		if copyAbility.Kind != AbilityActivated || !strings.Contains(copyAbility.Cost, "{T}") || !copyTokenRegex.MatchString(copyAbility.Effect) {
+			continue
+		}
+		effect := strings.ToLower(copyAbility.Effect)
+		if strings.Contains(effect, "nonlegendary") && isLegendaryCard(untapper.card) {
+			continue
+		}
+		for _, trigger := range untapper.abilities {
+			if trigger.Kind != AbilityTriggered || !strings.Contains(strings.ToLower(trigger.Trigger), "enters") {
+				continue
+			}
+			match := untapEffectRegex.FindStringSubmatch(trigger.Effect)
+			if match == nil || !untapReaches(match, copier.card) {
+				continue
+			}
+
+			results := []string{"infinite tokens", "infinite ETB"}
+			if strings.Contains(effect, "haste") {
+				results[0] = "infinite hasty tokens"
+			}
+			return Combo{
+				ID:            fmt.Sprintf("heuristic-copy:%s+%s", comboNameKey(copier.card.Name), comboNameKey(untapper.card.Name)),
+				Cards:         []string{copier.card.Name, untapper.card.Name},
+				Prerequisites: []string{fmt.Sprintf("%s can be tapped this turn", copier.card.Name)},
+				Results:       results,
+				Description: fmt.Sprintf("%s copies %s, and the copy's enters trigger untaps %s to copy it again.",
+					copier.card.Name, untapper.card.Name, copier.card.Name),
+			}, true
+		}
+	}
+	return Combo{}, false
+}
+
+func isCreatureCard(card scryfall.Card) bool {
+	for _, face := range parseTypeLine(card.TypeLine) {
+		if face.HasType("Creature") {
+			return true
+		}
+	}
+	return false
+}
+
+func isLegendaryCard(card scryfall.Card) bool {
+	faces := parseTypeLine(card.TypeLine)
+	return len(faces) > 0 && contains(faces[0].Supertypes, "Legendary")
+}
+
+// detectCardDeckLoops checks the main card against every card in a deck
+func detectCardDeckLoops(mainCard scryfall.Card, cards []scryfall.Card) []Combo {
+	main := newComboPiece(mainCard)
+	combos := []Combo{}
+	for _, card := range cards {
+		if cardKey(card) != cardKey(mainCard) {
+			combos = append(combos, detectComboLoops(main, newComboPiece(card))...)
+		}
+	}
+	return combos
+}
+
+// detectDeckLoops checks every pair of cards in a deck for loops
+func detectDeckLoops(cards []scryfall.Card) []Combo {
+	pieces := make([]comboPiece, len(cards))
+	for i, card := range cards {
+		pieces[i] = newComboPiece(card)
+	}
+
+	combos := []Combo{}
+	for i := range pieces {
+		for j := i + 1; j < len(pieces); j++ {
+			combos = append(combos, detectComboLoops(pieces[i], pieces[j])...)
+		}
+	}
+	return combos
+}
+
+// loopPartnerQueries returns Scryfall searches for cards that could complete
+// a loop with the card, based on the role its abilities can play.
+func loopPartnerQueries(piece comboPiece) []string {
+	queries := []string{}
+	for _, ability := range piece.abilities {
+		switch {
+		case ability.Kind == AbilityActivated && strings.Contains(ability.Cost, "{T}") && manaProduced(ability.Effect) > 0:
+			queries = append(queries, `(o:"untap target" OR o:"untap enchanted" OR o:"untap equipped")`)
+		case ability.Kind == AbilityActivated && untapEffectRegex.MatchString(ability.Effect):
+			queries = append(queries, `o:"{T}: Add"`)
+		case ability.Kind == AbilityActivated && strings.Contains(ability.Cost, "{T}") && copyTokenRegex.MatchString(ability.Effect):
+			queries = append(queries, `t:creature o:"enters" o:"untap target"`)
+		case ability.Kind == AbilityTriggered && strings.Contains(strings.ToLower(ability.Trigger), "enters") && untapEffectRegex.MatchString(ability.Effect):
+			queries = append(queries, `o:"{T}: Create a token that's a copy"`)
+		}
+	}
+
+	unique := []string{}
+	for _, query := range queries {
+		if !contains(unique, query) {
+			unique = append(unique, query)
+		}
+	}
+	return unique
+}
+
+// detectCardLoops searches Scryfall for cards that form a loop with the
+// main card and checks each of them
+func detectCardLoops(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions) []Combo {
+	main := newComboPiece(mainCard)
+	combos := []Combo{}
+	for _, query := range loopPartnerQueries(main) {
+		query = fmt.Sprintf(`%s -name:"%s"`, query, mainCard.Name)
+		log.Printf("Searching for loop partners of %s (Query: %s)", mainCard.Name, query)
+		result, err := client.SearchCards(ctx, query, opts)
+		if err != nil {
+			log.Printf("Error searching for loop partners: %v", err)
+			continue
+		}
+		for _, card := range result.Cards {
+			combos = append(combos, detectComboLoops(main, newComboPiece(card))...)
+		}
+	}
+	return combos
+}
@@ -9,7 +9,18 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// debugLogging turns on debugf output
+var debugLogging bool
+
+// debugf logs details such as timings that are only useful when debugging
+func debugf(format string, args ...any) {
+	if debugLogging {
+		log.Printf("[debug] "+format, args...)
+	}
+}
+
 func setupLogging(config *Config) {
+	debugLogging = config.Debug
 	if !config.LogToFile {
 		log.SetOutput(os.Stderr)
 		log.Println("Logging to stderr only (file logging disabled)")
@@ -29,18 +40,48 @@ func setupLogging(config *Config) {
 func main() {
 	config := LoadConfig()
 	setupLogging(config)
+	setupScryfallAccess(config)
+
+	if err := validateEmbeddedThemePatterns(); err != nil {
+		log.Fatalf("Invalid theme patterns:\n%v", err)
+	}
+	setupThemePacks(config)
+	if err := validateEmbeddedComboDataset(); err != nil {
+		log.Fatalf("Invalid combo dataset:\n%v", err)
+	}
+	setupComboFile(config)
+	if err := validateEmbeddedBoosterDataset(); err != nil {
+		log.Fatalf("Invalid booster definitions:\n%v", err)
+	}
+	setupBoosterFile(config)
+	setupRatingsDir(config)
+	setupOfflineCards(config)
+	setupSetSnapshot(config)
+	setupToolTimeout(config)
+	setupLegalitySnapshots(config)
+	if err := validateEmbeddedPrompts(); err != nil {
+		log.Fatalf("Invalid prompts:\n%v", err)
+	}
+	setupPromptDir(config)
 
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    config.ServerName,
-		Version: config.ServerVersion}, nil)
+		Version: config.ServerVersion}, &mcp.ServerOptions{
+		PageSize:          config.PageSize,
+		CompletionHandler: completeArgument,
+	})
 
 	registerTools(server)
+	registerResources(server)
+	registerPrompts(server)
 
 	switch config.Transport {
 	case TransportStdio:
 		runStdioServer(server)
 	case TransportSSE:
 		runSSEServer(config, server)
+	case TransportHTTP:
+		runStreamableHTTPServer(config, server)
 	default:
 		log.Fatalf("Unknown transport type: %s", config.Transport)
 	}
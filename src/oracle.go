@@ -0,0 +1,312 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Kinds of abilities found in rules text
+const (
+	AbilityKeyword   = "keyword"
+	AbilityActivated = "activated"
+	AbilityTriggered = "triggered"
+	AbilityStatic    = "static"
+	AbilitySpell     = "spell"
+)
+
+var (
+	reminderCaptureRegex = regexp.MustCompile(`\(([^)]*)\)`)
+	abilityWordRegex     = regexp.MustCompile(`^([A-Z][A-Za-z' ]*?) — (.+)$`)
+	chapterRegex         = regexp.MustCompile(`^([IVX]+(?:, [IVX]+)*) — (.+)$`)
+	triggerRegex         = regexp.MustCompile(`^(When|Whenever|At) `)
+	grantVerbRegex       = regexp.MustCompile(`(?i)\b(has|have|gains?|gained)\s+`)
+	keywordListSepRegex  = regexp.MustCompile(`^(,? and |,? or |, )`)
+
+	sortedKeywordsOnce  sync.Once
+	sortedKeywordsCache []string
+)
+
+// parseCardAbilities splits every face of a card into abilities
+func parseCardAbilities(card scryfall.Card) []CardAbility {
+	selfNames := cardSelfNames(card)
+	if card.OracleText != "" || len(card.CardFaces) == 0 {
+		return parseOracleText(card.OracleText, selfNames, "", isSpellTypeLine(card.TypeLine))
+	}
+
+	abilities := []CardAbility{}
+	for _, face := range card.CardFaces {
+		if face.OracleText == nil || *face.OracleText == "" {
+			continue
+		}
+		abilities = append(abilities, parseOracleText(*face.OracleText, selfNames, face.Name, isSpellTypeLine(face.TypeLine))...)
+	}
+	return abilities
+}
+
+func isSpellTypeLine(typeLine string) bool {
+	faces := parseTypeLine(typeLine)
+	return len(faces) > 0 && (faces[0].HasType("Instant") || faces[0].HasType("Sorcery"))
+}
+
+// parseOracleText splits rules text into abilities, one or more per line.
+// Self-references are replaced with CARDNAME and reminder text is moved to
+// its own field. Modal bullets are kept with the ability that introduces
+// them. Lines of an instant or sorcery that are not keywords are spell
+// abilities.
+func parseOracleText(text string, selfNames []string, face string, spell bool) []CardAbility {
+	abilities := []CardAbility{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, name := range selfNames {
+			line = strings.ReplaceAll(line, name, "CARDNAME")
+		}
+
+		if strings.HasPrefix(line, "•") && len(abilities) > 0 {
+			last := &abilities[len(abilities)-1]
+			last.Text += "\n" + line
+			if last.Effect != "" {
+				last.Effect += "\n" + line
+			}
+			continue
+		}
+
+		reminders := []string{}
+		for _, match := range reminderCaptureRegex.FindAllStringSubmatch(line, -1) {
+			reminders = append(reminders, strings.TrimSpace(match[1]))
+		}
+		body := strings.TrimSpace(whitespaceRegex.ReplaceAllString(reminderTextRegex.ReplaceAllString(line, ""), " "))
+		reminder := strings.Join(reminders, " ")
+		if body == "" {
+			// A line made up only of reminder text, such as a basic land's
+			// mana ability, is the ability itself
+			body, reminder = reminder, ""
+		}
+
+		if keywords, ok := parseKeywordLine(body); ok {
+			for i := range keywords {
+				keywords[i].Face = face
+			}
+			if len(keywords) == 1 {
+				keywords[0].Reminder = reminder
+			}
+			abilities = append(abilities, keywords...)
+			continue
+		}
+
+		abilities = append(abilities, parseAbilityLine(body, reminder, face, spell))
+	}
+	return abilities
+}
+
+// parseAbilityLine classifies a single non-keyword line
+func parseAbilityLine(body, reminder, face string, spell bool) CardAbility {
+	ability := CardAbility{Text: body, Face: face, Reminder: reminder}
+
+	if match := chapterRegex.FindStringSubmatch(body); match != nil {
+		ability.Kind = AbilityTriggered
+		ability.Trigger = "Chapter " + match[1]
+		ability.Effect = match[2]
+		ability.GrantedKeywords = grantedKeywords(ability.Effect)
+		return ability
+	}
+	if match := abilityWordRegex.FindStringSubmatch(body); match != nil {
+		ability.AbilityWord = match[1]
+		body = match[2]
+	}
+
+	switch {
+	case triggerRegex.MatchString(body):
+		ability.Kind = AbilityTriggered
+		if trigger, effect, ok := strings.Cut(body, ", "); ok {
+			ability.Trigger = trigger
+			ability.Effect = effect
+		} else {
+			ability.Trigger = body
+		}
+	case isActivatedAbility(body):
+		cost, effect, _ := strings.Cut(body, ":")
+		ability.Kind = AbilityActivated
+		ability.Cost = strings.TrimSpace(cost)
+		ability.Effect = strings.TrimSpace(effect)
+	case spell:
+		ability.Kind = AbilitySpell
+		ability.Effect = body
+	default:
+		ability.Kind = AbilityStatic
+		ability.Effect = body
+	}
+	ability.GrantedKeywords = grantedKeywords(ability.Effect)
+	return ability
+}
+
+// isActivatedAbility reports whether text has the "cost: effect" shape of an
+// activated or loyalty ability.
+func isActivatedAbility(text string) bool {
+	cost, _, ok := strings.Cut(text, ":")
+	if !ok || cost == "" {
+		return false
+	}
+	return !strings.ContainsAny(cost, `".`) && !triggerRegex.MatchString(cost)
+}
+
+// parseKeywordLine parses a line made up only of keyword abilities, such as
+// "Flying, vigilance", "Ward {2}" or "Ward—Pay 3 life.". Lines that read as
+// sentences are rejected so that "Flying creatures you control get +1/+1."
+// is not mistaken for flying.
+func parseKeywordLine(line string) ([]CardAbility, bool) {
+	if head, tail, ok := strings.Cut(line, "—"); ok && !strings.HasSuffix(head, " ") {
+		keyword, parameter, ok := matchKeyword(head)
+		if !ok || parameter != "" {
+			return nil, false
+		}
+		return []CardAbility{{Kind: AbilityKeyword, Text: line, Keyword: keyword, Parameter: strings.TrimSpace(tail)}}, true
+	}
+	if strings.HasSuffix(line, ".") || strings.Contains(line, "—") {
+		return nil, false
+	}
+
+	abilities := []CardAbility{}
+	for i, part := range strings.Split(line, ", ") {
+		part = strings.TrimSpace(part)
+		keyword, parameter, ok := matchKeyword(part)
+		if !ok {
+			// A capitalized part continues the previous parameter, as in
+			// "Partner with Pir, Imaginative Rascal"
+			if i > 0 && startsUpper(part) && abilities[len(abilities)-1].Parameter != "" {
+				abilities[len(abilities)-1].Parameter += ", " + part
+				abilities[len(abilities)-1].Text += ", " + part
+				continue
+			}
+			return nil, false
+		}
+		abilities = append(abilities, CardAbility{Kind: AbilityKeyword, Text: part, Keyword: keyword, Parameter: parameter})
+	}
+	return abilities, len(abilities) > 0
+}
+
+// matchKeyword matches a keyword ability at the start of text and returns its
+// lowercase name and any parameter, e.g. "toxic" and "3" for "Toxic 3".
+// Landwalk and typecycling variants such as "Islandwalk" are reported as
+// landwalk and cycling with the land type as part of the parameter.
+func matchKeyword(text string) (string, string, bool) {
+	lower := strings.ToLower(text)
+	for _, kw := range sortedKeywordAbilities() {
+		if lower == kw {
+			return kw, "", true
+		}
+		if strings.HasPrefix(lower, kw+" ") {
+			return kw, strings.TrimSpace(text[len(kw):]), true
+		}
+	}
+
+	first, rest, _ := strings.Cut(lower, " ")
+	for _, base := range []string{"walk", "cycling"} {
+		prefix, ok := strings.CutSuffix(first, base)
+		if !ok || prefix == "" || strings.ContainsAny(prefix, "{}") {
+			continue
+		}
+		keyword := base
+		if base == "walk" {
+			keyword = "landwalk"
+		}
+		if contains(sortedKeywordAbilities(), keyword) {
+			return keyword, strings.TrimSpace(prefix + " " + text[len(text)-len(rest):]), true
+		}
+	}
+	return "", "", false
+}
+
+// sortedKeywordAbilities returns the lowercase keyword abilities, longest
+// first so that the most specific keyword wins when one prefixes another.
+func sortedKeywordAbilities() []string {
+	sortedKeywordsOnce.Do(func() {
+		for _, kw := range loadKeywordAbilities() {
+			if kw != "" {
+				sortedKeywordsCache = append(sortedKeywordsCache, strings.ToLower(kw))
+			}
+		}
+		sort.SliceStable(sortedKeywordsCache, func(i, j int) bool {
+			return len(sortedKeywordsCache[i]) > len(sortedKeywordsCache[j])
+		})
+	})
+	return sortedKeywordsCache
+}
+
+// grantedKeywords returns the keywords an effect gives, such as flying and
+// trample in "Creatures you control have flying and trample". Keywords that
+// are only mentioned, as in "creatures without flying", are not included.
+func grantedKeywords(text string) []string {
+	lower := strings.ToLower(text)
+	granted := []string{}
+	for _, loc := range grantVerbRegex.FindAllStringIndex(lower, -1) {
+		rest := lower[loc[1]:]
+		for {
+			keyword, ok := keywordPrefix(rest)
+			if !ok {
+				break
+			}
+			if !contains(granted, keyword) {
+				granted = append(granted, keyword)
+			}
+			rest = rest[len(keyword):]
+			sep := keywordListSepRegex.FindString(rest)
+			if sep == "" {
+				break
+			}
+			rest = rest[len(sep):]
+		}
+	}
+	return granted
+}
+
+// keywordPrefix returns the keyword that text starts with, if it is followed
+// by a word boundary
+func keywordPrefix(text string) (string, bool) {
+	for _, kw := range sortedKeywordAbilities() {
+		if !strings.HasPrefix(text, kw) {
+			continue
+		}
+		if len(text) == len(kw) || !isWordByte(text[len(kw)]) {
+			return kw, true
+		}
+	}
+	return "", false
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// abilityKeywords returns the keywords a card has or grants, in order of
+// appearance
+func abilityKeywords(abilities []CardAbility) []string {
+	keywords := []string{}
+	for _, ability := range abilities {
+		found := ability.GrantedKeywords
+		if ability.Kind == AbilityKeyword {
+			found = []string{ability.Keyword}
+		}
+		for _, kw := range found {
+			if !contains(keywords, kw) {
+				keywords = append(keywords, kw)
+			}
+		}
+	}
+	return keywords
+}
+
+// abilityThemeText returns the text theme patterns are matched against: the
+// ability with its reminder text, which often spells out what a keyword does
+func abilityThemeText(ability CardAbility) string {
+	if ability.Reminder == "" {
+		return ability.Text
+	}
+	return ability.Text + " (" + ability.Reminder + ")"
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOracleText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		selfNames []string
+		spell     bool
+		want      []CardAbility
+	}{
+		{
+			name: "keywords with parameters",
+			text: "Flying, ward {2}\nToxic 3 (Players dealt combat damage by this creature also get three poison counters.)",
+			want: []CardAbility{
+				{Kind: AbilityKeyword, Text: "Flying", Keyword: "flying"},
+				{Kind: AbilityKeyword, Text: "ward {2}", Keyword: "ward", Parameter: "{2}"},
+				{Kind: AbilityKeyword, Text: "Toxic 3", Keyword: "toxic", Parameter: "3", Reminder: "Players dealt combat damage by this creature also get three poison counters."},
+			},
+		},
+		{
+			name:      "triggered ability with self-reference",
+			text:      "Whenever Goblin Guide attacks, defending player reveals the top card of their library.",
+			selfNames: []string{"Goblin Guide"},
+			want: []CardAbility{
+				{Kind: AbilityTriggered, Text: "Whenever CARDNAME attacks, defending player reveals the top card of their library.", Trigger: "Whenever CARDNAME attacks", Effect: "defending player reveals the top card of their library.", GrantedKeywords: []string{}},
+			},
+		},
+		{
+			name:      "activated ability with short self-reference",
+			text:      "{T}: Add {G}.\n{1}{G}, Sacrifice Ezuri: Creatures you control gain trample until end of turn.",
+			selfNames: []string{"Ezuri, Renegade Leader", "Ezuri"},
+			want: []CardAbility{
+				{Kind: AbilityActivated, Text: "{T}: Add {G}.", Cost: "{T}", Effect: "Add {G}.", GrantedKeywords: []string{}},
+				{Kind: AbilityActivated, Text: "{1}{G}, Sacrifice CARDNAME: Creatures you control gain trample until end of turn.", Cost: "{1}{G}, Sacrifice CARDNAME", Effect: "Creatures you control gain trample until end of turn.", GrantedKeywords: []string{"trample"}},
+			},
+		},
+		{
+			name: "ability word",
+			text: "Landfall — Whenever a land you control enters, you gain 1 life.",
+			want: []CardAbility{
+				{Kind: AbilityTriggered, Text: "Landfall — Whenever a land you control enters, you gain 1 life.", AbilityWord: "Landfall", Trigger: "Whenever a land you control enters", Effect: "you gain 1 life.", GrantedKeywords: []string{}},
+			},
+		},
+		{
+			name: "keyword mentioned but not granted",
+			text: "Flying creatures you control get +1/+1.\nCreatures without flying can't block.",
+			want: []CardAbility{
+				{Kind: AbilityStatic, Text: "Flying creatures you control get +1/+1.", Effect: "Flying creatures you control get +1/+1.", GrantedKeywords: []string{}},
+				{Kind: AbilityStatic, Text: "Creatures without flying can't block.", Effect: "Creatures without flying can't block.", GrantedKeywords: []string{}},
+			},
+		},
+		{
+			name:  "modal spell",
+			text:  "Choose one —\n• Destroy target artifact.\n• Destroy target enchantment.",
+			spell: true,
+			want: []CardAbility{
+				{Kind: AbilitySpell, Text: "Choose one —\n• Destroy target artifact.\n• Destroy target enchantment.", Effect: "Choose one —\n• Destroy target artifact.\n• Destroy target enchantment.", GrantedKeywords: []string{}},
+			},
+		},
+		{
+			name: "reminder text only",
+			text: "({T}: Add {U}.)",
+			want: []CardAbility{
+				{Kind: AbilityActivated, Text: "{T}: Add {U}.", Cost: "{T}", Effect: "Add {U}.", GrantedKeywords: []string{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseOracleText(tt.text, tt.selfNames, "", tt.spell)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOracleText(%q) =\n%+v\nwant\n%+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeywordLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []CardAbility
+		ok   bool
+	}{
+		{"Flying, vigilance", []CardAbility{
+			{Kind: AbilityKeyword, Text: "Flying", Keyword: "flying"},
+			{Kind: AbilityKeyword, Text: "vigilance", Keyword: "vigilance"},
+		}, true},
+		{"Ward {2}", []CardAbility{{Kind: AbilityKeyword, Text: "Ward {2}", Keyword: "ward", Parameter: "{2}"}}, true},
+		{"Ward—Pay 3 life.", []CardAbility{{Kind: AbilityKeyword, Text: "Ward—Pay 3 life.", Keyword: "ward", Parameter: "Pay 3 life."}}, true},
+		{"Toxic 3", []CardAbility{{Kind: AbilityKeyword, Text: "Toxic 3", Keyword: "toxic", Parameter: "3"}}, true},
+		{"Partner with Pir, Imaginative Rascal", []CardAbility{{Kind: AbilityKeyword, Text: "Partner with Pir, Imaginative Rascal", Keyword: "partner", Parameter: "with Pir, Imaginative Rascal"}}, true},
+		{"Flying creatures you control get +1/+1.", nil, false},
+		{"Creatures without flying can't block", nil, false},
+		{"Landfall — Whenever a land you control enters, you gain 1 life.", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseKeywordLine(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeywordLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchKeyword(t *testing.T) {
+	tests := []struct {
+		text      string
+		keyword   string
+		parameter string
+		ok        bool
+	}{
+		{"Flying", "flying", "", true},
+		{"Ward {2}", "ward", "{2}", true},
+		{"Toxic 3", "toxic", "3", true},
+		{"Islandwalk", "landwalk", "island", true},
+		{"Swampcycling {2}", "cycling", "swamp {2}", true},
+		{"Cycling {2}", "cycling", "{2}", true},
+		{"Flyingfish", "", "", false},
+		{"Destroy target creature.", "", "", false},
+	}
+
+	for _, tt := range tests {
+		keyword, parameter, ok := matchKeyword(tt.text)
+		if keyword != tt.keyword || parameter != tt.parameter || ok != tt.ok {
+			t.Errorf("matchKeyword(%q) = %q, %q, %v, want %q, %q, %v", tt.text, keyword, parameter, ok, tt.keyword, tt.parameter, tt.ok)
+		}
+	}
+}
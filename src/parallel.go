@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// maxParallelSearches bounds how many Scryfall requests one tool call has in
+// flight. The client's rate limiter still spaces the requests themselves, so
+// this only keeps a slow search from holding up the others.
+const maxParallelSearches = 4
+
+// runParallel calls run for every index below n, on at most limit goroutines
+// at a time, and waits for them to finish. Indexes that haven't started when
+// ctx is done are skipped.
+func runParallel(ctx context.Context, n, limit int, run func(i int)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(limit, 1))
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case slots <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+}
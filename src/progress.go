@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Reasons a tool returned partial results
+const (
+	PartialCancelled = "cancelled"
+	PartialTimedOut  = "timed out"
+)
+
+// toolTimeout limits how long the multi-search tools run before returning
+// what they have found so far; zero means no limit
+var toolTimeout time.Duration
+
+// setupToolTimeout sets the time limit of the multi-search tools
+func setupToolTimeout(config *Config) {
+	toolTimeout = config.ToolTimeout
+	if toolTimeout > 0 {
+		log.Printf("Limiting multi-search tools to %s", toolTimeout)
+	}
+}
+
+// withToolTimeout applies the configured time limit to a tool's context
+func withToolTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if toolTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, toolTimeout)
+}
+
+// toolProgress reports the sub-searches of a tool call to the client as
+// progress notifications. It is carried in the context, so the search
+// helpers report progress without knowing which tool called them.
+type toolProgress struct {
+	mu       sync.Mutex
+	session  *mcp.ServerSession
+	token    any
+	progress float64
+	total    float64
+}
+
+type toolProgressKey struct{}
+
+// startToolProgress sets up progress reporting for a tool call expected to
+// take total steps. Nothing is sent unless the client asked for progress by
+// giving a progress token.
+func startToolProgress(ctx context.Context, req *mcp.CallToolRequest, total int) context.Context {
+	progress := &toolProgress{total: float64(total)}
+	if req != nil && req.Params != nil {
+		progress.session = req.Session
+		progress.token = req.Params.GetProgressToken()
+	}
+	return context.WithValue(ctx, toolProgressKey{}, progress)
+}
+
+// reportProgress counts one step of the tool call in ctx and tells the
+// client what it is. The total grows when there are more steps than
+// expected, such as one search per creature type.
+func reportProgress(ctx context.Context, message string) {
+	progress, ok := ctx.Value(toolProgressKey{}).(*toolProgress)
+	if !ok {
+		return
+	}
+
+	progress.mu.Lock()
+	progress.progress++
+	progress.total = max(progress.total, progress.progress)
+	params := &mcp.ProgressNotificationParams{
+		ProgressToken: progress.token,
+		Message:       message,
+		Progress:      progress.progress,
+		Total:         progress.total,
+	}
+	progress.mu.Unlock()
+
+	if progress.token == nil || progress.session == nil {
+		return
+	}
+	if err := progress.session.NotifyProgress(ctx, params); err != nil {
+		log.Printf("Error sending progress notification: %v", err)
+	}
+}
+
+// partialReason says why a tool stopped early, or returns "" when ctx is
+// still live
+func partialReason(ctx context.Context) string {
+	switch {
+	case ctx.Err() == nil:
+		return ""
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return PartialTimedOut
+	}
+	return PartialCancelled
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Prompt argument types. Clients always send arguments as strings; they are
+// converted to these types before the templates see them.
+const (
+	PromptArgString  = "string"
+	PromptArgInteger = "integer"
+	PromptArgNumber  = "number"
+	PromptArgBoolean = "boolean"
+	PromptArgEnum    = "enum"
+	PromptArgFormat  = "format"
+)
+
+var promptArgumentNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// promptArgument is one argument of a prompt. Default is used when the client
+// leaves an optional argument out; Options lists the values of an enum. A
+// format argument is decoded as an enum of the formats format_info knows.
+type promptArgument struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// promptMessage is one message of a prompt, rendered from a text/template
+// file named relative to the dataset
+type promptMessage struct {
+	Role     string `json:"role"`
+	Template string `json:"template"`
+}
+
+// promptDefinition describes one prompt offered to clients
+type promptDefinition struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description"`
+	Arguments   []promptArgument `json:"arguments"`
+	Messages    []promptMessage  `json:"messages"`
+}
+
+// promptDataset is the format of res/prompts.json and of prompts.json in
+// MCP_PROMPT_DIR
+type promptDataset struct {
+	Version string             `json:"version"`
+	Prompts []promptDefinition `json:"prompts"`
+}
+
+// loadedPrompt is a prompt definition with its parsed message templates
+type loadedPrompt struct {
+	definition promptDefinition
+	templates  []*template.Template
+}
+
+func decodePromptDataset(data []byte) (promptDataset, error) {
+	var dataset promptDataset
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&dataset); err != nil {
+		return promptDataset{}, err
+	}
+	for _, prompt := range dataset.Prompts {
+		for i, argument := range prompt.Arguments {
+			if argument.Type == PromptArgFormat {
+				prompt.Arguments[i].Type = PromptArgEnum
+				prompt.Arguments[i].Options = legalityFormats()
+			}
+		}
+	}
+	return dataset, nil
+}
+
+// parsePromptArgument converts a value sent by the client to the argument's
+// type
+func parsePromptArgument(argument promptArgument, value string) (any, error) {
+	value = strings.TrimSpace(value)
+	switch argument.Type {
+	case PromptArgInteger:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("argument '%s' must be a whole number", argument.Name)
+		}
+		return number, nil
+	case PromptArgNumber:
+		number, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("argument '%s' must be a number", argument.Name)
+		}
+		return number, nil
+	case PromptArgBoolean:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("argument '%s' must be true or false", argument.Name)
+		}
+		return flag, nil
+	case PromptArgEnum:
+		for _, option := range argument.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return nil, fmt.Errorf("argument '%s' must be one of %s", argument.Name, strings.Join(argument.Options, ", "))
+	}
+	return value, nil
+}
+
+// zeroPromptArgument is the value a template sees for an optional argument
+// that was left out and has no default
+func zeroPromptArgument(argument promptArgument) any {
+	switch argument.Type {
+	case PromptArgInteger:
+		return int64(0)
+	case PromptArgNumber:
+		return 0.0
+	case PromptArgBoolean:
+		return false
+	}
+	return ""
+}
+
+// promptArgumentValues checks the client's arguments against the prompt's
+// and converts them for its templates. Every declared argument is set, so
+// templates can test optional ones with {{if}}.
+func promptArgumentValues(definition promptDefinition, arguments map[string]string) (map[string]any, error) {
+	declared := map[string]bool{}
+	for _, argument := range definition.Arguments {
+		declared[argument.Name] = true
+	}
+	unknown := []string{}
+	for name := range arguments {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown arguments: %s", strings.Join(unknown, ", "))
+	}
+
+	values := map[string]any{}
+	for _, argument := range definition.Arguments {
+		value, ok := arguments[argument.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			if argument.Required {
+				return nil, fmt.Errorf("missing required argument '%s'", argument.Name)
+			}
+			if argument.Default == "" {
+				values[argument.Name] = zeroPromptArgument(argument)
+				continue
+			}
+			value = argument.Default
+		}
+		parsed, err := parsePromptArgument(argument, value)
+		if err != nil {
+			return nil, err
+		}
+		values[argument.Name] = parsed
+	}
+	return values, nil
+}
+
+// loadPromptDataset reads prompts.json from fsys and parses the templates its
+// messages name
+func loadPromptDataset(fsys fs.FS) (promptDataset, map[string][]*template.Template, error) {
+	data, err := fs.ReadFile(fsys, "prompts.json")
+	if err != nil {
+		return promptDataset{}, nil, err
+	}
+	dataset, err := decodePromptDataset(data)
+	if err != nil {
+		return promptDataset{}, nil, fmt.Errorf("prompts.json: %w", err)
+	}
+
+	var errs []error
+	templates := map[string][]*template.Template{}
+	for _, prompt := range dataset.Prompts {
+		for _, message := range prompt.Messages {
+			text, err := fs.ReadFile(fsys, message.Template)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("prompt '%s': %w", prompt.Name, err))
+				continue
+			}
+			parsed, err := template.New(message.Template).Option("missingkey=error").Parse(string(text))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("prompt '%s': %w", prompt.Name, err))
+				continue
+			}
+			templates[prompt.Name] = append(templates[prompt.Name], parsed)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return promptDataset{}, nil, err
+	}
+	if err := validatePromptDataset(dataset, templates); err != nil {
+		return promptDataset{}, nil, err
+	}
+	return dataset, templates, nil
+}
+
+// validatePromptDataset checks that every prompt has a unique name, a
+// description and messages, that its arguments have usable names, known
+// types and valid defaults, and that its templates only use its arguments.
+func validatePromptDataset(dataset promptDataset, templates map[string][]*template.Template) error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, prompt := range dataset.Prompts {
+		label := fmt.Sprintf("#%d (%s)", i, prompt.Name)
+		if prompt.Name == "" {
+			errs = append(errs, fmt.Errorf("prompt %s: missing name", label))
+		}
+		if seen[prompt.Name] {
+			errs = append(errs, fmt.Errorf("prompt %s: defined twice", label))
+		}
+		seen[prompt.Name] = true
+		if prompt.Description == "" {
+			errs = append(errs, fmt.Errorf("prompt %s: missing description", label))
+		}
+		if len(prompt.Messages) == 0 {
+			errs = append(errs, fmt.Errorf("prompt %s: missing messages", label))
+		}
+		for _, message := range prompt.Messages {
+			if message.Role != "user" && message.Role != "assistant" {
+				errs = append(errs, fmt.Errorf("prompt %s: role must be user or assistant", label))
+			}
+		}
+
+		arguments := map[string]bool{}
+		for _, argument := range prompt.Arguments {
+			if !promptArgumentNamePattern.MatchString(argument.Name) {
+				errs = append(errs, fmt.Errorf("prompt %s: argument '%s' must be lower case letters, digits and underscores", label, argument.Name))
+			}
+			if arguments[argument.Name] {
+				errs = append(errs, fmt.Errorf("prompt %s: argument '%s' defined twice", label, argument.Name))
+			}
+			arguments[argument.Name] = true
+			if !contains([]string{PromptArgString, PromptArgInteger, PromptArgNumber, PromptArgBoolean, PromptArgEnum}, argument.Type) {
+				errs = append(errs, fmt.Errorf("prompt %s: argument '%s' has unknown type '%s'", label, argument.Name, argument.Type))
+			}
+			if argument.Type == PromptArgEnum && len(argument.Options) == 0 {
+				errs = append(errs, fmt.Errorf("prompt %s: enum argument '%s' has no options", label, argument.Name))
+			}
+			if argument.Default != "" {
+				if _, err := parsePromptArgument(argument, argument.Default); err != nil {
+					errs = append(errs, fmt.Errorf("prompt %s: default of %w", label, err))
+				}
+			}
+		}
+
+		// Rendering with every argument left out catches templates that use
+		// an argument the prompt doesn't declare
+		values := map[string]any{}
+		for _, argument := range prompt.Arguments {
+			values[argument.Name] = zeroPromptArgument(argument)
+		}
+		for _, parsed := range templates[prompt.Name] {
+			if err := parsed.Execute(&bytes.Buffer{}, values); err != nil {
+				errs = append(errs, fmt.Errorf("prompt %s: %w", label, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadEmbeddedPrompts loads res/prompts.json and its templates
+func loadEmbeddedPrompts() (promptDataset, map[string][]*template.Template, error) {
+	fsys, err := fs.Sub(embeddedResources, "res")
+	if err != nil {
+		return promptDataset{}, nil, err
+	}
+	return loadPromptDataset(fsys)
+}
+
+// validateEmbeddedPrompts checks res/prompts.json and its templates at
+// startup
+func validateEmbeddedPrompts() error {
+	if _, _, err := loadEmbeddedPrompts(); err != nil {
+		return fmt.Errorf("res/prompts.json:\n%w", err)
+	}
+	return nil
+}
+
+var (
+	promptDir         string
+	promptDefinitions = map[string]promptDefinition{}
+)
+
+// setupPromptDir sets the directory whose prompts.json adds to or replaces
+// the embedded prompts
+func setupPromptDir(config *Config) {
+	promptDir = config.PromptDir
+}
+
+// loadPrompts returns the embedded prompts merged with those in promptDir. A
+// prompt with the same name as an embedded one replaces it.
+func loadPrompts() []loadedPrompt {
+	dataset, templates, err := loadEmbeddedPrompts()
+	if err != nil {
+		log.Printf("Error loading prompts: %v", err)
+		return nil
+	}
+
+	if promptDir != "" {
+		extra, extraTemplates, err := loadPromptDataset(os.DirFS(promptDir))
+		if err != nil {
+			log.Printf("Error loading prompts from %s: %v", promptDir, err)
+		} else {
+			index := map[string]int{}
+			for i, prompt := range dataset.Prompts {
+				index[prompt.Name] = i
+			}
+			for _, prompt := range extra.Prompts {
+				templates[prompt.Name] = extraTemplates[prompt.Name]
+				if i, ok := index[prompt.Name]; ok {
+					dataset.Prompts[i] = prompt
+					continue
+				}
+				dataset.Prompts = append(dataset.Prompts, prompt)
+			}
+			log.Printf("Loaded %d prompts from %s", len(extra.Prompts), promptDir)
+		}
+	}
+
+	prompts := []loadedPrompt{}
+	for _, prompt := range dataset.Prompts {
+		prompts = append(prompts, loadedPrompt{definition: prompt, templates: templates[prompt.Name]})
+	}
+	return prompts
+}
+
+// promptHandler renders a prompt's messages from the client's arguments
+func promptHandler(prompt loadedPrompt) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		values, err := promptArgumentValues(prompt.definition, req.Params.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("prompt '%s': %w", prompt.definition.Name, err)
+		}
+		log.Printf("Rendering prompt '%s'", prompt.definition.Name)
+
+		result := &mcp.GetPromptResult{Description: prompt.definition.Description}
+		for i, message := range prompt.definition.Messages {
+			var text bytes.Buffer
+			if err := prompt.templates[i].Execute(&text, values); err != nil {
+				return nil, fmt.Errorf("prompt '%s': %w", prompt.definition.Name, err)
+			}
+			result.Messages = append(result.Messages, &mcp.PromptMessage{
+				Role:    mcp.Role(message.Role),
+				Content: &mcp.TextContent{Text: strings.TrimSpace(text.String())},
+			})
+		}
+		return result, nil
+	}
+}
+
+// describePromptArgument adds an argument's type, options and default to its
+// description, since MCP prompt arguments carry no type of their own
+func describePromptArgument(argument promptArgument) string {
+	description := argument.Description
+	switch argument.Type {
+	case PromptArgEnum:
+		description += fmt.Sprintf(" (one of %s)", strings.Join(argument.Options, ", "))
+	case PromptArgInteger:
+		description += " (whole number)"
+	case PromptArgNumber:
+		description += " (number)"
+	case PromptArgBoolean:
+		description += " (true or false)"
+	}
+	if argument.Default != "" {
+		description += fmt.Sprintf(", default %s", argument.Default)
+	}
+	return description
+}
+
+func registerPrompts(server *mcp.Server) {
+	for _, prompt := range loadPrompts() {
+		promptDefinitions[prompt.definition.Name] = prompt.definition
+		arguments := []*mcp.PromptArgument{}
+		for _, argument := range prompt.definition.Arguments {
+			arguments = append(arguments, &mcp.PromptArgument{
+				Name:        argument.Name,
+				Title:       argument.Title,
+				Description: describePromptArgument(argument),
+				Required:    argument.Required,
+			})
+		}
+		server.AddPrompt(&mcp.Prompt{
+			Name:        prompt.definition.Name,
+			Title:       prompt.definition.Title,
+			Description: prompt.definition.Description,
+			Arguments:   arguments,
+		}, promptHandler(prompt))
+		log.Printf("Prompt '%s' registered.", prompt.definition.Name)
+	}
+}
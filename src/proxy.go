@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the networks whose X-Forwarded-* headers are believed.
+// Requests from anywhere else have those headers removed, so a client can't
+// claim to be someone else.
+var trustedProxies []*net.IPNet
+
+// setupTrustedProxies parses MCP_TRUSTED_PROXIES. Entries are CIDRs, or
+// single addresses.
+func setupTrustedProxies(config *Config) error {
+	trustedProxies = nil
+	for _, entry := range config.TrustedProxies {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trustedProxies = append(trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+	return nil
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the address part of a request's RemoteAddr
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// forwardedClient walks X-Forwarded-For from the right, skipping trusted
+// proxies, and returns the first address that isn't one: the client as seen
+// by the outermost proxy we trust
+func forwardedClient(values []string) string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !isTrustedProxy(hops[i]) || i == 0 {
+			return hops[i]
+		}
+	}
+	return ""
+}
+
+// withForwardedHeaders applies X-Forwarded-For and X-Forwarded-Host to
+// requests that come from a trusted proxy, so that the client address and
+// host are those the client used. X-Forwarded-Proto is left for
+// requestScheme; r.URL is left alone because the SSE handler builds its
+// session endpoint relative to it.
+func withForwardedHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isTrustedProxy(remoteHost(r.RemoteAddr)) {
+			r.Header.Del("X-Forwarded-For")
+			r.Header.Del("X-Forwarded-Proto")
+			r.Header.Del("X-Forwarded-Host")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if client := forwardedClient(r.Header.Values("X-Forwarded-For")); client != "" {
+			r.RemoteAddr = net.JoinHostPort(client, "0")
+		}
+		if host := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Host"), ",")[0]); host != "" {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestScheme is the scheme the client used: the one the trusted proxy
+// reports, or that of the connection. X-Forwarded-Proto only survives
+// withForwardedHeaders when a trusted proxy sent it.
+func requestScheme(r *http.Request) string {
+	proto := strings.ToLower(strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0]))
+	if proto == "http" || proto == "https" {
+		return proto
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// withAccessLog logs each request when debug logging is on
+func withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		debugf("%s %s %s://%s%s", remoteHost(r.RemoteAddr), r.Method, requestScheme(r), r.Host, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Columns of a 17Lands-style card ratings export, in order of preference for
+// the win rate used to rank cards
+var (
+	ratingNameColumns    = []string{"name", "card name", "card"}
+	ratingColorColumns   = []string{"color", "colors"}
+	ratingRarityColumns  = []string{"rarity"}
+	ratingMetricColumns  = []string{"gih wr", "gp wr", "oh wr", "gd wr", "rating"}
+	ratingGamesColumns   = []string{"# gih", "# gp", "# oh", "# gd"}
+	ratingALSAColumns    = []string{"alsa"}
+	ratingATAColumns     = []string{"ata"}
+	ratingSetCodePattern = regexp.MustCompile(`^[a-z0-9]{2,6}$`)
+)
+
+// ratingRarities expands the rarity letters 17Lands exports use
+var ratingRarities = map[string]string{
+	"c": "common", "u": "uncommon", "r": "rare", "m": "mythic", "s": "special", "b": "bonus",
+}
+
+// setRatings is a set's imported card ratings, as stored in MCP_RATINGS_DIR
+type setRatings struct {
+	Set        string       `json:"set"`
+	Metric     string       `json:"metric"`
+	Source     string       `json:"source,omitempty"`
+	ImportedAt string       `json:"imported_at"`
+	Cards      []CardRating `json:"cards"`
+}
+
+// topRatedCards is how many of the best cards load_set_ratings reports
+const topRatedCards = 10
+
+var (
+	ratingsMu    sync.RWMutex
+	ratingsCache = map[string]*setRatings{}
+	ratingsDir   string
+)
+
+// setupRatingsDir sets where imported ratings are stored
+func setupRatingsDir(config *Config) {
+	ratingsDir = config.RatingsDir
+	log.Printf("Storing card ratings in %s", ratingsDir)
+}
+
+// findColumn returns the index of the first header matching one of names
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, column := range header {
+			if column == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseRatingValue parses numbers such as "55.3%", "4.5pp" or "2.31",
+// reporting false for empty cells
+func parseRatingValue(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "%"), "pp")
+	if value == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// parseRatingsCSV reads a card ratings export. The win rate column is the
+// first of GIH WR, GP WR, OH WR, GD WR or Rating that the file has.
+func parseRatingsCSV(r io.Reader) ([]CardRating, string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("reading header: %w", err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+
+	nameColumn := findColumn(header, ratingNameColumns)
+	if nameColumn < 0 {
+		return nil, "", errors.New("missing a Name column")
+	}
+	metricColumn := findColumn(header, ratingMetricColumns)
+	if metricColumn < 0 {
+		return nil, "", fmt.Errorf("missing a win rate column; expected one of %s", strings.Join(ratingMetricColumns, ", "))
+	}
+	colorColumn := findColumn(header, ratingColorColumns)
+	rarityColumn := findColumn(header, ratingRarityColumns)
+	gamesColumn := findColumn(header, ratingGamesColumns)
+	alsaColumn := findColumn(header, ratingALSAColumns)
+	ataColumn := findColumn(header, ratingATAColumns)
+
+	cell := func(record []string, column int) string {
+		if column < 0 || column >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[column])
+	}
+
+	ratings := []CardRating{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("line %d: %w", line, err)
+		}
+		name := cell(record, nameColumn)
+		if name == "" {
+			continue
+		}
+		rating := CardRating{
+			Name:   name,
+			Color:  strings.ToUpper(cell(record, colorColumn)),
+			Rarity: strings.ToLower(cell(record, rarityColumn)),
+		}
+		if rarity, ok := ratingRarities[rating.Rarity]; ok {
+			rating.Rarity = rarity
+		}
+		if value, ok := parseRatingValue(cell(record, metricColumn)); ok {
+			rating.WinRate = &value
+		}
+		if value, ok := parseRatingValue(cell(record, gamesColumn)); ok {
+			rating.Games = int(value)
+		}
+		if value, ok := parseRatingValue(cell(record, alsaColumn)); ok {
+			rating.ALSA = value
+		}
+		if value, ok := parseRatingValue(cell(record, ataColumn)); ok {
+			rating.ATA = value
+		}
+		ratings = append(ratings, rating)
+	}
+	if len(ratings) == 0 {
+		return nil, "", errors.New("no card rows")
+	}
+	return ratings, strings.ToUpper(header[metricColumn]), nil
+}
+
+func ratingsPath(set string) string {
+	return filepath.Join(ratingsDir, strings.ToLower(set)+".json")
+}
+
+// saveSetRatings stores a set's ratings and replaces any cached copy
+func saveSetRatings(ratings setRatings) error {
+	data, err := json.MarshalIndent(ratings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ratingsDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(ratingsPath(ratings.Set), data, 0644); err != nil {
+		return err
+	}
+
+	ratingsMu.Lock()
+	ratingsCache[ratings.Set] = &ratings
+	ratingsMu.Unlock()
+	markCardNamesStale()
+	return nil
+}
+
+// importSetRatings parses a ratings CSV and stores it for the set. Exports
+// saved by spreadsheets often start with a byte order mark, which is dropped.
+func importSetRatings(set, csvText, source string) (setRatings, error) {
+	set = strings.ToLower(strings.TrimSpace(set))
+	if !ratingSetCodePattern.MatchString(set) {
+		return setRatings{}, fmt.Errorf("invalid set code '%s'", set)
+	}
+	cards, metric, err := parseRatingsCSV(strings.NewReader(strings.TrimPrefix(csvText, "\ufeff")))
+	if err != nil {
+		return setRatings{}, err
+	}
+	ratings := setRatings{
+		Set:        set,
+		Metric:     metric,
+		Source:     source,
+		ImportedAt: time.Now().UTC().Format(time.RFC3339),
+		Cards:      cards,
+	}
+	if err := saveSetRatings(ratings); err != nil {
+		return setRatings{}, err
+	}
+	log.Printf("Imported %d card ratings for %s (%s)", len(cards), set, metric)
+	return ratings, nil
+}
+
+// storedSetRatings returns a set's stored ratings, reading them from disk on
+// first use. It reports false when the set has none, or when set isn't a
+// set code, so it can't be used to read other files.
+func storedSetRatings(set string) (*setRatings, bool) {
+	set = strings.ToLower(strings.TrimSpace(set))
+	if !ratingSetCodePattern.MatchString(set) {
+		return nil, false
+	}
+	ratingsMu.RLock()
+	cached, ok := ratingsCache[set]
+	ratingsMu.RUnlock()
+	if ok {
+		return cached, true
+	}
+
+	data, err := os.ReadFile(ratingsPath(set))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error reading ratings for %s: %v", set, err)
+		}
+		return nil, false
+	}
+	var ratings setRatings
+	if err := json.Unmarshal(data, &ratings); err != nil {
+		log.Printf("Error parsing ratings for %s: %v", set, err)
+		return nil, false
+	}
+
+	ratingsMu.Lock()
+	ratingsCache[set] = &ratings
+	ratingsMu.Unlock()
+	return &ratings, true
+}
+
+// ratingStats returns the mean and standard deviation of a set's win rates
+func ratingStats(ratings *setRatings) (float64, float64) {
+	sum, count := 0.0, 0
+	for _, card := range ratings.Cards {
+		if card.WinRate != nil {
+			sum += *card.WinRate
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	mean := sum / float64(count)
+	variance := 0.0
+	for _, card := range ratings.Cards {
+		if card.WinRate != nil {
+			variance += (*card.WinRate - mean) * (*card.WinRate - mean)
+		}
+	}
+	return mean, math.Sqrt(variance / float64(count))
+}
+
+// findCardRating looks a card up by name, also matching the front face of
+// multi-faced cards
+func findCardRating(ratings *setRatings, name string) (CardRating, bool) {
+	key := comboNameKey(name)
+	for _, card := range ratings.Cards {
+		if strings.EqualFold(card.Name, name) || comboNameKey(card.Name) == key {
+			return card, true
+		}
+	}
+	return CardRating{}, false
+}
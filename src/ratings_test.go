@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// seventeenLandsHeader is the header row of a 17Lands card ratings export
+const seventeenLandsHeader = `"Name","Color","Rarity","# Seen","ALSA","# Picked","ATA","# GP","% GP","GP WR","# OH","OH WR","# GD","GD WR","# GIH","GIH WR","# GNS","GNS WR","IWD"`
+
+func TestParseRatingsCSV(t *testing.T) {
+	winRate := func(value float64) *float64 { return &value }
+
+	tests := []struct {
+		name       string
+		csv        string
+		want       []CardRating
+		wantMetric string
+	}{
+		{
+			name: "17Lands export",
+			csv: seventeenLandsHeader + "\n" +
+				`"Sheoldred's Edict","B","U","40046","3.41","5921","2.89","24811","79.5%","57.2%","6032","55.8%","8921","57.9%","14953","58.1%","10314","53.7%","4.4pp"` + "\n" +
+				`"Llanowar Elves","G","C","51210","5.12","4031","4.75","9874","61.9%","55.4%","2412","54.9%","3466","56.8%","5878","56.0%","5021","53.1%","2.9pp"` + "\n" +
+				`"Rare Land","","R","812","9.80","97","9.12","45","12.0%","","12","","20","","32","","30","",""` + "\n",
+			want: []CardRating{
+				{Name: "Sheoldred's Edict", Color: "B", Rarity: "uncommon", WinRate: winRate(58.1), Games: 14953, ALSA: 3.41, ATA: 2.89},
+				{Name: "Llanowar Elves", Color: "G", Rarity: "common", WinRate: winRate(56.0), Games: 5878, ALSA: 5.12, ATA: 4.75},
+				{Name: "Rare Land", Rarity: "rare", Games: 32, ALSA: 9.8, ATA: 9.12},
+			},
+			wantMetric: "GIH WR",
+		},
+		{
+			name: "game played win rate without GIH WR",
+			csv: `"Name","Color","Rarity","# GP","GP WR","# OH","OH WR"` + "\n" +
+				`"Llanowar Elves","G","C","9874","55.4%","2412","54.9%"` + "\n",
+			want: []CardRating{
+				{Name: "Llanowar Elves", Color: "G", Rarity: "common", WinRate: winRate(55.4), Games: 9874},
+			},
+			wantMetric: "GP WR",
+		},
+		{
+			name: "opening hand win rate",
+			csv: `"Name","# OH","OH WR","# GD","GD WR"` + "\n" +
+				`"Llanowar Elves","2412","54.9%","3466","56.8%"` + "\n",
+			want: []CardRating{
+				{Name: "Llanowar Elves", WinRate: winRate(54.9), Games: 2412},
+			},
+			wantMetric: "OH WR",
+		},
+		{
+			name: "games drawn win rate",
+			csv: "Card Name,GD WR\n" +
+				"Llanowar Elves,56.8\n" +
+				",57.0\n",
+			want: []CardRating{
+				{Name: "Llanowar Elves", WinRate: winRate(56.8)},
+			},
+			wantMetric: "GD WR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, metric, err := parseRatingsCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("parseRatingsCSV() returned error: %v", err)
+			}
+			if metric != tt.wantMetric {
+				t.Errorf("parseRatingsCSV() metric = %q, want %q", metric, tt.wantMetric)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRatingsCSV() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRatingsCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"no name column", `"Color","GIH WR"` + "\n" + `"G","56.0%"`},
+		{"no win rate column", `"Name","ALSA","IWD"` + "\n" + `"Llanowar Elves","5.12","2.9pp"`},
+		{"no card rows", seventeenLandsHeader + "\n"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		if ratings, _, err := parseRatingsCSV(strings.NewReader(tt.csv)); err == nil {
+			t.Errorf("%s: parseRatingsCSV() = %+v, want an error", tt.name, ratings)
+		}
+	}
+}
+
+func TestParseRatingValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"58.1%", 58.1, true},
+		{"4.4pp", 4.4, true},
+		{"-1.2pp", -1.2, true},
+		{" 2.31 ", 2.31, true},
+		{"", 0, false},
+		{"%", 0, false},
+		{"n/a", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRatingValue(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRatingValue(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
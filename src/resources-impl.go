@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// URI scheme of the server's resources
+const resourceScheme = "mtg"
+
+// maxStoredDecks is how many built decks are kept as resources; the oldest
+// is dropped when another is built
+const maxStoredDecks = 100
+
+var oracleIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// storedDeck is a deck built by one of the deck tools, readable as
+// mtg://deck/{id}
+type storedDeck struct {
+	ID          string
+	Name        string
+	Description string
+	Decklist    string
+}
+
+// decksMu guards the stored decks; deckResourcesMu keeps the server's deck
+// resources in the order the decks were stored, without holding decksMu while
+// the server notifies clients of the change
+var (
+	decksMu         sync.Mutex
+	deckResourcesMu sync.Mutex
+	storedDecks     = map[string]storedDeck{}
+	storedDeckIDs   []string
+	resourceServer  *mcp.Server
+)
+
+// resourcePath checks that uri is an mtg:// URI of the given kind, such as
+// card or set, and returns its unescaped path segments
+func resourcePath(uri, kind string) ([]string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != resourceScheme || parsed.Host != kind {
+		return nil, fmt.Errorf("not an %s://%s/ URI: %s", resourceScheme, kind, uri)
+	}
+
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(parsed.EscapedPath(), "/"), "/") {
+		value, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			segments = append(segments, value)
+		}
+	}
+	return segments, nil
+}
+
+// textResource wraps text as the contents of a resource read
+func textResource(uri, mimeType, text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: mimeType, Text: text}},
+	}
+}
+
+// cardFaceText writes one face of a card: name and cost, type line, rules
+// text and stats
+func cardFaceText(name, manaCost, typeLine, oracleText string, power, toughness, loyalty, defense *string) []string {
+	heading := "## " + name
+	if manaCost != "" {
+		heading += " " + manaCost
+	}
+	lines := []string{heading, "", typeLine}
+	if oracleText != "" {
+		lines = append(lines, "", oracleText)
+	}
+	switch {
+	case power != nil && toughness != nil:
+		lines = append(lines, "", fmt.Sprintf("%s/%s", *power, *toughness))
+	case loyalty != nil:
+		lines = append(lines, "", "Loyalty: "+*loyalty)
+	case defense != nil:
+		lines = append(lines, "", "Defense: "+*defense)
+	}
+	return lines
+}
+
+// cardResourceText writes a card as Markdown for attaching to a conversation
+func cardResourceText(card scryfall.Card) string {
+	lines := []string{"# " + card.Name, ""}
+	if len(card.CardFaces) > 0 {
+		for _, face := range card.CardFaces {
+			oracleText := ""
+			if face.OracleText != nil {
+				oracleText = *face.OracleText
+			}
+			lines = append(lines, cardFaceText(face.Name, face.ManaCost, face.TypeLine, oracleText, face.Power, face.Toughness, face.Loyalty, face.Defense)...)
+			lines = append(lines, "")
+		}
+	} else {
+		lines = append(lines, cardFaceText(card.Name, card.ManaCost, card.TypeLine, card.OracleText, card.Power, card.Toughness, card.Loyalty, card.Defense)...)
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, fmt.Sprintf("%s (%s) #%s, %s", card.SetName, strings.ToUpper(card.Set), card.CollectorNumber, card.Rarity))
+	legal := []string{}
+	for _, legality := range cardFormatLegalities(card) {
+		if legality.Status == LegalityLegal || legality.Status == LegalityRestricted {
+			legal = append(legal, legalityFormatNames[legality.Format])
+		}
+	}
+	if len(legal) > 0 {
+		lines = append(lines, "Legal in: "+strings.Join(legal, ", "))
+	}
+	if card.ScryfallURI != "" {
+		lines = append(lines, card.ScryfallURI)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readCardResource reads mtg://card/{set}/{number} and
+// mtg://card/oracle/{oracle_id}
+func readCardResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, err := resourcePath(uri, "card")
+	if err != nil || len(segments) != 2 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		return nil, fmt.Errorf("initializing card search service: %w", err)
+	}
+
+	var card scryfall.Card
+	if segments[0] == "oracle" {
+		oracleID := strings.ToLower(segments[1])
+		if !oracleIDPattern.MatchString(oracleID) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		log.Printf("Reading card resource by oracle ID %s", oracleID)
+		result, err := client.SearchCards(ctx, "oracleid:"+oracleID, scryfall.SearchCardsOptions{Unique: scryfall.UniqueModeCards})
+		if err != nil {
+			var scryfallErr *scryfall.Error
+			if errors.As(err, &scryfallErr) && scryfallErr.Status == 404 {
+				return nil, mcp.ResourceNotFoundError(uri)
+			}
+			return nil, fmt.Errorf("looking up card: %w", err)
+		}
+		if len(result.Cards) == 0 {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		card = result.Cards[0]
+	} else {
+		log.Printf("Reading card resource %s #%s", segments[0], segments[1])
+		card, err = client.GetCardBySetCodeAndCollectorNumber(ctx, strings.ToLower(segments[0]), segments[1])
+		if err != nil {
+			var scryfallErr *scryfall.Error
+			if errors.As(err, &scryfallErr) && scryfallErr.Status == 404 {
+				return nil, mcp.ResourceNotFoundError(uri)
+			}
+			return nil, fmt.Errorf("looking up card: %w", err)
+		}
+	}
+	return textResource(uri, "text/markdown", cardResourceText(card)), nil
+}
+
+// readSetResource reads mtg://set/{code}, from the bundled sets when
+// Scryfall cannot be reached
+func readSetResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, err := resourcePath(uri, "set")
+	if err != nil || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using bundled sets: %v", err)
+		client = nil
+	}
+	set, source, err := findSet(ctx, client, segments[0])
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	result := GetSetResult{Set: newSetInfo(set), Source: source}
+	result.SnapshotDate = setSnapshotDate(source)
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return textResource(uri, "application/json", string(data)), nil
+}
+
+// readRulesResource reads mtg://rules/{number}: a chapter, section, rule or
+// subrule of the Comprehensive Rules, or a glossary term
+func readRulesResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, err := resourcePath(uri, "rules")
+	if err != nil || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	document := loadComprehensiveRules()
+	text, ok := lookupRules(document, segments[0])
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return textResource(uri, "text/plain", rulesHeader(document)+"\n\n"+text), nil
+}
+
+// readDeckResource reads mtg://deck/{id}, a deck built earlier in this run
+// of the server by any of its clients
+func readDeckResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, err := resourcePath(uri, "deck")
+	if err != nil || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	decksMu.Lock()
+	deck, ok := storedDecks[segments[0]]
+	decksMu.Unlock()
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return textResource(uri, "text/plain", deck.Decklist), nil
+}
+
+func deckResourceURI(id string) string {
+	return fmt.Sprintf("%s://deck/%s", resourceScheme, id)
+}
+
+// storeDeck keeps a built deck as a resource and returns its URI. Decks are
+// identified by their decklist, so building the same deck again reuses the
+// same URI. The store belongs to the server, not a session: every client
+// connected to it lists and can read every stored deck.
+func storeDeck(name, description, decklist string) string {
+	sum := sha256.Sum256([]byte(decklist))
+	deck := storedDeck{ID: hex.EncodeToString(sum[:])[:12], Name: name, Description: description, Decklist: decklist}
+	uri := deckResourceURI(deck.ID)
+
+	deckResourcesMu.Lock()
+	defer deckResourcesMu.Unlock()
+
+	decksMu.Lock()
+	if _, ok := storedDecks[deck.ID]; !ok {
+		storedDeckIDs = append(storedDeckIDs, deck.ID)
+	}
+	storedDecks[deck.ID] = deck
+	evicted := []string{}
+	for len(storedDeckIDs) > maxStoredDecks {
+		evicted = append(evicted, deckResourceURI(storedDeckIDs[0]))
+		delete(storedDecks, storedDeckIDs[0])
+		storedDeckIDs = storedDeckIDs[1:]
+	}
+	server := resourceServer
+	decksMu.Unlock()
+
+	if server != nil {
+		if len(evicted) > 0 {
+			server.RemoveResources(evicted...)
+		}
+		server.AddResource(&mcp.Resource{
+			URI:         uri,
+			Name:        "deck-" + deck.ID,
+			Title:       name,
+			Description: description,
+			MIMEType:    "text/plain",
+			Size:        int64(len(decklist)),
+		}, readDeckResource)
+	}
+	return uri
+}
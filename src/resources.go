@@ -2,9 +2,102 @@ package main
 
 import (
 	"embed"
+	"fmt"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Embed all resource files from the res directory
 // https://pkg.go.dev/embed
 //go:embed res/*
 var embeddedResources embed.FS
+
+func registerCardResourceTemplates(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "://card/{set}/{number}",
+		Name:        "card",
+		Title:       "Card by set and collector number",
+		Description: "A Magic: The Gathering card printing, such as mtg://card/m21/123, with its rules text, stats and legality.",
+		MIMEType:    "text/markdown",
+	}, readCardResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "://card/oracle/{oracle_id}",
+		Name:        "card-oracle",
+		Title:       "Card by oracle ID",
+		Description: "A Magic: The Gathering card by its Scryfall oracle ID, which is shared by every printing.",
+		MIMEType:    "text/markdown",
+	}, readCardResource)
+
+	log.Println("Resource templates 'card' and 'card-oracle' registered.")
+}
+
+func registerSetResources(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "://set/{code}",
+		Name:        "set",
+		Title:       "Set",
+		Description: "A Magic: The Gathering set by code, such as mtg://set/dmu, with its type, release date and card count.",
+		MIMEType:    "application/json",
+	}, readSetResource)
+
+	snapshot, _ := offlineSets()
+	sets := snapshot.Sets
+	for _, set := range sets {
+		server.AddResource(&mcp.Resource{
+			URI:         fmt.Sprintf("%s://set/%s", resourceScheme, set.Code),
+			Name:        "set-" + set.Code,
+			Title:       set.Name,
+			Description: fmt.Sprintf("%s, released %s", set.Name, setReleaseDate(set)),
+			MIMEType:    "application/json",
+		}, readSetResource)
+	}
+
+	log.Printf("Resource template 'set' and %d set resources registered.", len(sets))
+}
+
+func registerRulesResources(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "://rules/{number}",
+		Name:        "rules",
+		Title:       "Comprehensive Rules",
+		Description: "A chapter (7), section (702), rule (702.9) or subrule (702.9a) of the Magic: The Gathering Comprehensive Rules, or a glossary term such as Flying.",
+		MIMEType:    "text/plain",
+	}, readRulesResource)
+
+	document := loadComprehensiveRules()
+	for _, section := range document.Sections {
+		server.AddResource(&mcp.Resource{
+			URI:         fmt.Sprintf("%s://rules/%s", resourceScheme, section.Number),
+			Name:        "rules-" + section.Number,
+			Title:       section.Text,
+			Description: fmt.Sprintf("Every rule in section %s of the Comprehensive Rules", section.Number),
+			MIMEType:    "text/plain",
+		}, readRulesResource)
+	}
+
+	log.Printf("Resource template 'rules' and %d rules section resources registered.", len(document.Sections))
+}
+
+func registerDeckResources(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "://deck/{id}",
+		Name:        "deck",
+		Title:       "Built deck",
+		Description: "A deck built by build_commander_deck or build_limited_deck, by the ID in the deck_uri the tool returned.",
+		MIMEType:    "text/plain",
+	}, readDeckResource)
+
+	decksMu.Lock()
+	resourceServer = server
+	decksMu.Unlock()
+
+	log.Println("Resource template 'deck' registered.")
+}
+
+func registerResources(server *mcp.Server) {
+	registerCardResourceTemplates(server)
+	registerSetResources(server)
+	registerRulesResources(server)
+	registerDeckResources(server)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Rule numbers in res/comprules.txt: chapters such as "7", sections such as
+// "702", rules such as "702.9" and subrules such as "702.9a"
+var (
+	ruleChapterPattern   = regexp.MustCompile(`^(\d)\. (.+)$`)
+	ruleSectionPattern   = regexp.MustCompile(`^(\d{3})\. (.+)$`)
+	ruleNumberPattern    = regexp.MustCompile(`^(\d{3}\.\d+[a-z]?)\.? `)
+	ruleEffectivePattern = regexp.MustCompile(`effective as of ([A-Z][a-z]+ \d{1,2}, \d{4})`)
+)
+
+// ruleEntry is one numbered paragraph of the Comprehensive Rules, or one
+// glossary term with its definition
+type ruleEntry struct {
+	Number string
+	Text   string
+}
+
+// rulesDocument is the parsed Comprehensive Rules, in document order
+type rulesDocument struct {
+	Effective string
+	Chapters  []ruleEntry
+	Sections  []ruleEntry
+	Rules     []ruleEntry
+	Glossary  []ruleEntry
+}
+
+var (
+	comprehensiveRulesOnce sync.Once
+	comprehensiveRules     rulesDocument
+)
+
+// parseComprehensiveRules splits the rules text into chapters, sections,
+// rules and glossary entries. Paragraphs are separated by blank lines; an
+// example stays in the paragraph of the rule it belongs to.
+func parseComprehensiveRules(text string) rulesDocument {
+	var document rulesDocument
+	inGlossary := false
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		switch {
+		case paragraph == "":
+		case paragraph == "Glossary":
+			inGlossary = true
+		case paragraph == "Credits":
+			inGlossary = false
+		case inGlossary:
+			term, _, _ := strings.Cut(paragraph, "\n")
+			document.Glossary = append(document.Glossary, ruleEntry{Number: term, Text: paragraph})
+		case ruleNumberPattern.MatchString(paragraph):
+			number := ruleNumberPattern.FindStringSubmatch(paragraph)[1]
+			document.Rules = append(document.Rules, ruleEntry{Number: number, Text: paragraph})
+		case ruleSectionPattern.MatchString(paragraph):
+			number := ruleSectionPattern.FindStringSubmatch(paragraph)[1]
+			document.Sections = append(document.Sections, ruleEntry{Number: number, Text: paragraph})
+		case ruleChapterPattern.MatchString(paragraph):
+			number := ruleChapterPattern.FindStringSubmatch(paragraph)[1]
+			document.Chapters = append(document.Chapters, ruleEntry{Number: number, Text: paragraph})
+		case document.Effective == "" && ruleEffectivePattern.MatchString(paragraph):
+			document.Effective = ruleEffectivePattern.FindStringSubmatch(paragraph)[1]
+		}
+	}
+	return document
+}
+
+// loadComprehensiveRules returns the Comprehensive Rules shipped with the
+// server
+func loadComprehensiveRules() rulesDocument {
+	comprehensiveRulesOnce.Do(func() {
+		data, err := embeddedResources.ReadFile("res/comprules.txt")
+		if err != nil {
+			log.Printf("Error loading comprehensive rules: %v", err)
+			return
+		}
+		comprehensiveRules = parseComprehensiveRules(string(data))
+	})
+	return comprehensiveRules
+}
+
+// lookupRules returns the text of a rules reference: a chapter lists its
+// sections, a section or rule includes every rule under it, and anything
+// else is looked up as a glossary term
+func lookupRules(document rulesDocument, reference string) (string, bool) {
+	reference = strings.TrimSuffix(strings.TrimSpace(reference), ".")
+	if reference == "" {
+		return "", false
+	}
+
+	for _, chapter := range document.Chapters {
+		if chapter.Number != reference {
+			continue
+		}
+		lines := []string{chapter.Text, ""}
+		for _, section := range document.Sections {
+			if strings.HasPrefix(section.Number, reference) {
+				lines = append(lines, section.Text)
+			}
+		}
+		return strings.Join(lines, "\n"), true
+	}
+
+	paragraphs := []string{}
+	for _, section := range document.Sections {
+		if section.Number == reference {
+			paragraphs = append(paragraphs, section.Text)
+		}
+	}
+	for _, rule := range document.Rules {
+		if ruleUnder(rule.Number, reference) {
+			paragraphs = append(paragraphs, rule.Text)
+		}
+	}
+	if len(paragraphs) > 0 {
+		return strings.Join(paragraphs, "\n\n"), true
+	}
+
+	for _, entry := range document.Glossary {
+		if strings.EqualFold(entry.Number, reference) {
+			return entry.Text, true
+		}
+	}
+	return "", false
+}
+
+// ruleUnder reports whether a rule number is reference itself or one of its
+// subrules: 702.9a is under 702.9 and 702, but 702.90 is not under 702.9
+func ruleUnder(number, reference string) bool {
+	if number == reference {
+		return true
+	}
+	if !strings.HasPrefix(number, reference) {
+		return false
+	}
+	next := number[len(reference)]
+	if strings.Contains(reference, ".") {
+		return next >= 'a' && next <= 'z'
+	}
+	return next == '.'
+}
+
+// rulesHeader names the rules document and its effective date
+func rulesHeader(document rulesDocument) string {
+	if document.Effective == "" {
+		return "Magic: The Gathering Comprehensive Rules"
+	}
+	return fmt.Sprintf("Magic: The Gathering Comprehensive Rules, effective %s", document.Effective)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Scryfall asks for no more than about ten requests a second. Every client
+// the server creates shares one limiter, so concurrent sessions and parallel
+// sub-searches together stay within it.
+const (
+	defaultScryfallRate  = 10
+	defaultScryfallBurst = 2
+)
+
+// Retry and circuit breaker settings for Scryfall requests
+const (
+	defaultScryfallRetries  = 3
+	scryfallAttemptTimeout  = 30 * time.Second
+	scryfallBaseBackoff     = 500 * time.Millisecond
+	scryfallMaxBackoff      = 10 * time.Second
+	scryfallMaxRetryAfter   = 30 * time.Second
+	scryfallBreakerFailures = 5
+	scryfallBreakerCooldown = 30 * time.Second
+)
+
+// defaultScryfallCacheBytes bounds the responses kept to answer requests
+// while Scryfall is unavailable
+const defaultScryfallCacheBytes = 64 << 20
+
+// errScryfallUnavailable is returned without contacting Scryfall while the
+// circuit breaker is open and nothing is cached for the request
+var errScryfallUnavailable = errors.New("Scryfall is unavailable, try again later")
+
+// tokenBucket is a rate limiter that allows bursts of up to capacity
+// requests and refills at rate tokens a second
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, capacity int) *tokenBucket {
+	return &tokenBucket{rate: rate, capacity: float64(capacity), tokens: float64(capacity), last: time.Now()}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// circuitBreaker stops requests to Scryfall after repeated failures. Once
+// the cooldown has passed one request is let through as a probe: success
+// closes the breaker, failure opens it again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// Allow reports whether a request may be sent now
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < scryfallBreakerFailures {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= scryfallBreakerFailures {
+		log.Println("Scryfall is reachable again, closing circuit breaker")
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+// Abandon lets another probe through when the caller of this one gave up
+// before Scryfall answered
+func (b *circuitBreaker) Abandon() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= scryfallBreakerFailures {
+		if b.failures == scryfallBreakerFailures {
+			log.Printf("Scryfall failed %d times in a row, failing fast for %s", b.failures, scryfallBreakerCooldown)
+		}
+		b.openUntil = time.Now().Add(scryfallBreakerCooldown)
+	}
+}
+
+// responseCache keeps the bodies of successful GET responses, least recently
+// used first out, to answer requests Scryfall can't
+type responseCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type cachedResponse struct {
+	url    string
+	header http.Header
+	body   []byte
+}
+
+func newResponseCache(maxBytes int) *responseCache {
+	return &responseCache{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *responseCache) Put(url string, header http.Header, body []byte) {
+	if len(body) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[url]; ok {
+		c.size -= len(element.Value.(*cachedResponse).body)
+		c.order.Remove(element)
+	}
+	c.entries[url] = c.order.PushFront(&cachedResponse{url: url, header: header.Clone(), body: body})
+	c.size += len(body)
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cachedResponse)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.url)
+		c.size -= len(entry.body)
+	}
+}
+
+func (c *responseCache) Get(url string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResponse), true
+}
+
+// scryfallTransport sends every Scryfall request through the shared limiter,
+// retries throttled and unavailable responses, and falls back to the last
+// good response when Scryfall keeps failing
+type scryfallTransport struct {
+	base    http.RoundTripper
+	limiter *tokenBucket
+	breaker *circuitBreaker
+	cache   *responseCache
+	retries int
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads a Retry-After header given in seconds or as a date
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// retryDelay is the wait before retry attempt: exponential backoff with full
+// jitter, or the server's Retry-After when that is longer
+func retryDelay(attempt int, header http.Header) time.Duration {
+	backoff := min(scryfallBaseBackoff<<attempt, scryfallMaxBackoff)
+	delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+	if after, ok := retryAfter(header); ok && after > delay {
+		delay = after
+	}
+	return delay
+}
+
+// attempt sends one request with its own timeout and reads the whole body,
+// so the timeout can be released before the response is returned
+func (t *scryfallTransport) attempt(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), scryfallAttemptTimeout)
+	defer cancel()
+
+	attemptReq := req.Clone(ctx)
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+	resp, err := t.base.RoundTrip(attemptReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// fromCache answers req from the cache, if it has the URL, and otherwise
+// returns cause
+func (t *scryfallTransport) fromCache(req *http.Request, cause error) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, cause
+	}
+	cached, ok := t.cache.Get(req.URL.String())
+	if !ok {
+		return nil, cause
+	}
+	log.Printf("Scryfall unavailable (%v), answering %s from cache", cause, req.URL.Path)
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cached.body)),
+		ContentLength: int64(len(cached.body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *scryfallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.Allow() {
+		return t.fromCache(req, errScryfallUnavailable)
+	}
+	canRetry := req.Body == nil || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		if req.Context().Err() != nil {
+			// The caller gave up; that says nothing about Scryfall
+			t.breaker.Abandon()
+			return nil, req.Context().Err()
+		}
+
+		var header http.Header
+		switch {
+		case err != nil:
+			// Network errors aren't retried, so that tools with an offline
+			// fallback reach it quickly
+			log.Printf("Scryfall request %s failed: %v", req.URL.Path, err)
+			t.breaker.Failure()
+			return t.fromCache(req, err)
+		case retryableStatus(resp.StatusCode):
+			header = resp.Header
+			err = fmt.Errorf("Scryfall returned %s", resp.Status)
+			log.Printf("Scryfall request %s: %s", req.URL.Path, resp.Status)
+		case resp.StatusCode >= 500:
+			t.breaker.Failure()
+			return t.fromCacheOr(req, resp)
+		default:
+			t.breaker.Success()
+			if req.Method == http.MethodGet && resp.StatusCode == http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				t.cache.Put(req.URL.String(), resp.Header, body)
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			return resp, nil
+		}
+
+		delay := retryDelay(attempt, header)
+		if attempt >= t.retries || !canRetry || delay > scryfallMaxRetryAfter {
+			t.breaker.Failure()
+			return t.fromCacheOr(req, resp)
+		}
+		debugf("Retrying Scryfall request %s in %s (attempt %d)", req.URL.Path, delay.Round(time.Millisecond), attempt+1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			t.breaker.Abandon()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// fromCacheOr answers from the cache when it can, and otherwise passes on
+// Scryfall's error response so its status and details reach the caller
+func (t *scryfallTransport) fromCacheOr(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if cached, err := t.fromCache(req, fmt.Errorf("Scryfall returned %s", resp.Status)); err == nil {
+		return cached, nil
+	}
+	return resp, nil
+}
+
+var sharedScryfallTransport = &scryfallTransport{
+	base:    http.DefaultTransport,
+	limiter: newTokenBucket(defaultScryfallRate, defaultScryfallBurst),
+	breaker: &circuitBreaker{},
+	cache:   newResponseCache(defaultScryfallCacheBytes),
+	retries: defaultScryfallRetries,
+}
+
+// setupScryfallAccess applies the configured rate limit, retries and cache
+// size to every Scryfall client
+func setupScryfallAccess(config *Config) {
+	sharedScryfallTransport.limiter = newTokenBucket(config.ScryfallRate, config.ScryfallBurst)
+	sharedScryfallTransport.retries = config.ScryfallRetries
+	sharedScryfallTransport.cache = newResponseCache(config.ScryfallCacheMB << 20)
+	log.Printf("Limiting Scryfall requests to %g a second (bursts of %d), %d retries, %d MB fallback cache",
+		config.ScryfallRate, config.ScryfallBurst, config.ScryfallRetries, config.ScryfallCacheMB)
+}
+
+// newScryfallClient returns a Scryfall client that shares the server's rate
+// limiter, retry policy, circuit breaker and fallback cache. The client's own
+// limiter is turned off, since the shared one replaces it.
+func newScryfallClient() (*scryfall.Client, error) {
+	return scryfall.NewClient(
+		scryfall.WithLimiter(nil),
+		scryfall.WithHTTPClient(&http.Client{Transport: sharedScryfallTransport}),
+	)
+}
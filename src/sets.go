@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Sources of set data
+const (
+	SetSourceScryfall = "scryfall"
+	SetSourceSnapshot = "snapshot"
+	SetSourceBundled  = "bundled"
+	SetSourceOffline  = "offline"
+)
+
+// Paging limits for browse_set. Scryfall returns search results in pages of
+// scryfallSearchPageSize cards.
+const (
+	defaultSetPageSize     = 50
+	maxSetPageSize         = 175
+	scryfallSearchPageSize = 175
+	defaultSetListResults  = 100
+)
+
+// setRarities are the rarities browse_set accepts
+var setRarities = []string{"common", "uncommon", "rare", "mythic", "special", "bonus"}
+
+// setDataset is the format of res/sets.json: a snapshot of Scryfall's /sets
+// list with the date it was taken
+type setDataset struct {
+	Version string         `json:"version"`
+	Sets    []scryfall.Set `json:"data"`
+}
+
+var (
+	bundledSetsOnce sync.Once
+	bundledSets     setDataset
+)
+
+// setSnapshotFile is where the latest set list from Scryfall is saved, at
+// most once a day, to be used instead of the older bundled snapshot when
+// Scryfall can't be reached
+var (
+	setSnapshotMu   sync.Mutex
+	setSnapshotFile string
+	setSnapshot     *setDataset
+)
+
+// setupSetSnapshot sets the file the set list is saved to
+func setupSetSnapshot(config *Config) {
+	setSnapshotMu.Lock()
+	setSnapshotFile = config.SetSnapshotFile
+	setSnapshotMu.Unlock()
+}
+
+// loadBundledSets returns the set snapshot shipped with the server
+func loadBundledSets() setDataset {
+	bundledSetsOnce.Do(func() {
+		data, err := embeddedResources.ReadFile("res/sets.json")
+		if err != nil {
+			log.Printf("Error loading bundled sets: %v", err)
+			return
+		}
+		if err := json.Unmarshal(data, &bundledSets); err != nil {
+			log.Printf("Error parsing bundled sets: %v", err)
+		}
+	})
+	return bundledSets
+}
+
+// loadSetSnapshot returns the saved set list, reading it on first use
+func loadSetSnapshot() (setDataset, bool) {
+	setSnapshotMu.Lock()
+	defer setSnapshotMu.Unlock()
+	if setSnapshot != nil {
+		return *setSnapshot, true
+	}
+	if setSnapshotFile == "" {
+		return setDataset{}, false
+	}
+	data, err := os.ReadFile(setSnapshotFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error reading set snapshot %s: %v", setSnapshotFile, err)
+		}
+		return setDataset{}, false
+	}
+	var dataset setDataset
+	if err := json.Unmarshal(data, &dataset); err != nil || len(dataset.Sets) == 0 {
+		log.Printf("Error parsing set snapshot %s: %v", setSnapshotFile, err)
+		return setDataset{}, false
+	}
+	setSnapshot = &dataset
+	return dataset, true
+}
+
+// saveSetSnapshot saves the set list from Scryfall unless it was already
+// saved today
+func saveSetSnapshot(sets []scryfall.Set) {
+	today := time.Now().UTC().Format(time.DateOnly)
+	if saved, ok := loadSetSnapshot(); ok && saved.Version == today {
+		return
+	}
+
+	dataset := setDataset{Version: today, Sets: sets}
+	data, err := json.MarshalIndent(dataset, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding set snapshot: %v", err)
+		return
+	}
+
+	setSnapshotMu.Lock()
+	defer setSnapshotMu.Unlock()
+	if setSnapshotFile == "" {
+		return
+	}
+	if err := os.WriteFile(setSnapshotFile, data, 0644); err != nil {
+		log.Printf("Error saving set snapshot %s: %v", setSnapshotFile, err)
+		return
+	}
+	setSnapshot = &dataset
+	log.Printf("Saved %d sets to %s", len(sets), setSnapshotFile)
+}
+
+// offlineSets returns the newest set list that doesn't need Scryfall: the
+// saved snapshot, or the bundled one when it is newer or there is none
+func offlineSets() (setDataset, string) {
+	bundled := loadBundledSets()
+	if saved, ok := loadSetSnapshot(); ok && saved.Version >= bundled.Version {
+		return saved, SetSourceSnapshot
+	}
+	return bundled, SetSourceBundled
+}
+
+// setSnapshotDate is the date of the snapshot set data came from, or "" when
+// it came from Scryfall
+func setSnapshotDate(source string) string {
+	if source == SetSourceScryfall {
+		return ""
+	}
+	snapshot, _ := offlineSets()
+	return snapshot.Version
+}
+
+// fetchSets lists every set from Scryfall, falling back to the saved or
+// bundled snapshot when Scryfall cannot be reached. The returned source
+// tells which one was used.
+func fetchSets(ctx context.Context, client *scryfall.Client) ([]scryfall.Set, string, error) {
+	if client != nil {
+		sets, err := client.ListSets(ctx)
+		if err == nil {
+			saveSetSnapshot(sets)
+			return sets, SetSourceScryfall, nil
+		}
+		log.Printf("Error listing sets from Scryfall, using a snapshot: %v", err)
+	}
+	snapshot, source := offlineSets()
+	if len(snapshot.Sets) == 0 {
+		return nil, "", errors.New("set data is unavailable")
+	}
+	return snapshot.Sets, source, nil
+}
+
+// findSet looks a set up by code, or by name in the set list. When Scryfall
+// cannot be reached the saved or bundled snapshot is searched instead.
+func findSet(ctx context.Context, client *scryfall.Client, codeOrName string) (scryfall.Set, string, error) {
+	codeOrName = strings.TrimSpace(codeOrName)
+	if client != nil && !strings.Contains(codeOrName, " ") {
+		set, err := client.GetSet(ctx, strings.ToLower(codeOrName))
+		if err == nil {
+			return set, SetSourceScryfall, nil
+		}
+		var scryfallErr *scryfall.Error
+		if !errors.As(err, &scryfallErr) || scryfallErr.Status != 404 {
+			log.Printf("Error getting set %s from Scryfall, using a snapshot: %v", codeOrName, err)
+			client = nil
+		}
+	}
+
+	sets, source, err := fetchSets(ctx, client)
+	if err != nil {
+		return scryfall.Set{}, "", err
+	}
+	for _, set := range sets {
+		if strings.EqualFold(set.Code, codeOrName) || strings.EqualFold(set.Name, codeOrName) {
+			return set, source, nil
+		}
+	}
+	return scryfall.Set{}, "", fmt.Errorf("set '%s' not found", codeOrName)
+}
+
+// parseSetDate parses a YYYY-MM-DD date filter
+func parseSetDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s'; use YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+// setReleaseDate returns a set's release date as YYYY-MM-DD, or "" if unknown
+func setReleaseDate(set scryfall.Set) string {
+	if set.ReleasedAt == nil {
+		return ""
+	}
+	return set.ReleasedAt.Format(time.DateOnly)
+}
+
+// filterSets returns the sets matching every filter in args. Types and blocks
+// are compared case-insensitively and blocks match either name or code.
+func filterSets(sets []scryfall.Set, args ListSetsArgs) ([]scryfall.Set, error) {
+	after, err := parseSetDate(args.ReleasedAfter)
+	if err != nil {
+		return nil, err
+	}
+	before, err := parseSetDate(args.ReleasedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	types := []string{}
+	for _, setType := range strings.Split(args.SetType, ",") {
+		if setType = strings.ToLower(strings.TrimSpace(setType)); setType != "" {
+			types = append(types, setType)
+		}
+	}
+
+	filtered := []scryfall.Set{}
+	for _, set := range sets {
+		if len(types) > 0 && !contains(types, strings.ToLower(string(set.SetType))) {
+			continue
+		}
+		if args.Block != "" {
+			block, blockCode := "", ""
+			if set.Block != nil {
+				block = *set.Block
+			}
+			if set.BlockCode != nil {
+				blockCode = *set.BlockCode
+			}
+			if !strings.EqualFold(block, args.Block) && !strings.EqualFold(blockCode, args.Block) {
+				continue
+			}
+		}
+		if args.Digital != nil && set.Digital != *args.Digital {
+			continue
+		}
+		if !after.IsZero() || !before.IsZero() {
+			released := setReleaseDate(set)
+			if released == "" {
+				continue
+			}
+			if !after.IsZero() && released < after.Format(time.DateOnly) {
+				continue
+			}
+			if !before.IsZero() && released > before.Format(time.DateOnly) {
+				continue
+			}
+		}
+		filtered = append(filtered, set)
+	}
+	return filtered, nil
+}
+
+// newSetInfo converts a Scryfall set into the summary returned by the set tools
+func newSetInfo(set scryfall.Set) SetInfo {
+	info := SetInfo{
+		Code:          set.Code,
+		Name:          set.Name,
+		SetType:       strings.ToLower(string(set.SetType)),
+		ReleasedAt:    setReleaseDate(set),
+		ParentSetCode: set.ParentSetCode,
+		CardCount:     set.CardCount,
+		Digital:       set.Digital,
+		FoilOnly:      set.FoilOnly,
+		IconSVGURI:    set.IconSVGURI,
+		ScryfallURI:   set.ScryfallURI,
+	}
+	if set.Block != nil {
+		info.Block = *set.Block
+	}
+	if set.BlockCode != nil {
+		info.BlockCode = *set.BlockCode
+	}
+	return info
+}
+
+// setCardsQuery builds the search for a set's cards, optionally limited to a
+// rarity and to cards that include the given colors
+func setCardsQuery(code, rarity, colors string) (string, error) {
+	query := fmt.Sprintf("e:%s", code)
+	if rarity != "" {
+		rarity = strings.ToLower(strings.TrimSpace(rarity))
+		if !contains(setRarities, rarity) {
+			return "", fmt.Errorf("unknown rarity '%s'; use one of %s", rarity, strings.Join(setRarities, ", "))
+		}
+		query += " r:" + rarity
+	}
+	if colors != "" {
+		identity, err := parseColorIdentity(colors)
+		if err != nil {
+			return "", err
+		}
+		if identity == "C" {
+			query += " c:c"
+		} else {
+			query += " c>=" + identity
+		}
+	}
+	return query, nil
+}
+
+// browseSetCards returns one page of a set search in collector number order.
+// Pages of any size are cut from Scryfall's fixed-size pages, fetching the
+// next Scryfall page when a page spans two.
+func browseSetCards(ctx context.Context, client *scryfall.Client, query string, page, pageSize int) ([]scryfall.Card, int, bool, error) {
+	offset := (page - 1) * pageSize
+	opts := scryfall.SearchCardsOptions{
+		Unique: scryfall.UniqueModePrints,
+		Order:  scryfall.OrderSet,
+		Dir:    scryfall.DirAsc,
+		Page:   offset/scryfallSearchPageSize + 1,
+	}
+	skip := offset % scryfallSearchPageSize
+
+	cards := []scryfall.Card{}
+	total := 0
+	for len(cards) < pageSize {
+		log.Printf("Browsing set cards (Query: %s, Page: %d)", query, opts.Page)
+		result, err := client.SearchCards(ctx, query, opts)
+		if err != nil {
+			var scryfallErr *scryfall.Error
+			if errors.As(err, &scryfallErr) && scryfallErr.Status == 404 {
+				return cards, total, false, nil
+			}
+			return nil, 0, false, err
+		}
+		total = result.TotalCards
+		if skip < len(result.Cards) {
+			cards = append(cards, result.Cards[skip:]...)
+		}
+		skip = 0
+		if !result.HasMore {
+			break
+		}
+		opts.Page++
+	}
+
+	if len(cards) > pageSize {
+		cards = cards[:pageSize]
+	}
+	return cards, total, offset+len(cards) < total, nil
+}
+
+// browseOfflineSetCards returns one page of a set search from the offline
+// card data, in the same form as browseSetCards
+func browseOfflineSetCards(query string, page, pageSize int) ([]scryfall.Card, int, bool, error) {
+	cards, err := offlineSearch(query, false)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	offset := (page - 1) * pageSize
+	if offset >= len(cards) {
+		return []scryfall.Card{}, len(cards), false, nil
+	}
+	end := min(offset+pageSize, len(cards))
+	return cards[offset:end], len(cards), end < len(cards), nil
+}
+
+// newSetCard summarizes a card for browse_set
+func newSetCard(card scryfall.Card) SetCard {
+	setCard := SetCard{
+		CollectorNumber: card.CollectorNumber,
+		Name:            card.Name,
+		ManaCost:        cardManaCost(card),
+		TypeLine:        card.TypeLine,
+		Rarity:          card.Rarity,
+		ScryfallURI:     card.ScryfallURI,
+	}
+	if price, ok := cardPriceUSD(card); ok {
+		setCard.PriceUSD = price
+	}
+	return setCard
+}
@@ -2,51 +2,137 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// https://modelcontextprotocol.io/specification/2025-06-18/basic/transports#streamable-http
+// serverInfo is the body of /info
+type serverInfo struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Transport   string `json:"transport"`
+	Endpoint    string `json:"endpoint"`
+	SSEEndpoint string `json:"sse_endpoint,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Stateless   bool   `json:"stateless,omitempty"`
+}
+
+// runSSEServer serves the deprecated HTTP+SSE transport, for clients that
+// don't support Streamable HTTP yet.
+// https://modelcontextprotocol.io/specification/2024-11-05/basic/transports#http-with-sse
 func runSSEServer(config *Config, server *mcp.Server) {
-	addr := fmt.Sprintf("%s:%s", config.SSEHost, config.SSEPort)
+	log.Printf("Starting MCP server in SSE mode...")
 
-	log.Printf("Starting MCP server in SSE (HTTPS) mode...")
-	log.Printf("Server listening on https://%s%s", addr, config.SSEPath)
-	log.Printf("Clients can connect to: https://%s%s", addr, config.SSEPath)
+	ssePath := config.BasePath + config.SSEPath
+	mux := http.NewServeMux()
+	mux.Handle(ssePath, newSSEHandler(server))
+	serveHTTP(config, mux, serverInfo{Transport: string(TransportSSE), Endpoint: ssePath})
+}
 
-	handler := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
+// runStreamableHTTPServer serves the Streamable HTTP transport, and the SSE
+// transport on the same listener when MCP_HTTP_ENABLE_SSE is set.
+// https://modelcontextprotocol.io/specification/2025-06-18/basic/transports#streamable-http
+//
+// Each client gets a session, identified by the Mcp-Session-Id header, whose
+// stream events are kept so a client that reconnects with Last-Event-ID
+// receives what it missed. In stateless mode there are no sessions, so any
+// instance behind a load balancer can answer any request, but the server
+// can't send requests of its own to the client.
+func runStreamableHTTPServer(config *Config, server *mcp.Server) {
+	log.Printf("Starting MCP server in Streamable HTTP mode...")
+
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
 		return server
-	}, nil)
+	}, &mcp.StreamableHTTPOptions{Stateless: config.HTTPStateless})
 
+	httpPath := config.BasePath + config.HTTPPath
 	mux := http.NewServeMux()
-	mux.Handle(config.SSEPath, handler)
+	mux.Handle(httpPath, handler)
+	info := serverInfo{Transport: string(TransportHTTP), Endpoint: httpPath, Stateless: config.HTTPStateless}
+	if config.HTTPEnableSSE {
+		if config.SSEPath == config.HTTPPath {
+			log.Fatalf("MCP_SSE_PATH and MCP_HTTP_PATH must differ to serve both transports (both are %s)", config.HTTPPath)
+		}
+		mux.Handle(config.BasePath+config.SSEPath, newSSEHandler(server))
+		info.SSEEndpoint = config.BasePath + config.SSEPath
+	}
+	if config.HTTPStateless {
+		log.Println("Streamable HTTP is stateless: no sessions, and no server-to-client requests")
+	}
+	serveHTTP(config, mux, info)
+}
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+func newSSEHandler(server *mcp.Server) http.Handler {
+	return mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
+		return server
+	}, nil)
+}
+
+// serveHTTP adds /health and /info to mux and serves it until the process is
+// told to stop. Every route is under MCP_BASE_PATH, so the server can share a
+// host with others behind a reverse proxy. With a certificate and key it
+// serves HTTPS, reloading the certificate when it changes on disk; without
+// them it serves plain HTTP, for running behind a proxy that terminates TLS.
+func serveHTTP(config *Config, mux *http.ServeMux, info serverInfo) {
+	if (config.SSLCertFile == "") != (config.SSLKeyFile == "") {
+		log.Fatalf("MCP_SSL_CERT_FILE and MCP_SSL_KEY_FILE must be set together")
+	}
+	useTLS := config.SSLCertFile != ""
+
+	if err := setupTrustedProxies(config); err != nil {
+		log.Fatalf("Invalid MCP_TRUSTED_PROXIES: %v", err)
+	}
+	if len(trustedProxies) > 0 {
+		log.Printf("Trusting X-Forwarded-* headers from %s", strings.Join(config.TrustedProxies, ", "))
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	addr := fmt.Sprintf("%s:%s", config.SSEHost, config.SSEPort)
+	log.Printf("Server listening on %s://%s%s", scheme, addr, info.Endpoint)
+	log.Printf("Clients can connect to: %s://%s%s", scheme, addr, info.Endpoint)
+	if info.SSEEndpoint != "" {
+		log.Printf("SSE clients can connect to: %s://%s%s", scheme, addr, info.SSEEndpoint)
+	}
+
+	mux.HandleFunc(config.BasePath+"/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
-	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+	info.Name = config.ServerName
+	info.Version = config.ServerVersion
+	mux.HandleFunc(config.BasePath+"/info", func(w http.ResponseWriter, r *http.Request) {
+		reply := info
+		reply.URL = fmt.Sprintf("%s://%s%s", requestScheme(r), r.Host, info.Endpoint)
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"name":"%s","version":"%s","transport":"sse","endpoint":"%s"}`,
-			config.ServerName, config.ServerVersion, config.SSEPath)
+		json.NewEncoder(w).Encode(reply)
 	})
 
+	// No write timeout: responses are event streams that stay open for as
+	// long as a tool runs or the client listens
 	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:        addr,
+		Handler:     withForwardedHeaders(withAccessLog(mux)),
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
 	}
 
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -63,11 +149,23 @@ func runSSEServer(config *Config, server *mcp.Server) {
 
 	log.Printf("Server listening...")
 
-	if config.SSLCertFile == "" || config.SSLKeyFile == "" {
-		log.Fatalf("SSLCertFile and SSLKeyFile must be set. (e.g., MCP_SSL_CERT_FILE and MCP_SSL_KEY_FILE env vars)")
+	if !useTLS {
+		log.Println("No TLS certificate set, serving plain HTTP: run behind a reverse proxy that terminates TLS")
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+		log.Println("Server stopped.")
+		return
+	}
+
+	certs, err := newCertReloader(config.SSLCertFile, config.SSLKeyFile)
+	if err != nil {
+		log.Fatalf("Error loading TLS certificate: %v", err)
 	}
+	go certs.watch(ctx, certReloadInterval)
+	httpServer.TLSConfig = &tls.Config{GetCertificate: certs.GetCertificate}
 
-	if err := httpServer.ListenAndServeTLS(config.SSLCertFile, config.SSLKeyFile); err != nil && err != http.ErrServerClosed {
+	if err := httpServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("HTTPS server failed: %v", err)
 	}
 
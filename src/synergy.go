@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// Weights of the signals that make up a synergy score
+const (
+	sharedThemeWeight        = 2.0
+	sharedKeywordWeight      = 1.0
+	sharedCreatureTypeWeight = 1.5
+	colorIdentityFitWeight   = 1.0
+	offColorPenalty          = -3.0
+	complementaryRoleWeight  = 2.5
+	popularityWeight         = 1.5
+	multipleSearchesWeight   = 0.5
+)
+
+// Lowest EDHREC rank that still earns a popularity bonus
+const popularityRankCutoff = 20000
+
+// Bounds on the searches behind a synergy lookup: at most maxSynergySearches
+// keyword and theme queries, each paging through Scryfall's results up to
+// synergyCandidateBudget cards
+const (
+	maxSynergySearches     = 8
+	synergyCandidateBudget = 350
+)
+
+var (
+	compiledThemePatterns sync.Map
+	payoffClauseRegex     = regexp.MustCompile(`(?i)^(whenever|when|at the beginning)\b|\bfor each\b|\bgets? \+\d+/\+\d+ for\b`)
+	sentenceSplitRegex    = regexp.MustCompile(`[.\n]`)
+)
+
+// compileThemePattern compiles a theme pattern case-insensitively, caching
+// the result so that scoring many candidates doesn't recompile every pattern.
+func compileThemePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledThemePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledThemePatterns.Store(pattern, re)
+	return re, nil
+}
+
+// Roles a card can play in a theme
+const (
+	ThemeRoleEnabler = "enabler"
+	ThemeRolePayoff  = "payoff"
+	ThemeRoleBoth    = "both"
+)
+
+// themeRoles reports, for each theme found on a card, whether the card
+// enables the theme, pays it off, or both. Themes with enabler and payoff
+// patterns are classified by those; for the rest, a sentence matching the
+// theme is treated as a payoff when it is a trigger ("whenever ...") or
+// scales with the theme ("for each ..."), and as an enabler otherwise.
+func themeRoles(card scryfall.Card) map[string]string {
+	text := cardOracleText(card)
+	roles := map[string]string{}
+	for theme, pattern := range loadThemePatterns() {
+		if len(pattern.EnablerPatterns) > 0 || len(pattern.PayoffPatterns) > 0 {
+			enables := anyPatternMatches(pattern.EnablerPatterns, text)
+			pays := anyPatternMatches(pattern.PayoffPatterns, text)
+			switch {
+			case enables && pays:
+				roles[theme] = ThemeRoleBoth
+			case enables:
+				roles[theme] = ThemeRoleEnabler
+			case pays:
+				roles[theme] = ThemeRolePayoff
+			}
+			continue
+		}
+
+		for _, sentence := range sentenceSplitRegex.Split(text, -1) {
+			sentence = strings.TrimSpace(sentence)
+			if sentence == "" || !anyPatternMatches(pattern.Patterns, sentence) {
+				continue
+			}
+			role := ThemeRoleEnabler
+			if payoffClauseRegex.MatchString(sentence) {
+				role = ThemeRolePayoff
+			}
+			if existing, ok := roles[theme]; ok && existing != role {
+				role = ThemeRoleBoth
+			}
+			roles[theme] = role
+		}
+	}
+	return roles
+}
+
+func anyPatternMatches(patterns []string, text string) bool {
+	for _, patternStr := range patterns {
+		if patternStr == "" {
+			continue
+		}
+		re, err := compileThemePattern(patternStr)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// popularityBonus scales popularityWeight by a card's EDHREC rank on a log
+// scale, so the most played cards earn the full weight and cards ranked
+// below popularityRankCutoff earn nothing.
+func popularityBonus(card scryfall.Card) (float64, bool) {
+	if card.EDHRECRank == nil || *card.EDHRECRank <= 0 || *card.EDHRECRank >= popularityRankCutoff {
+		return 0, false
+	}
+	weight := popularityWeight * (1 - math.Log10(float64(*card.EDHRECRank))/math.Log10(popularityRankCutoff))
+	return roundCents(weight), true
+}
+
+// colorIdentityWithin reports whether every color of the candidate's identity
+// is part of the main card's identity.
+func colorIdentityWithin(candidate, main []scryfall.Color) bool {
+	for _, color := range candidate {
+		found := false
+		for _, c := range main {
+			if c == color {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// synergyProfile holds everything about the main card needed to score candidates
+type synergyProfile struct {
+	card          scryfall.Card
+	themes        []string
+	keywords      []string
+	creatureTypes []string
+	changeling    bool
+	roles         map[string]string
+	focusTheme    string
+}
+
+func newSynergyProfile(card scryfall.Card, searchThemes []string, focusTheme string) synergyProfile {
+	themes := extractThemesFromCard(card)
+	for _, theme := range searchThemes {
+		if !contains(themes, theme) {
+			themes = append(themes, theme)
+		}
+	}
+	return synergyProfile{
+		card:          card,
+		themes:        themes,
+		keywords:      extractKeywordsFromText(cardOracleText(card)),
+		creatureTypes: cardCreatureTypes(card),
+		changeling:    isChangeling(card),
+		roles:         themeRoles(card),
+		focusTheme:    focusTheme,
+	}
+}
+
+// scoreSynergyCandidate scores a single candidate against the main card and
+// records every signal that contributed to the score. searches is the number
+// of synergy searches that returned the candidate.
+func scoreSynergyCandidate(profile synergyProfile, candidate scryfall.Card, categories []string, searches int) ScoredSynergyCard {
+	scored := ScoredSynergyCard{
+		Card:       candidate,
+		Categories: categories,
+		Signals:    []SynergySignal{},
+	}
+	addSignal := func(signal string, weight float64, detail string) {
+		scored.Signals = append(scored.Signals, SynergySignal{Signal: signal, Weight: weight, Detail: detail})
+		scored.Score += weight
+	}
+
+	candidateKeywords := extractKeywordsFromText(cardOracleText(candidate))
+	for _, theme := range extractThemesFromCard(candidate) {
+		if !contains(profile.themes, theme) {
+			continue
+		}
+		if contains(candidateKeywords, theme) {
+			if contains(profile.keywords, theme) {
+				addSignal("shared_keyword", sharedKeywordWeight, fmt.Sprintf("Both cards have %s", theme))
+			}
+			continue
+		}
+		weight := sharedThemeWeight
+		if theme == profile.focusTheme {
+			weight *= 2
+		}
+		addSignal("shared_theme", weight, fmt.Sprintf("Both cards fit the %s theme", theme))
+	}
+
+	candidateTypes := cardCreatureTypes(candidate)
+	switch {
+	case profile.changeling && len(candidateTypes) > 0:
+		addSignal("shared_creature_type", sharedCreatureTypeWeight, "The card is a changeling and shares every creature type")
+	case isChangeling(candidate) && len(profile.creatureTypes) > 0:
+		addSignal("shared_creature_type", sharedCreatureTypeWeight, "Changeling, so it shares every creature type")
+	default:
+		for _, creatureType := range candidateTypes {
+			if contains(profile.creatureTypes, creatureType) {
+				addSignal("shared_creature_type", sharedCreatureTypeWeight, fmt.Sprintf("Shares the %s type", creatureType))
+			}
+		}
+	}
+
+	if colorIdentityWithin(candidate.ColorIdentity, profile.card.ColorIdentity) {
+		addSignal("color_identity_fit", colorIdentityFitWeight, "Fits within the card's color identity")
+	} else {
+		addSignal("off_color", offColorPenalty, "Outside the card's color identity")
+	}
+
+	candidateRoles := themeRoles(candidate)
+	for _, theme := range sortedKeys(candidateRoles) {
+		role := candidateRoles[theme]
+		mainRole, ok := profile.roles[theme]
+		if !ok {
+			continue
+		}
+		if mainRole == ThemeRoleEnabler && role != ThemeRoleEnabler {
+			addSignal("complementary_role", complementaryRoleWeight, fmt.Sprintf("Pays off the %s theme the card enables", theme))
+		} else if mainRole == ThemeRolePayoff && role != ThemeRolePayoff {
+			addSignal("complementary_role", complementaryRoleWeight, fmt.Sprintf("Enables the %s theme the card pays off", theme))
+		}
+	}
+
+	if weight, ok := popularityBonus(candidate); ok {
+		addSignal("popularity", weight, fmt.Sprintf("EDHREC rank %d", *candidate.EDHRECRank))
+	}
+
+	if searches > 1 {
+		addSignal("multiple_searches", multipleSearchesWeight*float64(searches-1), fmt.Sprintf("Found by %d synergy searches", searches))
+	}
+
+	scored.Score = roundCents(scored.Score)
+	explanations := []string{}
+	for _, signal := range scored.Signals {
+		explanations = append(explanations, signal.Detail)
+	}
+	scored.Explanation = strings.Join(explanations, "; ")
+	return scored
+}
+
+// rankSynergies merges the candidates found by every synergy search, scores
+// each unique card once, and returns the top results along with the
+// categories trimmed to the ranked cards they contributed.
+func rankSynergies(profile synergyProfile, synergies []SynergyCategory, maxResults int) ([]SynergyCategory, []ScoredSynergyCard) {
+	candidates := map[string]scryfall.Card{}
+	foundIn := map[string][]int{}
+	order := []string{}
+
+	for i, category := range synergies {
+		for _, card := range category.Cards {
+			if card.OracleID == profile.card.OracleID {
+				continue
+			}
+			key := cardKey(card)
+			if _, ok := candidates[key]; !ok {
+				candidates[key] = card
+				order = append(order, key)
+			}
+			foundIn[key] = append(foundIn[key], i)
+		}
+	}
+
+	ranked := make([]ScoredSynergyCard, 0, len(order))
+	for _, key := range order {
+		categories := []string{}
+		for _, i := range foundIn[key] {
+			if !contains(categories, synergies[i].SynergyType) {
+				categories = append(categories, synergies[i].SynergyType)
+			}
+		}
+		ranked = append(ranked, scoreSynergyCandidate(profile, candidates[key], categories, len(foundIn[key])))
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	ranked = limitScoredCards(ranked, maxResults)
+
+	trimmed := []SynergyCategory{}
+	for i, category := range synergies {
+		cards := []scryfall.Card{}
+		for _, scored := range ranked {
+			for _, j := range foundIn[cardKey(scored.Card)] {
+				if i == j {
+					cards = append(cards, scored.Card)
+					break
+				}
+			}
+		}
+		if len(cards) == 0 {
+			continue
+		}
+		category.Cards = cards
+		trimmed = append(trimmed, category)
+	}
+	return trimmed, ranked
+}
+
+// cardKey identifies a card independently of its printing
+func cardKey(card scryfall.Card) string {
+	if card.OracleID != "" {
+		return card.OracleID
+	}
+	return card.ID
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func limitScoredCards(cards []ScoredSynergyCard, max int) []ScoredSynergyCard {
+	if max <= 0 || max > len(cards) {
+		return cards
+	}
+	return cards[:max]
+}
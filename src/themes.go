@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source recorded for themes that come from res/themepatterns.json
+const embeddedThemeSource = "embedded"
+
+// How often the theme directory is checked for changes
+const themeReloadInterval = 5 * time.Second
+
+func themeSourceMap(patterns map[string]ThemePattern, source string) map[string]string {
+	sources := make(map[string]string, len(patterns))
+	for theme := range patterns {
+		sources[theme] = source
+	}
+	return sources
+}
+
+// loadThemeSources returns the source of every loaded theme
+func loadThemeSources() map[string]string {
+	loadThemePatterns()
+	themePatternsMu.RLock()
+	defer themePatternsMu.RUnlock()
+	return themeSourcesCache
+}
+
+// themePackFiles returns the theme pack files in dir in the order they are
+// applied. Packs are applied alphabetically, so "20-voltron.json" overrides
+// themes from "10-aristocrats.json".
+func themePackFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// reloadThemePatterns rebuilds the theme patterns from the embedded file and
+// every theme pack in dir. A theme defined in a pack replaces the embedded
+// theme of the same name. Packs that fail to decode or validate are skipped
+// and reported in the returned error; the remaining packs are still applied.
+func reloadThemePatterns(dir string) error {
+	patterns, err := loadEmbeddedThemePatterns()
+	if err != nil {
+		return err
+	}
+	sources := themeSourceMap(patterns, embeddedThemeSource)
+
+	files, err := themePackFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	errs := []string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		pack, err := decodeThemePatterns(data)
+		if err == nil {
+			err = validateThemePatterns(pack)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s:\n%v", file, err))
+			continue
+		}
+
+		for theme, pattern := range pack {
+			if source, ok := sources[theme]; ok {
+				log.Printf("Theme '%s' from %s overrides the one from %s", theme, file, source)
+			}
+			patterns[theme] = pattern
+			sources[theme] = file
+		}
+		log.Printf("Loaded %d themes from %s", len(pack), file)
+	}
+
+	themePatternsMu.Lock()
+	themePatternsCache = patterns
+	themeSourcesCache = sources
+	themePatternsMu.Unlock()
+	// Drop patterns of themes that were removed or changed
+	compiledThemePatterns.Clear()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("skipped invalid theme packs:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// themeDirSnapshot captures the name, size and modification time of every
+// theme pack so that changes can be detected by polling.
+func themeDirSnapshot(dir string) string {
+	files, err := themePackFiles(dir)
+	if err != nil {
+		return ""
+	}
+	parts := []string{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s|%d|%d", file, info.Size(), info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// watchThemeDir reloads the theme patterns whenever a theme pack in dir is
+// added, removed or modified, until ctx is cancelled.
+func watchThemeDir(ctx context.Context, dir string, interval time.Duration) {
+	last := themeDirSnapshot(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := themeDirSnapshot(dir)
+			if current == last {
+				continue
+			}
+			last = current
+			log.Printf("Theme directory %s changed, reloading theme patterns", dir)
+			if err := reloadThemePatterns(dir); err != nil {
+				log.Printf("Error reloading theme patterns: %v", err)
+			}
+		}
+	}
+}
+
+// setupThemePacks loads theme packs from the configured directory and starts
+// watching it for changes.
+func setupThemePacks(config *Config) {
+	if config.ThemeDir == "" {
+		return
+	}
+
+	log.Printf("Loading theme packs from %s", config.ThemeDir)
+	if err := reloadThemePatterns(config.ThemeDir); err != nil {
+		log.Printf("Error loading theme packs: %v", err)
+	}
+	go watchThemeDir(context.Background(), config.ThemeDir, themeReloadInterval)
+}
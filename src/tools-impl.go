@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/BlueMonday/go-scryfall"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func executeSearch(ctx context.Context, searchQuery, searchTerm, searchType string) (*mcp.CallToolResult, SearchCardResult, error) {
-	client, err := scryfall.NewClient()
+	client, err := newScryfallClient()
 	if err != nil {
 		log.Printf("Error creating Scryfall client: %v", err)
 		return &mcp.CallToolResult{
@@ -108,7 +111,7 @@ func findRelatedCards(ctx context.Context, req *mcp.CallToolRequest, args FindRe
 		maxResults = 10
 	}
 
-	client, err := scryfall.NewClient()
+	client, err := newScryfallClient()
 	if err != nil {
 		log.Printf("Error creating Scryfall client: %v", err)
 		return &mcp.CallToolResult{
@@ -140,43 +143,81 @@ func findRelatedCards(ctx context.Context, req *mcp.CallToolRequest, args FindRe
 
 	relationTypes := args.RelationType
 	if len(relationTypes) == 0 {
-		relationTypes = []string{"reprints", "tokens", "mechanics", "same_set"}
+		relationTypes = allRelationTypes
 	}
 
-	// 1. Find reprints (same oracle_id)
-	if contains(relationTypes, "reprints") {
-		if category := findReprintCards(ctx, client, mainCard, opts, maxResults); category != nil {
-			categories = append(categories, *category)
-		}
+	// Each relation type is one sub-search, in the order of allRelationTypes
+	searches := []struct {
+		relation string
+		find     func(ctx context.Context) *RelatedCardCategory
+	}{
+		{"reprints", func(ctx context.Context) *RelatedCardCategory {
+			return findReprintCards(ctx, client, mainCard, opts, maxResults)
+		}},
+		{"tokens", func(ctx context.Context) *RelatedCardCategory {
+			return findTokenCards(ctx, client, mainCard, maxResults)
+		}},
+		{"mechanics", func(ctx context.Context) *RelatedCardCategory {
+			return findMechanicCards(ctx, client, mainCard, opts)
+		}},
+		{"same_set", func(ctx context.Context) *RelatedCardCategory {
+			return findSameSetCards(ctx, client, mainCard, opts, maxResults)
+		}},
+		{"same_artist", func(ctx context.Context) *RelatedCardCategory {
+			return findArtistCards(ctx, client, mainCard, opts, maxResults)
+		}},
+		{"same_name_variants", func(ctx context.Context) *RelatedCardCategory {
+			return findNameVariantCards(ctx, client, mainCard, maxResults)
+		}},
+		{"meld_and_combo_parts", func(ctx context.Context) *RelatedCardCategory {
+			return findMeldAndComboParts(ctx, client, mainCard, maxResults)
+		}},
+		{"functional_reprints", func(ctx context.Context) *RelatedCardCategory {
+			return findFunctionalReprintCards(ctx, client, mainCard, opts, maxResults)
+		}},
+		{"same_cycle", func(ctx context.Context) *RelatedCardCategory {
+			return findCycleCards(ctx, client, mainCard, opts, maxResults)
+		}},
+		{"referenced_cards", func(ctx context.Context) *RelatedCardCategory {
+			return findReferencedCards(ctx, client, mainCard, maxResults)
+		}},
 	}
 
-	// 2. Find tokens created
-	if contains(relationTypes, "tokens") {
-		if category := findTokenCards(ctx, client, mainCard, maxResults); category != nil {
-			categories = append(categories, *category)
+	selected := searches[:0]
+	for _, search := range searches {
+		if contains(relationTypes, search.relation) {
+			selected = append(selected, search)
 		}
 	}
+	ctx, cancel := withToolTimeout(ctx)
+	defer cancel()
+	ctx = startToolProgress(ctx, req, len(selected))
 
-	// 3. Find similar mechanics (based on keywords)
-	if contains(relationTypes, "mechanics") {
-		if category := findMechanicCards(ctx, client, mainCard, opts); category != nil {
+	// Searches run side by side, but categories keep the order of searches
+	found := make([]*RelatedCardCategory, len(selected))
+	runParallel(ctx, len(selected), maxParallelSearches, func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		reportProgress(ctx, "Searching "+strings.ReplaceAll(selected[i].relation, "_", " "))
+		start := time.Now()
+		found[i] = selected[i].find(ctx)
+		debugf("find_related_cards %s for '%s': found=%t in %s", selected[i].relation, mainCard.Name, found[i] != nil, time.Since(start).Round(time.Millisecond))
+	})
+	for _, category := range found {
+		if category != nil {
 			categories = append(categories, *category)
 		}
 	}
 
-	// Find cards from same set
-	if contains(relationTypes, "same_set") && mainCard.Set != "" {
-		log.Printf("Searching for cards from set %s", mainCard.Set)
-		setQuery := fmt.Sprintf(`set:%s -name:"%s"`, mainCard.Set, mainCard.Name)
-		setCards, err := client.SearchCards(ctx, setQuery, opts)
-		if err == nil && len(setCards.Cards) > 0 {
-			categories = append(categories, RelatedCardCategory{
-				CategoryName: fmt.Sprintf("Same Set (%s)", mainCard.SetName),
-				Cards:        limitCards(setCards.Cards, maxResults),
-				Count:        len(setCards.Cards),
-			})
-			log.Printf("Found %d cards from same set", len(setCards.Cards))
-		}
+	if reason := partialReason(ctx); reason != "" {
+		log.Printf("Related card search for '%s' %s, returning %d categories", mainCard.Name, reason, len(categories))
+		return nil, FindRelatedCardsResult{
+			MainCard:      mainCard,
+			Categories:    categories,
+			Partial:       true,
+			PartialReason: reason,
+		}, nil
 	}
 
 	log.Printf("Successfully found related cards for '%s' in %d categories", mainCard.Name, len(categories))
@@ -201,7 +242,7 @@ func findCardSynergies(ctx context.Context, req *mcp.CallToolRequest, args FindC
 		maxResults = 15
 	}
 
-	client, err := scryfall.NewClient()
+	client, err := newScryfallClient()
 	if err != nil {
 		log.Printf("Error creating Scryfall client: %v", err)
 		return &mcp.CallToolResult{
@@ -242,16 +283,30 @@ func findCardSynergies(ctx context.Context, req *mcp.CallToolRequest, args FindC
 		log.Printf("Using user specified theme: %s", args.Theme)
 	}
 
+	// One progress step per keyword and theme searched, plus colors
+	keywords, themes := synergySearches(mainCard, searchThemes)
+	ctx, cancel := withToolTimeout(ctx)
+	defer cancel()
+	ctx = startToolProgress(ctx, req, len(keywords)+len(themes)+1)
+
 	// Keyword based 
-	synergies = findKeywordSynergies(ctx, client, mainCard, opts, synergies)
+	synergies = findKeywordSynergies(ctx, client, mainCard, opts, keywords, synergies)
 
 	// Themebased 
-	synergies = findThemeSynergies(ctx, client, mainCard, opts, searchThemes, synergies)
+	synergies = findThemeSynergies(ctx, client, mainCard, opts, themes, synergies)
 
 	// Color identity synergies
 	synergies = findColorIdentitySynergies(ctx, client, mainCard, opts, synergies)
 
-	if len(synergies) == 0 {
+	// Score every candidate against the main card
+	profile := newSynergyProfile(mainCard, searchThemes, args.Theme)
+	synergies, rankedCards := rankSynergies(profile, synergies, maxResults)
+	reason := partialReason(ctx)
+	if reason != "" {
+		log.Printf("Synergy search for '%s' %s, ranking %d categories found so far", mainCard.Name, reason, len(synergies))
+	}
+
+	if len(rankedCards) == 0 {
 		log.Printf("No synergies found for '%s'", mainCard.Name)
 		return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No clear synergies found for '%s'. Try specifying a specific theme.", mainCard.Name)}},
@@ -259,13 +314,1171 @@ func findCardSynergies(ctx context.Context, req *mcp.CallToolRequest, args FindC
 				MainCard:        mainCard,
 				ExtractedThemes: extractedThemes,
 				Synergies:       []SynergyCategory{},
+				RankedCards:     []ScoredSynergyCard{},
+				Partial:         reason != "",
+				PartialReason:   reason,
 			}, nil
 	}
 
-	log.Printf("Successfully found synergy categories")
+	log.Printf("Successfully ranked %d synergistic cards across %d categories", len(rankedCards), len(synergies))
 	return nil, FindCardSynergiesResult{
 		MainCard:        mainCard,
 		ExtractedThemes: extractedThemes,
 		Synergies:       synergies,
+		RankedCards:     rankedCards,
+		Partial:         reason != "",
+		PartialReason:   reason,
+	}, nil
+}
+
+func deckCollectionDiff(ctx context.Context, req *mcp.CallToolRequest, args DeckCollectionDiffArgs) (*mcp.CallToolResult, DeckCollectionDiffResult, error) {
+	if args.Decklist == "" {
+		log.Println("Error: Received request with empty decklist.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Decklist cannot be empty."}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	allowAlternates := true
+	if args.AllowAlternatePrintings != nil {
+		allowAlternates = *args.AllowAlternatePrintings
+	}
+
+	deck, err := parseDecklist(args.Decklist)
+	if err != nil {
+		log.Printf("Error parsing decklist: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error parsing decklist: %v", err)}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	collection, err := parseDecklist(args.Collection)
+	if err != nil {
+		log.Printf("Error parsing collection: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error parsing collection: %v", err)}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	deckCards, deckNotFound, err := resolveDeckEntries(ctx, client, deck)
+	if err != nil {
+		log.Printf("Error resolving decklist: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up decklist cards: %v", err)}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	collectionCards, collectionNotFound, err := resolveDeckEntries(ctx, client, collection)
+	if err != nil {
+		log.Printf("Error resolving collection: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up collection cards: %v", err)}},
+		}, DeckCollectionDiffResult{}, nil
+	}
+
+	result := diffDeckAgainstCollection(deck, deckCards, collection, collectionCards, allowAlternates)
+	result.NotFound = append(deckNotFound, collectionNotFound...)
+
+	log.Printf("Deck diff: %d/%d cards owned, %d missing ($%.2f)", result.OwnedCount, result.TotalCards, result.MissingCount, result.MissingCostUSD)
+	return nil, result, nil
+}
+
+func findFunctionalAlternatives(ctx context.Context, req *mcp.CallToolRequest, args FindFunctionalAlternativesArgs) (*mcp.CallToolResult, FindFunctionalAlternativesResult, error) {
+	if args.CardName == "" {
+		log.Println("Error: Received request with empty card name.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Card name cannot be empty."}},
+		}, FindFunctionalAlternativesResult{}, nil
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 15
+	}
+
+	includeSimilar := true
+	if args.IncludeSimilar != nil {
+		includeSimilar = *args.IncludeSimilar
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, FindFunctionalAlternativesResult{}, nil
+	}
+
+	searchQuery := fmt.Sprintf(`name:"%s"`, args.CardName)
+	opts := scryfall.SearchCardsOptions{
+		Unique:              scryfall.UniqueModeCards,
+		IncludeMultilingual: false,
+		IncludeExtras:       false,
+		IncludeVariations:   false,
+	}
+
+	log.Printf("Searching for main card: %s", args.CardName)
+	result, err := client.SearchCards(ctx, searchQuery, opts)
+	if err != nil || len(result.Cards) == 0 {
+		log.Printf("Error finding main card '%s': %v", args.CardName, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Could not find card '%s'", args.CardName)}},
+		}, FindFunctionalAlternativesResult{}, nil
+	}
+
+	mainCard := result.Cards[0]
+	candidates, err := findFunctionalAlternativeCandidates(ctx, client, mainCard, opts)
+	if err != nil {
+		log.Printf("No alternative candidates found for '%s': %v", mainCard.Name, err)
+	}
+
+	alternatives := rankFunctionalAlternatives(mainCard, candidates, includeSimilar)
+	if len(alternatives) > maxResults {
+		alternatives = alternatives[:maxResults]
+	}
+
+	if len(alternatives) == 0 {
+		log.Printf("No functional alternatives found for '%s'", mainCard.Name)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No functional alternatives found for '%s'.", mainCard.Name)}},
+		}, FindFunctionalAlternativesResult{MainCard: mainCard, Alternatives: []FunctionalAlternative{}}, nil
+	}
+
+	log.Printf("Found %d functional alternatives for '%s'", len(alternatives), mainCard.Name)
+	return nil, FindFunctionalAlternativesResult{
+		MainCard:     mainCard,
+		Alternatives: alternatives,
 	}, nil
 }
+
+func listThemes(ctx context.Context, req *mcp.CallToolRequest, args ListThemesArgs) (*mcp.CallToolResult, ListThemesResult, error) {
+	patterns := loadThemePatterns()
+	sources := loadThemeSources()
+
+	themes := []ThemeInfo{}
+	for _, name := range sortedKeys(patterns) {
+		pattern := patterns[name]
+		source := sources[name]
+		if args.Source != "" && source != args.Source {
+			continue
+		}
+		themes = append(themes, ThemeInfo{
+			Name:             name,
+			SynergyType:      pattern.SynergyType,
+			Description:      pattern.SynergyDescription,
+			Source:           source,
+			HasEnablerPayoff: len(pattern.EnablerPatterns) > 0 || len(pattern.PayoffPatterns) > 0,
+		})
+	}
+
+	log.Printf("Listing %d themes", len(themes))
+	return nil, ListThemesResult{Themes: themes}, nil
+}
+
+func parseCardText(ctx context.Context, req *mcp.CallToolRequest, args ParseCardTextArgs) (*mcp.CallToolResult, ParseCardTextResult, error) {
+	if args.CardName == "" && args.Text == "" {
+		log.Println("Error: Received request with neither a card name nor text.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide either a card name or rules text to parse."}},
+		}, ParseCardTextResult{}, nil
+	}
+
+	card := scryfall.Card{OracleText: args.Text}
+	if args.Text == "" {
+		client, err := newScryfallClient()
+		if err != nil {
+			log.Printf("Error creating Scryfall client: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+			}, ParseCardTextResult{}, nil
+		}
+
+		searchQuery := fmt.Sprintf(`name:"%s"`, args.CardName)
+		opts := scryfall.SearchCardsOptions{
+			Unique:              scryfall.UniqueModeCards,
+			IncludeMultilingual: false,
+			IncludeExtras:       false,
+			IncludeVariations:   false,
+		}
+
+		log.Printf("Searching for card to parse: %s", args.CardName)
+		result, err := client.SearchCards(ctx, searchQuery, opts)
+		if err != nil || len(result.Cards) == 0 {
+			log.Printf("Error finding card '%s': %v", args.CardName, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Card '%s' not found", args.CardName)}},
+			}, ParseCardTextResult{}, nil
+		}
+		card = result.Cards[0]
+	}
+
+	abilities := parseCardAbilities(card)
+	parsed := ParseCardTextResult{
+		Name:      card.Name,
+		TypeLine:  parseTypeLine(card.TypeLine),
+		Abilities: abilities,
+		Keywords:  abilityKeywords(abilities),
+		Themes:    extractThemesFromCard(card),
+	}
+	if len(parsed.TypeLine) == 0 {
+		parsed.TypeLine = nil
+	}
+
+	log.Printf("Parsed %d abilities from %s", len(abilities), card.Name)
+	return nil, parsed, nil
+}
+
+func findCombos(ctx context.Context, req *mcp.CallToolRequest, args FindCombosArgs) (*mcp.CallToolResult, FindCombosResult, error) {
+	if args.CardName == "" && args.Decklist == "" {
+		log.Println("Error: Received request with neither a card name nor a decklist.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide a card name, a decklist or both."}},
+		}, FindCombosResult{}, nil
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 20
+	}
+
+	includeHeuristic := true
+	if args.IncludeHeuristic != nil {
+		includeHeuristic = *args.IncludeHeuristic
+	}
+
+	var deck []DeckEntry
+	var deckNames map[string]bool
+	if args.Decklist != "" {
+		var err error
+		deck, err = parseDecklist(args.Decklist)
+		if err != nil {
+			log.Printf("Error parsing decklist: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error parsing decklist: %v", err)}},
+			}, FindCombosResult{}, nil
+		}
+		deckNames = deckNameSet(deck)
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using offline data: %v", err)
+		client = nil
+	}
+
+	dataset := loadComboDataset()
+	result := FindCombosResult{DatasetVersion: dataset.Version, Warnings: []string{}}
+
+	// Combos are matched on the card's full name, so "kiki-jiki" finds those
+	// of Kiki-Jiki, Mirror Breaker
+	var mainCard scryfall.Card
+	hasRulesText := false
+	if args.CardName != "" {
+		mainCard, hasRulesText, err = resolveComboCard(ctx, client, dataset, args.CardName)
+		if err != nil {
+			log.Printf("Error finding main card '%s': %v", args.CardName, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			}, FindCombosResult{}, nil
+		}
+		result.Card = mainCard.Name
+	}
+
+	result.Combos = findDatasetCombos(dataset, result.Card, deckNames)
+	log.Printf("Found %d known combos", len(result.Combos))
+
+	if includeHeuristic {
+		opts := scryfall.SearchCardsOptions{
+			Unique:              scryfall.UniqueModeCards,
+			IncludeMultilingual: false,
+			IncludeExtras:       false,
+			IncludeVariations:   false,
+		}
+
+		deckCards := []scryfall.Card{}
+		if len(deck) > 0 && client == nil {
+			result.Warnings = append(result.Warnings, "Could not look up decklist cards without Scryfall, so loops were not detected")
+		} else if len(deck) > 0 {
+			resolved, notFound, err := resolveDeckEntries(ctx, client, deck)
+			if err != nil {
+				log.Printf("Error resolving decklist: %v", err)
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Could not look up decklist cards, so loops were not detected: %v", err))
+			}
+			result.NotFound = notFound
+			for i := range deck {
+				if card, ok := resolved[i]; ok {
+					deckCards = append(deckCards, card)
+				}
+			}
+		}
+
+		var loops []Combo
+		switch {
+		case args.CardName == "":
+			loops = detectDeckLoops(deckCards)
+		case !hasRulesText:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The rules text of %s could not be looked up, so loops were not detected", result.Card))
+		case len(deck) > 0:
+			loops = detectCardDeckLoops(mainCard, deckCards)
+		case client != nil:
+			loops = detectCardLoops(ctx, client, mainCard, opts)
+		}
+		log.Printf("Detected %d possible loops", len(loops))
+		result.Combos = addHeuristicCombos(result.Combos, loops, deckNames)
+	}
+
+	sortComboMatches(result.Combos)
+	result.Combos = limitComboMatches(result.Combos, maxResults)
+	return nil, result, nil
+}
+
+func suggestCommanders(ctx context.Context, req *mcp.CallToolRequest, args SuggestCommandersArgs) (*mcp.CallToolResult, SuggestCommandersResult, error) {
+	if args.Theme == "" && args.Colors == "" && len(args.SeedCards) == 0 {
+		log.Println("Error: Received request with no theme, colors or seed cards.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide a theme, colors, seed cards or a combination of them."}},
+		}, SuggestCommandersResult{}, nil
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+
+	theme := strings.ToLower(strings.TrimSpace(args.Theme))
+	if theme != "" {
+		if _, ok := loadThemePatterns()[theme]; !ok {
+			log.Printf("Error: Unknown theme '%s'", args.Theme)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: Unknown theme '%s'. Available themes: %s", args.Theme, strings.Join(sortedKeys(loadThemePatterns()), ", "))}},
+			}, SuggestCommandersResult{}, nil
+		}
+	}
+
+	identity, err := parseColorIdentity(args.Colors)
+	if err != nil {
+		log.Printf("Error parsing colors '%s': %v", args.Colors, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, SuggestCommandersResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, SuggestCommandersResult{}, nil
+	}
+
+	result := SuggestCommandersResult{ColorIdentity: identity}
+
+	seeds := []scryfall.Card{}
+	if len(args.SeedCards) > 0 {
+		entries := make([]DeckEntry, len(args.SeedCards))
+		for i, name := range args.SeedCards {
+			entries[i] = DeckEntry{Quantity: 1, Name: name}
+		}
+		resolved, notFound, err := resolveDeckEntries(ctx, client, entries)
+		if err != nil {
+			log.Printf("Error looking up seed cards: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up seed cards: %v", err)}},
+			}, SuggestCommandersResult{}, nil
+		}
+		result.NotFound = notFound
+		for i := range entries {
+			if card, ok := resolved[i]; ok {
+				seeds = append(seeds, card)
+			}
+		}
+	}
+
+	themes := []string{}
+	if theme != "" {
+		themes = append(themes, theme)
+	}
+	for _, seedTheme := range seedThemes(seeds) {
+		if !contains(themes, seedTheme) {
+			themes = append(themes, seedTheme)
+		}
+	}
+	result.Themes = themes
+
+	// Without explicit colors, commanders must be able to play every seed card
+	seedIdentity := ""
+	if identity == "" && len(seeds) > 0 {
+		seedIdentity = seedColorIdentity(seeds)
+	}
+
+	candidates := findCommanderCandidates(ctx, client, commanderSearchQueries(themes, identity, seedIdentity))
+	log.Printf("Scoring %d commander candidates", len(candidates))
+	result.Commanders = rankCommanders(candidates, themes, theme, identity, seeds, maxResults)
+
+	return nil, result, nil
+}
+
+func buildCommanderDeck(ctx context.Context, req *mcp.CallToolRequest, args BuildCommanderDeckArgs) (*mcp.CallToolResult, BuildCommanderDeckResult, error) {
+	if args.Commander == "" {
+		log.Println("Error: Received request with empty commander name.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Commander name cannot be empty."}},
+		}, BuildCommanderDeckResult{}, nil
+	}
+
+	seed := time.Now().UnixNano()
+	if args.Seed != nil {
+		seed = *args.Seed
+	}
+
+	var collection []DeckEntry
+	if args.Collection != "" {
+		var err error
+		collection, err = parseDecklist(args.Collection)
+		if err != nil {
+			log.Printf("Error parsing collection: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error parsing collection: %v", err)}},
+			}, BuildCommanderDeckResult{}, nil
+		}
+	}
+	if args.CollectionOnly && len(collection) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: collection_only requires a collection."}},
+		}, BuildCommanderDeckResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, BuildCommanderDeckResult{}, nil
+	}
+
+	searchQuery := fmt.Sprintf(`name:"%s" is:commander`, args.Commander)
+	opts := scryfall.SearchCardsOptions{
+		Unique:              scryfall.UniqueModeCards,
+		IncludeMultilingual: false,
+		IncludeExtras:       false,
+		IncludeVariations:   false,
+	}
+
+	log.Printf("Searching for commander: %s", args.Commander)
+	result, err := client.SearchCards(ctx, searchQuery, opts)
+	if err != nil || len(result.Cards) == 0 {
+		log.Printf("Error finding commander '%s': %v", args.Commander, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Could not find a commander named '%s'", args.Commander)}},
+		}, BuildCommanderDeckResult{}, nil
+	}
+	commander := result.Cards[0]
+
+	builder := newDeckBuilder(commander, seed, collection, args.CollectionOnly, args.MaxCardPriceUSD, args.BudgetUSD)
+	builder.build(ctx, client, args.Theme)
+	deck := builder.result(seed)
+	deck.DeckURI = storeDeck(commander.Name+" Commander deck", fmt.Sprintf("Commander deck built around %s with seed %d", commander.Name, seed), deck.Decklist)
+
+	log.Printf("Built a %d-card deck for %s with seed %d", deck.CardCount, commander.Name, seed)
+	return nil, deck, nil
+}
+
+func compareCards(ctx context.Context, req *mcp.CallToolRequest, args CompareCardsArgs) (*mcp.CallToolResult, CompareCardsResult, error) {
+	entries := []DeckEntry{}
+	for _, name := range args.CardNames {
+		if name = strings.TrimSpace(name); name != "" {
+			entries = append(entries, DeckEntry{Quantity: 1, Name: name})
+		}
+	}
+	if len(entries) < minCompareCards || len(entries) > maxCompareCards {
+		log.Printf("Error: Received request to compare %d cards.", len(entries))
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: Provide between %d and %d card names to compare.", minCompareCards, maxCompareCards)}},
+		}, CompareCardsResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, CompareCardsResult{}, nil
+	}
+
+	resolved, notFound, err := resolveDeckEntries(ctx, client, entries)
+	if err != nil {
+		log.Printf("Error resolving cards to compare: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up cards: %v", err)}},
+		}, CompareCardsResult{}, nil
+	}
+	if len(resolved) < minCompareCards {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: Fewer than %d cards were found. Not found: %s", minCompareCards, strings.Join(notFound, ", "))}},
+		}, CompareCardsResult{}, nil
+	}
+
+	result := CompareCardsResult{NotFound: notFound}
+	for i := range entries {
+		card, ok := resolved[i]
+		if !ok {
+			continue
+		}
+		printings, err := countPrintings(ctx, client, card)
+		if err != nil {
+			log.Printf("Error counting printings of %s: %v", card.Name, err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Could not count printings of %s: %v", card.Name, err))
+		}
+		result.Cards = append(result.Cards, newCardComparison(card, printings))
+	}
+
+	result.DifferingFields = differingAttributes(result.Cards)
+	result.DifferingFormats = legalityDifferences(result.Cards)
+	result.OracleTextDiffs = oracleTextDiffs(result.Cards)
+
+	log.Printf("Compared %d cards, %d attributes differ", len(result.Cards), len(result.DifferingFields))
+	return nil, result, nil
+}
+
+func listSets(ctx context.Context, req *mcp.CallToolRequest, args ListSetsArgs) (*mcp.CallToolResult, ListSetsResult, error) {
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSetListResults
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using bundled sets: %v", err)
+		client = nil
+	}
+
+	sets, source, err := fetchSets(ctx, client)
+	if err != nil {
+		log.Printf("Error listing sets: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error listing sets: %v", err)}},
+		}, ListSetsResult{}, nil
+	}
+
+	filtered, err := filterSets(sets, args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, ListSetsResult{}, nil
+	}
+
+	result := ListSetsResult{Sets: []SetInfo{}, TotalCount: len(filtered), Source: source}
+	result.SnapshotDate = setSnapshotDate(source)
+	for _, set := range filtered {
+		if len(result.Sets) == maxResults {
+			break
+		}
+		result.Sets = append(result.Sets, newSetInfo(set))
+	}
+
+	log.Printf("Listed %d of %d matching sets from %s", len(result.Sets), result.TotalCount, source)
+	return nil, result, nil
+}
+
+func getSet(ctx context.Context, req *mcp.CallToolRequest, args GetSetArgs) (*mcp.CallToolResult, GetSetResult, error) {
+	if strings.TrimSpace(args.Set) == "" {
+		log.Println("Error: Received request with empty set.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Set code or name cannot be empty."}},
+		}, GetSetResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using bundled sets: %v", err)
+		client = nil
+	}
+
+	set, source, err := findSet(ctx, client, args.Set)
+	if err != nil {
+		log.Printf("Error finding set '%s': %v", args.Set, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, GetSetResult{}, nil
+	}
+
+	result := GetSetResult{Set: newSetInfo(set), Source: source}
+	result.SnapshotDate = setSnapshotDate(source)
+	return nil, result, nil
+}
+
+func browseSet(ctx context.Context, req *mcp.CallToolRequest, args BrowseSetArgs) (*mcp.CallToolResult, BrowseSetResult, error) {
+	if strings.TrimSpace(args.Set) == "" {
+		log.Println("Error: Received request with empty set.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Set code or name cannot be empty."}},
+		}, BrowseSetResult{}, nil
+	}
+
+	page := max(args.Page, 1)
+	pageSize := args.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultSetPageSize
+	}
+	pageSize = min(pageSize, maxSetPageSize)
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using offline data: %v", err)
+		client = nil
+	}
+
+	set, _, err := findSet(ctx, client, args.Set)
+	if err != nil {
+		log.Printf("Error finding set '%s': %v", args.Set, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, BrowseSetResult{}, nil
+	}
+
+	query, err := setCardsQuery(set.Code, args.Rarity, args.Colors)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, BrowseSetResult{}, nil
+	}
+
+	result := BrowseSetResult{
+		Set:      newSetInfo(set),
+		Page:     page,
+		PageSize: pageSize,
+		Source:   SetSourceScryfall,
+		Cards:    []SetCard{},
+	}
+
+	var cards []scryfall.Card
+	if client != nil {
+		cards, result.TotalCards, result.HasMore, err = browseSetCards(ctx, client, query, page, pageSize)
+		if err != nil {
+			log.Printf("Error browsing set %s on Scryfall, using offline card data: %v", set.Code, err)
+		}
+	}
+	if client == nil || err != nil {
+		result.Source = SetSourceOffline
+		if len(offlineSetCards(set.Code)) == 0 {
+			reason := "the offline card data has no cards from this set"
+			if _, ok := loadedOfflineCards(); !ok {
+				reason = "no offline card data is loaded (MCP_CARD_DATA_FILE)"
+			}
+			result.Note = fmt.Sprintf("The card list of %s is unavailable: Scryfall could not be reached and %s.", set.Name, reason)
+			return nil, result, nil
+		}
+		cards, result.TotalCards, result.HasMore, err = browseOfflineSetCards(query, page, pageSize)
+		if err != nil {
+			log.Printf("Error browsing set %s offline: %v", set.Code, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error browsing cards in %s without Scryfall: %v", set.Name, err)}},
+			}, BrowseSetResult{}, nil
+		}
+		result.Note = "Scryfall could not be reached; cards come from the offline card data and have no prices."
+	}
+	for _, card := range cards {
+		result.Cards = append(result.Cards, newSetCard(card))
+	}
+
+	log.Printf("Browsed page %d of %s: %d of %d cards from %s", page, set.Code, len(result.Cards), result.TotalCards, result.Source)
+	return nil, result, nil
+}
+
+func formatInfo(ctx context.Context, req *mcp.CallToolRequest, args FormatInfoArgs) (*mcp.CallToolResult, FormatInfoResult, error) {
+	if args.Format == "" && args.CardName == "" {
+		log.Println("Error: Received request with neither a format nor a card name.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide a format, a card name, or both."}},
+		}, FormatInfoResult{}, nil
+	}
+
+	format := ""
+	if args.Format != "" {
+		var err error
+		if format, err = parseLegalityFormat(args.Format); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			}, FormatInfoResult{}, nil
+		}
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+		}, FormatInfoResult{}, nil
+	}
+
+	result := FormatInfoResult{}
+	snapshots := []legalitySnapshot{}
+	if legalitySnapshotDir == "" {
+		result.Notes = append(result.Notes, "Legality history is not recorded because MCP_LEGALITY_SNAPSHOT_DIR is off")
+	} else if snapshots, err = loadLegalitySnapshots(legalitySnapshotDir); err != nil {
+		log.Printf("Error loading legality snapshots: %v", err)
+		result.Notes = append(result.Notes, fmt.Sprintf("Could not load legality snapshots: %v", err))
+	} else if len(snapshots) < 2 {
+		result.Notes = append(result.Notes, "Changes are reported once at least two daily snapshots have been taken")
+	}
+	result.SnapshotCount = len(snapshots)
+
+	if args.CardName == "" {
+		list, err := fetchFormatBanList(ctx, client, format)
+		if err != nil {
+			log.Printf("Error fetching ban list for %s: %v", format, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error fetching the %s ban list: %v", legalityFormatNames[format], err)}},
+			}, FormatInfoResult{}, nil
+		}
+		result.BanList = &list
+		result.Changes = banListChanges(snapshots, format)
+
+		log.Printf("Ban list for %s: %d banned, %d restricted", format, len(list.Banned), len(list.Restricted))
+		return nil, result, nil
+	}
+
+	searchQuery := fmt.Sprintf(`name:"%s"`, args.CardName)
+	opts := scryfall.SearchCardsOptions{
+		Unique:              scryfall.UniqueModeCards,
+		IncludeMultilingual: false,
+		IncludeExtras:       false,
+		IncludeVariations:   false,
+	}
+
+	log.Printf("Searching for card to check legality: %s", args.CardName)
+	cards, err := client.SearchCards(ctx, searchQuery, opts)
+	if err != nil || len(cards.Cards) == 0 {
+		log.Printf("Error finding card '%s': %v", args.CardName, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Card '%s' not found", args.CardName)}},
+		}, FormatInfoResult{}, nil
+	}
+	card := cards.Cards[0]
+
+	result.Card = card.Name
+	for _, legality := range cardFormatLegalities(card) {
+		if format == "" || legality.Format == format {
+			result.Legalities = append(result.Legalities, legality)
+		}
+	}
+	result.Changes = legalityChanges(snapshots, card.Name, format)
+
+	log.Printf("Checked legality of %s in %d formats", card.Name, len(result.Legalities))
+	return nil, result, nil
+}
+
+func randomCard(ctx context.Context, req *mcp.CallToolRequest, args RandomCardArgs) (*mcp.CallToolResult, RandomCardResult, error) {
+	seed := time.Now().UnixNano()
+	if args.Seed != nil {
+		seed = *args.Seed
+	}
+	offlineQuery := strings.TrimSpace(args.Query)
+	query := offlineQuery
+	if query == "" {
+		query = "game:paper"
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using offline card data: %v", err)
+		client = nil
+	}
+
+	log.Printf("Picking random card (Query: %s, Seed: %d)", query, seed)
+	card, total, source, err := pickRandomCardOrOffline(ctx, client, query, offlineQuery, seed)
+	if err != nil {
+		log.Printf("Error picking random card: %v", err)
+		if scryfallErr, ok := err.(*scryfall.Error); ok {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Scryfall API error searching for '%s': %s (Status: %d)", query, scryfallErr.Details, scryfallErr.Status)}},
+			}, RandomCardResult{}, nil
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error picking a random card: %v", err)}},
+		}, RandomCardResult{}, nil
+	}
+
+	log.Printf("Picked %s from %d cards", card.Name, total)
+	result := RandomCardResult{Card: card, Query: query, TotalMatches: total, Seed: seed, Source: source}
+	if source == SetSourceOffline {
+		result.Note = "Scryfall could not be reached, so the card was picked from the offline card data; the same seed picks a different card from Scryfall"
+	}
+	return nil, result, nil
+}
+
+func generateBooster(ctx context.Context, req *mcp.CallToolRequest, args GenerateBoosterArgs) (*mcp.CallToolResult, GenerateBoosterResult, error) {
+	if strings.TrimSpace(args.Set) == "" {
+		log.Println("Error: Received request with empty set.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Set code or name cannot be empty."}},
+		}, GenerateBoosterResult{}, nil
+	}
+
+	boosterType := strings.ToLower(strings.TrimSpace(args.Type))
+	if boosterType == "" {
+		boosterType = BoosterPlay
+	}
+	count := args.Count
+	if count <= 0 {
+		count = 1
+	}
+	if count > maxBoosterCount {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: At most %d boosters can be opened at once.", maxBoosterCount)}},
+		}, GenerateBoosterResult{}, nil
+	}
+	seed := time.Now().UnixNano()
+	if args.Seed != nil {
+		seed = *args.Seed
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using offline card data: %v", err)
+		client = nil
+	}
+
+	code, name, definition, err := findBoosterSet(ctx, client, args.Set, boosterType)
+	if err != nil {
+		log.Printf("Error finding booster definition for '%s': %v", args.Set, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, GenerateBoosterResult{}, nil
+	}
+
+	result := GenerateBoosterResult{
+		Set:         code,
+		SetName:     name,
+		Type:        boosterType,
+		Definition:  definition.Set,
+		Description: definition.Description,
+		Seed:        seed,
+	}
+
+	generator := newBoosterGenerator(ctx, client, result.Set, seed)
+	for i := range count {
+		result.Boosters = append(result.Boosters, generator.openBooster(definition, i+1))
+	}
+	if err := generator.checkOpened(); err != nil {
+		log.Printf("Error opening boosters of %s: %v", result.Set, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		}, GenerateBoosterResult{}, nil
+	}
+	result.Notes = generator.notes
+
+	log.Printf("Opened %d %s boosters of %s (Seed: %d)", count, boosterType, result.Set, seed)
+	return nil, result, nil
+}
+
+func sealedPool(ctx context.Context, req *mcp.CallToolRequest, args SealedPoolArgs) (*mcp.CallToolResult, SealedPoolResult, error) {
+	if strings.TrimSpace(args.Set) == "" && strings.TrimSpace(args.Pool) == "" {
+		log.Println("Error: Received request with neither a set nor a pool.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide a set to open boosters of, or a pool to summarize."}},
+		}, SealedPoolResult{}, nil
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		client = nil
+	}
+
+	var result SealedPoolResult
+	var cards []scryfall.Card
+	if strings.TrimSpace(args.Pool) != "" {
+		if client == nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+			}, SealedPoolResult{}, nil
+		}
+		cards, result.NotFound, err = resolvePoolCards(ctx, client, args.Pool)
+		if err != nil {
+			log.Printf("Error resolving pool: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up pool cards: %v", err)}},
+			}, SealedPoolResult{}, nil
+		}
+	} else {
+		boosterType := strings.ToLower(strings.TrimSpace(args.Type))
+		if boosterType == "" {
+			boosterType = BoosterPlay
+		}
+		seed := time.Now().UnixNano()
+		if args.Seed != nil {
+			seed = *args.Seed
+		}
+		result, cards, err = openSealedPool(ctx, client, args.Set, boosterType, seed)
+		if err != nil {
+			log.Printf("Error opening sealed pool of '%s': %v", args.Set, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			}, SealedPoolResult{}, nil
+		}
+	}
+
+	result.Pool, result.ColorCounts, result.RarityCounts = sealedPoolCards(cards)
+	result.PoolSize = len(cards)
+	result.Decklist = poolDecklist(result.Pool)
+
+	log.Printf("Sealed pool of %d cards (%d distinct)", result.PoolSize, len(result.Pool))
+	return nil, result, nil
+}
+
+func buildLimitedDeck(ctx context.Context, req *mcp.CallToolRequest, args BuildLimitedDeckArgs) (*mcp.CallToolResult, BuildLimitedDeckResult, error) {
+	if strings.TrimSpace(args.Pool) == "" && strings.TrimSpace(args.Set) == "" {
+		log.Println("Error: Received request with neither a pool nor a set.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide a pool, or a set and seed to open one."}},
+		}, BuildLimitedDeckResult{}, nil
+	}
+
+	builds := args.Builds
+	if builds <= 0 {
+		builds = defaultLimitedBuilds
+	}
+	builds = min(max(builds, minLimitedBuilds), defaultLimitedBuilds)
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client: %v", err)
+		client = nil
+	}
+
+	result := BuildLimitedDeckResult{}
+	var cards []scryfall.Card
+	if strings.TrimSpace(args.Pool) != "" {
+		if client == nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error initializing card search service: %v", err)}},
+			}, BuildLimitedDeckResult{}, nil
+		}
+		cards, result.NotFound, err = resolvePoolCards(ctx, client, args.Pool)
+		if err != nil {
+			log.Printf("Error resolving pool: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up pool cards: %v", err)}},
+			}, BuildLimitedDeckResult{}, nil
+		}
+	} else {
+		boosterType := strings.ToLower(strings.TrimSpace(args.Type))
+		if boosterType == "" {
+			boosterType = BoosterPlay
+		}
+		seed := time.Now().UnixNano()
+		if args.Seed != nil {
+			seed = *args.Seed
+		}
+		var pool SealedPoolResult
+		pool, cards, err = openSealedPool(ctx, client, args.Set, boosterType, seed)
+		if err != nil {
+			log.Printf("Error opening sealed pool of '%s': %v", args.Set, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			}, BuildLimitedDeckResult{}, nil
+		}
+		result.Seed = pool.Seed
+		result.Notes = pool.Notes
+	}
+
+	result.PoolSize = len(cards)
+	if len(cards) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: The pool has no cards that could be found."}},
+		}, BuildLimitedDeckResult{}, nil
+	}
+	result.Builds, result.ColorDepth = rankLimitedBuilds(cards, builds)
+	for i, build := range result.Builds {
+		result.Builds[i].DeckURI = storeDeck(build.Colors+" limited deck", fmt.Sprintf("40-card %s build of a %d-card sealed pool", build.Colors, result.PoolSize), build.Decklist)
+	}
+	result.Notes = append(result.Notes, "Cards are rated by rarity, removal, evasion and card advantage rather than by pick ratings")
+
+	log.Printf("Built %d limited decks from a pool of %d cards", len(result.Builds), result.PoolSize)
+	return nil, result, nil
+}
+
+func loadSetRatings(ctx context.Context, req *mcp.CallToolRequest, args LoadSetRatingsArgs) (*mcp.CallToolResult, LoadSetRatingsResult, error) {
+	if strings.TrimSpace(args.Set) == "" || strings.TrimSpace(args.CSV) == "" {
+		log.Println("Error: Received ratings import without a set or CSV.")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: Provide the set code and the ratings CSV."}},
+		}, LoadSetRatingsResult{}, nil
+	}
+
+	ratings, err := importSetRatings(args.Set, args.CSV, strings.TrimSpace(args.Source))
+	if err != nil {
+		log.Printf("Error importing ratings for '%s': %v", args.Set, err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error importing ratings: %v", err)}},
+		}, LoadSetRatingsResult{}, nil
+	}
+
+	rated := []CardRating{}
+	for _, card := range ratings.Cards {
+		if card.WinRate != nil {
+			rated = append(rated, card)
+		}
+	}
+	ratedCount := len(rated)
+	sort.SliceStable(rated, func(i, j int) bool { return *rated[i].WinRate > *rated[j].WinRate })
+	if len(rated) > topRatedCards {
+		rated = rated[:topRatedCards]
+	}
+	mean, _ := ratingStats(&ratings)
+
+	return nil, LoadSetRatingsResult{
+		Set:         ratings.Set,
+		Metric:      ratings.Metric,
+		CardCount:   len(ratings.Cards),
+		RatedCount:  ratedCount,
+		MeanWinRate: roundCents(mean),
+		ImportedAt:  ratings.ImportedAt,
+		Path:        ratingsPath(ratings.Set),
+		TopCards:    rated,
+	}, nil
+}
+
+func draftPick(ctx context.Context, req *mcp.CallToolRequest, args DraftPickArgs) (*mcp.CallToolResult, DraftPickResult, error) {
+	if len(args.Pack) == 0 || len(args.Pack) > maxDraftPackSize {
+		log.Printf("Error: Received draft pick request with a pack of %d cards.", len(args.Pack))
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: Provide between 1 and %d pack cards.", maxDraftPackSize)}},
+		}, DraftPickResult{}, nil
+	}
+
+	result := DraftPickResult{Set: strings.ToLower(strings.TrimSpace(args.Set))}
+	var ratings *setRatings
+	if result.Set != "" {
+		if stored, ok := storedSetRatings(result.Set); ok {
+			ratings = stored
+			result.RatingsMetric = stored.Metric
+		} else {
+			result.Notes = append(result.Notes, fmt.Sprintf("No ratings imported for %s; cards are rated by rarity, removal and evasion. Import them with load_set_ratings.", result.Set))
+		}
+	} else {
+		result.Notes = append(result.Notes, "No set given; cards are rated by rarity, removal and evasion")
+	}
+
+	client, err := newScryfallClient()
+	if err != nil {
+		log.Printf("Error creating Scryfall client, using offline data: %v", err)
+		client = nil
+	}
+
+	packCards, notFound, ratingsOnly, offline, err := resolveDraftCards(ctx, client, args.Pack, ratings)
+	if err != nil {
+		log.Printf("Error looking up pack cards: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up pack cards: %v", err)}},
+		}, DraftPickResult{}, nil
+	}
+	pickCards, pickNotFound, pickRatingsOnly, pickOffline, err := resolveDraftCards(ctx, client, args.Picks, ratings)
+	if err != nil {
+		log.Printf("Error looking up picks: %v", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error looking up picks: %v", err)}},
+		}, DraftPickResult{}, nil
+	}
+	result.NotFound = append(notFound, pickNotFound...)
+	if offline || pickOffline {
+		note := "Scryfall could not be reached; cards were looked up in the offline card data"
+		if ratings != nil {
+			note += " and the set's ratings"
+		}
+		result.Notes = append(result.Notes, note)
+	}
+	if ratingsOnly = append(ratingsOnly, pickRatingsOnly...); len(ratingsOnly) > 0 {
+		result.Notes = append(result.Notes, fmt.Sprintf("Only found in the set's ratings, so rated without rules text or mana value: %s", strings.Join(ratingsOnly, ", ")))
+	}
+	if len(packCards) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: None of the pack cards could be found."}},
+		}, DraftPickResult{}, nil
+	}
+
+	picks := rateDraftCards(pickCards, ratings)
+	result.PickCount = len(picks)
+	result.ColorWeights = draftColorWeights(picks)
+	for color, weight := range result.ColorWeights {
+		result.ColorWeights[color] = roundCents(weight)
+	}
+	result.Rankings, result.MainColors, result.Commitment = rankDraftPack(rateDraftCards(packCards, ratings), picks)
+	result.Commitment = roundCents(result.Commitment)
+	result.Recommendation = result.Rankings[0].Name
+
+	log.Printf("Ranked a pack of %d cards after %d picks; recommending %s", len(result.Rankings), result.PickCount, result.Recommendation)
+	return nil, result, nil
+}
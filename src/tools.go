@@ -9,7 +9,11 @@ import (
 
 var outputSchema *jsonschema.Schema
 var relatedCardsSchema *jsonschema.Schema
+var relatedCardsInputSchema *jsonschema.Schema
 var synergiesSchema *jsonschema.Schema
+var alternativesSchema *jsonschema.Schema
+var commandersSchema *jsonschema.Schema
+var randomCardSchema *jsonschema.Schema
 
 func registerSearchByNameTool(server *mcp.Server) {
 	searchTool := &mcp.Tool{
@@ -50,7 +54,8 @@ func registerSearchByColorTool(server *mcp.Server) {
 func registerFindRelatedCardsTool(server *mcp.Server) {
 	relatedCardsTool := &mcp.Tool{
 		Name:         "find_related_cards",
-		Description:  "Find cards related to a given card, including reprints, tokens created, cards with similar mechanics, or from the same set.",
+		Description:  "Find cards related to a given card, including reprints, tokens created, cards with similar mechanics, cards from the same set or by the same artist, art and frame variants, meld and combo parts, functional reprints, cycle members, and cards named in its rules text.",
+		InputSchema:  relatedCardsInputSchema,
 		OutputSchema: relatedCardsSchema,
 	}
 
@@ -71,10 +76,229 @@ func registerFindCardSynergiesTool(server *mcp.Server) {
 	log.Println("Tool 'find_card_synergies' registered.")
 }
 
+func registerDeckCollectionDiffTool(server *mcp.Server) {
+	diffTool := &mcp.Tool{
+		Name:        "deck_collection_diff",
+		Description: "Compare a decklist against the user's collection and report which cards are owned, which are missing, and what the missing cards cost.",
+	}
+
+	mcp.AddTool(server, diffTool, deckCollectionDiff)
+
+	log.Println("Tool 'deck_collection_diff' registered.")
+}
+
+func registerFindFunctionalAlternativesTool(server *mcp.Server) {
+	alternativesTool := &mcp.Tool{
+		Name:         "find_functional_alternatives",
+		Description:  "Find cards that do the same thing as a given card, classified as functional reprints, strictly better, strictly worse or similar. Useful for budget and singleton substitutions.",
+		OutputSchema: alternativesSchema,
+	}
+
+	mcp.AddTool(server, alternativesTool, findFunctionalAlternatives)
+
+	log.Println("Tool 'find_functional_alternatives' registered.")
+}
+
+func registerListThemesTool(server *mcp.Server) {
+	themesTool := &mcp.Tool{
+		Name:        "list_themes",
+		Description: "List every deckbuilding theme known to the server, including themes loaded from theme packs, with their descriptions and where they were loaded from.",
+	}
+
+	mcp.AddTool(server, themesTool, listThemes)
+
+	log.Println("Tool 'list_themes' registered.")
+}
+
+func registerParseCardTextTool(server *mcp.Server) {
+	parseTool := &mcp.Tool{
+		Name:        "parse_card_text",
+		Description: "Parse a card's rules text into structured abilities: keyword abilities with their parameters, activated abilities with cost and effect, triggered abilities with trigger condition and effect, and static or spell abilities. Also returns the card's type line, keywords and detected themes.",
+	}
+
+	mcp.AddTool(server, parseTool, parseCardText)
+
+	log.Println("Tool 'parse_card_text' registered.")
+}
+
+func registerFindCombosTool(server *mcp.Server) {
+	combosTool := &mcp.Tool{
+		Name:        "find_combos",
+		Description: "Find known two- and three-card combos (infinite mana, infinite damage, loops and wins) for a card or a decklist, plus untap and copy loops detected from the cards' abilities. Each combo lists its pieces, prerequisites and results, and whether every piece is in the supplied deck.",
+	}
+
+	mcp.AddTool(server, combosTool, findCombos)
+
+	log.Println("Tool 'find_combos' registered.")
+}
+
+func registerSuggestCommandersTool(server *mcp.Server) {
+	commandersTool := &mcp.Tool{
+		Name:         "suggest_commanders",
+		Description:  "Suggest legal commanders for a theme (see list_themes), a set of colors such as 'Selesnya' or 'GW', and/or a list of seed cards, ranked by how well their color identity and rules text fit.",
+		OutputSchema: commandersSchema,
+	}
+
+	mcp.AddTool(server, commandersTool, suggestCommanders)
+
+	log.Println("Tool 'suggest_commanders' registered.")
+}
+
+func registerBuildCommanderDeckTool(server *mcp.Server) {
+	deckTool := &mcp.Tool{
+		Name:        "build_commander_deck",
+		Description: "Build a 99-card draft list for a commander, organized by role quotas (ramp, card draw, removal, board wipes, synergy pieces and lands), within the commander's color identity and optional budget and collection constraints. The same seed always gives the same deck, and every slot explains why it was included.",
+	}
+
+	mcp.AddTool(server, deckTool, buildCommanderDeck)
+
+	log.Println("Tool 'build_commander_deck' registered.")
+}
+
+func registerCompareCardsTool(server *mcp.Server) {
+	compareTool := &mcp.Tool{
+		Name:        "compare_cards",
+		Description: "Compare 2 to 6 Magic: The Gathering cards side by side: mana cost and value, type, power/toughness, keywords, themes, format legalities, price, rarity and number of printings. Lists the attributes that differ and diffs each card's rules text against the first card's.",
+	}
+
+	mcp.AddTool(server, compareTool, compareCards)
+
+	log.Println("Tool 'compare_cards' registered.")
+}
+
+func registerListSetsTool(server *mcp.Server) {
+	listSetsTool := &mcp.Tool{
+		Name:        "list_sets",
+		Description: "List Magic: The Gathering sets, newest first, filtered by set type (expansion, core, masters, commander, ...), release date range, block and whether they are digital-only. Uses a bundled snapshot when Scryfall can't be reached.",
+	}
+
+	mcp.AddTool(server, listSetsTool, listSets)
+
+	log.Println("Tool 'list_sets' registered.")
+}
+
+func registerGetSetTool(server *mcp.Server) {
+	getSetTool := &mcp.Tool{
+		Name:        "get_set",
+		Description: "Get a Magic: The Gathering set by code or name: type, release date, block, card count and icon URI. Uses a bundled snapshot when Scryfall can't be reached.",
+	}
+
+	mcp.AddTool(server, getSetTool, getSet)
+
+	log.Println("Tool 'get_set' registered.")
+}
+
+func registerBrowseSetTool(server *mcp.Server) {
+	browseSetTool := &mcp.Tool{
+		Name:        "browse_set",
+		Description: "Browse the cards of a Magic: The Gathering set in collector number order, one page at a time, optionally filtered by rarity and color.",
+	}
+
+	mcp.AddTool(server, browseSetTool, browseSet)
+
+	log.Println("Tool 'browse_set' registered.")
+}
+
+func registerFormatInfoTool(server *mcp.Server) {
+	formatTool := &mcp.Tool{
+		Name:        "format_info",
+		Description: "Look up format legality. Given a format, lists its banned and restricted cards. Given a card, explains its legality in every format (or only the given one) and reports when its legality changed, including rotation, from daily local snapshots. Given a format, also reports when cards moved on or off its banned or restricted list.",
+	}
+
+	mcp.AddTool(server, formatTool, formatInfo)
+
+	log.Println("Tool 'format_info' registered.")
+}
+
+func registerRandomCardTool(server *mcp.Server) {
+	randomTool := &mcp.Tool{
+		Name:         "random_card",
+		Description:  "Pick a random Magic: The Gathering card, optionally matching a Scryfall search such as 't:dragon r:mythic'. Passing the same seed gives the same card.",
+		OutputSchema: randomCardSchema,
+	}
+
+	mcp.AddTool(server, randomTool, randomCard)
+
+	log.Println("Tool 'random_card' registered.")
+}
+
+func registerGenerateBoosterTool(server *mcp.Server) {
+	boosterTool := &mcp.Tool{
+		Name:        "generate_booster",
+		Description: "Open plausible play, draft or set boosters of a Magic: The Gathering set, following per-set rarity distributions and slot rules such as the foil, land, wildcard and special guest slots. Passing the same seed gives the same boosters.",
+	}
+
+	mcp.AddTool(server, boosterTool, generateBooster)
+
+	log.Println("Tool 'generate_booster' registered.")
+}
+
+func registerSealedPoolTool(server *mcp.Server) {
+	sealedTool := &mcp.Tool{
+		Name:        "sealed_pool",
+		Description: "Open six boosters of a Magic: The Gathering set as a sealed pool, or summarize a pasted pool, with color and rarity counts and a list that build_limited_deck accepts. Passing the same seed gives the same pool.",
+	}
+
+	mcp.AddTool(server, sealedTool, sealedPool)
+
+	log.Println("Tool 'sealed_pool' registered.")
+}
+
+func registerBuildLimitedDeckTool(server *mcp.Server) {
+	limitedTool := &mcp.Tool{
+		Name:        "build_limited_deck",
+		Description: "Propose two or three 40-card limited decks from a sealed pool, comparing color depth, mana curve, removal count and creature count, with reasoning for each build.",
+	}
+
+	mcp.AddTool(server, limitedTool, buildLimitedDeck)
+
+	log.Println("Tool 'build_limited_deck' registered.")
+}
+
+func registerLoadSetRatingsTool(server *mcp.Server) {
+	ratingsTool := &mcp.Tool{
+		Name:        "load_set_ratings",
+		Description: "Import a set's card ratings from a 17Lands-style CSV export (Name, Color, Rarity and a win rate column such as GIH WR) and store them locally for draft_pick.",
+	}
+
+	mcp.AddTool(server, ratingsTool, loadSetRatings)
+
+	log.Println("Tool 'load_set_ratings' registered.")
+}
+
+func registerDraftPickTool(server *mcp.Server) {
+	draftTool := &mcp.Tool{
+		Name:        "draft_pick",
+		Description: "Rank the cards of a draft pack given the picks so far, combining the set's imported win-rate ratings (or, without them, rarity, removal and evasion) with how well each card fits the picks' colors and curve.",
+	}
+
+	mcp.AddTool(server, draftTool, draftPick)
+
+	log.Println("Tool 'draft_pick' registered.")
+}
+
 func registerTools(server *mcp.Server) {
 	registerSearchByTextTool(server)
 	registerSearchByNameTool(server)
 	registerSearchByColorTool(server)
 	registerFindRelatedCardsTool(server)
 	registerFindCardSynergiesTool(server)
+	registerDeckCollectionDiffTool(server)
+	registerFindFunctionalAlternativesTool(server)
+	registerListThemesTool(server)
+	registerParseCardTextTool(server)
+	registerFindCombosTool(server)
+	registerSuggestCommandersTool(server)
+	registerBuildCommanderDeckTool(server)
+	registerCompareCardsTool(server)
+	registerListSetsTool(server)
+	registerGetSetTool(server)
+	registerBrowseSetTool(server)
+	registerFormatInfoTool(server)
+	registerRandomCardTool(server)
+	registerGenerateBoosterTool(server)
+	registerSealedPoolTool(server)
+	registerBuildLimitedDeckTool(server)
+	registerLoadSetRatingsTool(server)
+	registerDraftPickTool(server)
 }
\ No newline at end of file
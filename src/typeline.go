@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+// CardTypeLine is one face of a parsed type line, e.g.
+// "Legendary Creature — Elf Druid" parses to supertypes [Legendary],
+// types [Creature] and subtypes [Elf Druid].
+type CardTypeLine struct {
+	Supertypes []string `json:"supertypes" jsonschema:"Supertypes such as Legendary, Basic or Snow"`
+	Types      []string `json:"types" jsonschema:"Card types such as Creature, Artifact or Instant"`
+	Subtypes   []string `json:"subtypes" jsonschema:"Subtypes such as creature types, land types or Equipment"`
+}
+
+var cardSupertypes = []string{"Basic", "Legendary", "Ongoing", "Snow", "World", "Elite", "Host"}
+
+// Plane subtypes of more than one word. Multi-word creature types, such as
+// Time Lord, come from res/creature-types.txt.
+var multiWordPlaneTypes = []string{"Bolas's Meditation Realm", "New Phyrexia", "Serra's Realm"}
+
+var (
+	changelingRegex       = regexp.MustCompile(`(?i)\bis every creature type\b`)
+	chooseCreatureTypeRex = regexp.MustCompile(`(?i)\bchoose a creature type\b|\bcreature type of your choice\b|\bof the chosen type\b`)
+)
+
+// parseTypeLine splits a type line into its faces and each face into
+// supertypes, card types and subtypes. Words before the dash that are
+// neither supertypes nor card types are kept as card types so that unusual
+// type lines are not silently dropped.
+func parseTypeLine(typeLine string) []CardTypeLine {
+	faces := []CardTypeLine{}
+	for _, face := range strings.Split(typeLine, "//") {
+		face = strings.TrimSpace(face)
+		if face == "" {
+			continue
+		}
+
+		parsed := CardTypeLine{Supertypes: []string{}, Types: []string{}, Subtypes: []string{}}
+		left, right, _ := strings.Cut(face, "—")
+		for _, word := range strings.Fields(left) {
+			if contains(cardSupertypes, word) {
+				parsed.Supertypes = append(parsed.Supertypes, word)
+			} else {
+				parsed.Types = append(parsed.Types, word)
+			}
+		}
+		parsed.Subtypes = append(parsed.Subtypes, splitSubtypes(right)...)
+		faces = append(faces, parsed)
+	}
+	return faces
+}
+
+// splitSubtypes splits the subtypes after a type line's dash into words,
+// keeping multi-word subtypes such as "Time Lord" together
+func splitSubtypes(text string) []string {
+	words := strings.Fields(text)
+	subtypes := []string{}
+	for i := 0; i < len(words); i++ {
+		subtype := words[i]
+		for _, candidate := range multiWordSubtypes() {
+			if i+len(candidate) <= len(words) && equalFoldWords(words[i:i+len(candidate)], candidate) {
+				subtype = strings.Join(candidate, " ")
+				i += len(candidate) - 1
+				break
+			}
+		}
+		subtypes = append(subtypes, subtype)
+	}
+	return subtypes
+}
+
+// equalFoldWords reports whether two lists of words are equal, ignoring case
+func equalFoldWords(a, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// HasType reports whether the face has the given card type
+func (t CardTypeLine) HasType(cardType string) bool {
+	return contains(t.Types, cardType)
+}
+
+// typeLineSubtypes returns the subtypes of every face in a type line
+func typeLineSubtypes(typeLine string) []string {
+	subtypes := []string{}
+	for _, face := range parseTypeLine(typeLine) {
+		for _, subtype := range face.Subtypes {
+			if !contains(subtypes, subtype) {
+				subtypes = append(subtypes, subtype)
+			}
+		}
+	}
+	return subtypes
+}
+
+// primaryCardType returns the last card type of the first face, e.g.
+// "Creature" for "Legendary Artifact Creature — Golem".
+func primaryCardType(typeLine string) string {
+	faces := parseTypeLine(typeLine)
+	if len(faces) == 0 || len(faces[0].Types) == 0 {
+		return "card"
+	}
+	return strings.ToLower(faces[0].Types[len(faces[0].Types)-1])
+}
+
+// sameCardTypes reports whether two type lines have the same supertypes and
+// card types on their first face.
+func sameCardTypes(a, b string) bool {
+	facesA, facesB := parseTypeLine(a), parseTypeLine(b)
+	if len(facesA) == 0 || len(facesB) == 0 {
+		return len(facesA) == len(facesB)
+	}
+	return equalStrings(facesA[0].Supertypes, facesB[0].Supertypes) && equalStrings(facesA[0].Types, facesB[0].Types)
+}
+
+// cardCreatureTypes returns the creature types of a card: the subtypes of
+// every creature or kindred face that appear in res/creature-types.txt.
+func cardCreatureTypes(card scryfall.Card) []string {
+	creatureTypes := []string{}
+	for _, face := range parseTypeLine(card.TypeLine) {
+		if !face.HasType("Creature") && !face.HasType("Kindred") && !face.HasType("Tribal") {
+			continue
+		}
+		for _, subtype := range face.Subtypes {
+			if isCreatureType(subtype) && !contains(creatureTypes, subtype) {
+				creatureTypes = append(creatureTypes, subtype)
+			}
+		}
+	}
+	return creatureTypes
+}
+
+// isChangeling reports whether a card is every creature type: it has the
+// changeling keyword itself, or says it is every creature type. A card that
+// only mentions changeling, such as one making changeling tokens, is not.
+func isChangeling(card scryfall.Card) bool {
+	if contains(card.Keywords, "Changeling") {
+		return true
+	}
+	for _, ability := range parseCardAbilities(card) {
+		if ability.Kind == AbilityKeyword && ability.Keyword == "changeling" {
+			return true
+		}
+	}
+	return changelingRegex.MatchString(cardOracleText(card))
+}
+
+// choosesCreatureType reports whether a card cares about a chosen creature type
+func choosesCreatureType(card scryfall.Card) bool {
+	return chooseCreatureTypeRex.MatchString(cardOracleText(card))
+}
+
+// findTribalSynergies searches for cards sharing each of the main card's
+// creature types. Changelings are matched with cards that care about
+// creature types, and cards that choose a creature type with changelings.
+func findTribalSynergies(ctx context.Context, client *scryfall.Client, mainCard scryfall.Card, opts scryfall.SearchCardsOptions, pattern ThemePattern) []SynergyCategory {
+	type tribalSearch struct {
+		query       string
+		description string
+	}
+
+	searches := []tribalSearch{}
+	for _, creatureType := range cardCreatureTypes(mainCard) {
+		searches = append(searches, tribalSearch{
+			query:       fmt.Sprintf(`type:"%s"`, creatureType),
+			description: fmt.Sprintf("Cards that share the %s creature type", creatureType),
+		})
+	}
+	if isChangeling(mainCard) {
+		searches = append(searches, tribalSearch{
+			query:       `(oracle:"choose a creature type" OR oracle:"of the chosen type" OR oracle:"share a creature type")`,
+			description: "Cards that care about creature types, which a changeling has all of",
+		})
+	}
+	if choosesCreatureType(mainCard) {
+		searches = append(searches, tribalSearch{
+			query:       `keyword:changeling`,
+			description: "Changelings, which are every creature type including the chosen one",
+		})
+	}
+
+	// Every search runs, side by side, each capped at synergyCandidateBudget
+	// candidates; categories keep the order of searches
+	found := make([]*SynergyCategory, len(searches))
+	runParallel(ctx, len(searches), maxParallelSearches, func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		search := searches[i]
+		tribalQuery := fmt.Sprintf(`%s -name:"%s"`, search.query, mainCard.Name)
+		log.Printf("Searching for tribal synergy: %s", search.query)
+		reportProgress(ctx, "Searching for tribal synergies")
+		tribalCards, err := searchSynergyCandidates(ctx, client, tribalQuery, opts)
+		if err == nil && len(tribalCards) > 0 {
+			found[i] = &SynergyCategory{
+				SynergyType: pattern.SynergyType,
+				Description: search.description,
+				Cards:       tribalCards,
+				Count:       len(tribalCards),
+			}
+			log.Printf("Found %d cards for tribal search %s", len(tribalCards), search.query)
+		}
+	})
+
+	synergies := []SynergyCategory{}
+	for _, category := range found {
+		if category != nil {
+			synergies = append(synergies, *category)
+		}
+	}
+	return synergies
+}
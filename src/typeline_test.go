@@ -0,0 +1,163 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BlueMonday/go-scryfall"
+)
+
+func TestParseTypeLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeLine string
+		want     []CardTypeLine
+	}{
+		{
+			name:     "supertype, type and subtypes",
+			typeLine: "Legendary Creature — Elf Druid",
+			want: []CardTypeLine{
+				{Supertypes: []string{"Legendary"}, Types: []string{"Creature"}, Subtypes: []string{"Elf", "Druid"}},
+			},
+		},
+		{
+			name:     "no subtypes",
+			typeLine: "Basic Snow Land",
+			want: []CardTypeLine{
+				{Supertypes: []string{"Basic", "Snow"}, Types: []string{"Land"}, Subtypes: []string{}},
+			},
+		},
+		{
+			name:     "multi-face",
+			typeLine: "Creature — Human Werewolf // Creature — Werewolf",
+			want: []CardTypeLine{
+				{Supertypes: []string{}, Types: []string{"Creature"}, Subtypes: []string{"Human", "Werewolf"}},
+				{Supertypes: []string{}, Types: []string{"Creature"}, Subtypes: []string{"Werewolf"}},
+			},
+		},
+		{
+			name:     "adventure face without subtypes",
+			typeLine: "Creature — Elf Knight // Instant — Adventure",
+			want: []CardTypeLine{
+				{Supertypes: []string{}, Types: []string{"Creature"}, Subtypes: []string{"Elf", "Knight"}},
+				{Supertypes: []string{}, Types: []string{"Instant"}, Subtypes: []string{"Adventure"}},
+			},
+		},
+		{
+			name:     "kindred",
+			typeLine: "Kindred Instant — Shapeshifter",
+			want: []CardTypeLine{
+				{Supertypes: []string{}, Types: []string{"Kindred", "Instant"}, Subtypes: []string{"Shapeshifter"}},
+			},
+		},
+		{
+			name:     "multi-word creature type",
+			typeLine: "Legendary Creature — Time Lord Doctor",
+			want: []CardTypeLine{
+				{Supertypes: []string{"Legendary"}, Types: []string{"Creature"}, Subtypes: []string{"Time Lord", "Doctor"}},
+			},
+		},
+		{
+			name:     "multi-word plane type",
+			typeLine: "Plane — Bolas's Meditation Realm",
+			want: []CardTypeLine{
+				{Supertypes: []string{}, Types: []string{"Plane"}, Subtypes: []string{"Bolas's Meditation Realm"}},
+			},
+		},
+		{
+			name:     "empty",
+			typeLine: "",
+			want:     []CardTypeLine{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTypeLine(tt.typeLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTypeLine(%q) = %+v, want %+v", tt.typeLine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSubtypes(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Elf Druid", []string{"Elf", "Druid"}},
+		{" Time Lord Doctor ", []string{"Time Lord", "Doctor"}},
+		{"Doctor Time Lord", []string{"Doctor", "Time Lord"}},
+		{"Time", []string{"Time"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := splitSubtypes(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitSubtypes(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCardCreatureTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeLine string
+		want     []string
+	}{
+		{"creature", "Creature — Elf Warrior", []string{"Elf", "Warrior"}},
+		{"no substring matches", "Creature — Elfkin", []string{}},
+		{"multi-word type", "Legendary Creature — Time Lord Doctor", []string{"Time Lord", "Doctor"}},
+		{"kindred", "Kindred Sorcery — Goblin", []string{"Goblin"}},
+		{"tribal", "Tribal Instant — Faerie", []string{"Faerie"}},
+		{"not a creature", "Artifact — Equipment", []string{}},
+		{"land subtypes are not creature types", "Land — Forest Island", []string{}},
+		{"every face", "Creature — Human Wizard // Creature — Human Insect", []string{"Human", "Wizard", "Insect"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cardCreatureTypes(scryfall.Card{TypeLine: tt.typeLine})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cardCreatureTypes(%q) = %q, want %q", tt.typeLine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsChangeling(t *testing.T) {
+	tests := []struct {
+		name string
+		card scryfall.Card
+		want bool
+	}{
+		{
+			name: "changeling keyword",
+			card: scryfall.Card{Name: "Changeling Outcast", OracleText: "Changeling (This card is every creature type.)\nChangeling Outcast can't block and can't be blocked.", Keywords: []string{"Changeling"}},
+			want: true,
+		},
+		{
+			name: "changeling keyword in rules text only",
+			card: scryfall.Card{Name: "Mirror Entity", OracleText: "Changeling (This card is every creature type.)"},
+			want: true,
+		},
+		{
+			name: "every creature type",
+			card: scryfall.Card{Name: "Mistform Ultimus", OracleText: "Mistform Ultimus is every creature type (even if this card isn't on the battlefield)."},
+			want: true,
+		},
+		{
+			name: "only makes changeling tokens",
+			card: scryfall.Card{Name: "Token Maker", OracleText: "Exile target creature. Its controller creates a 1/1 colorless Shapeshifter creature token with changeling."},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isChangeling(tt.card); got != tt.want {
+				t.Errorf("isChangeling(%s) = %v, want %v", tt.card.Name, got, tt.want)
+			}
+		})
+	}
+}
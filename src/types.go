@@ -20,7 +20,7 @@ type SearchCardByColorArgs struct {
 
 type FindRelatedCardsArgs struct {
 	CardName     string   `json:"card_name" jsonschema:"required,The name of the card to find relationships for"`
-	RelationType []string `json:"relation_type,omitempty" jsonschema:"Types of relationships to find. Options: reprints, tokens, mechanics, same_artist, same_set. If empty, returns all types."`
+	RelationType []string `json:"relation_type,omitempty" jsonschema:"Types of relationships to find. If empty, returns all types."`
 	MaxResults   int      `json:"max_results,omitempty" jsonschema:"Maximum number of results per category (default: 10)"`
 }
 
@@ -31,8 +31,10 @@ type RelatedCardCategory struct {
 }
 
 type FindRelatedCardsResult struct {
-	MainCard   scryfall.Card         `json:"main_card" jsonschema:"The original card being queried"`
-	Categories []RelatedCardCategory `json:"categories" jsonschema:"Categories of related cards"`
+	MainCard      scryfall.Card         `json:"main_card" jsonschema:"The original card being queried"`
+	Categories    []RelatedCardCategory `json:"categories" jsonschema:"Categories of related cards"`
+	Partial       bool                  `json:"partial,omitempty" jsonschema:"True when the search was cancelled or timed out and only the categories found so far are returned"`
+	PartialReason string                `json:"partial_reason,omitempty" jsonschema:"Why the results are partial: 'cancelled' or 'timed out'"`
 }
 
 type FindCardSynergiesArgs struct {
@@ -44,12 +46,547 @@ type FindCardSynergiesArgs struct {
 type SynergyCategory struct {
 	SynergyType string          `json:"synergy_type" jsonschema:"Type of synergy (e.g., 'Keyword Synergy', 'Mechanic Synergy', 'Thematic Synergy')"`
 	Description string          `json:"description" jsonschema:"Explanattion of why these cards synergize"`
-	Cards       []scryfall.Card `json:"cards" jsonschema:"Cards that synergize with the main card"`
-	Count       int             `json:"count" jsonschema:"Number of cards in this synergy category"`
+	Role        string          `json:"role,omitempty" jsonschema:"For theme synergies, which side of the theme these cards fill: 'enabler' or 'payoff'"`
+	Cards       []scryfall.Card `json:"cards" jsonschema:"Top ranked cards that synergize with the main card"`
+	Count       int             `json:"count" jsonschema:"Number of candidate cards found in this synergy category"`
+}
+
+type SynergySignal struct {
+	Signal string  `json:"signal" jsonschema:"The signal that contributed to the score (e.g., 'shared_theme', 'shared_creature_type', 'color_identity_fit', 'complementary_role', 'popularity')"`
+	Weight float64 `json:"weight" jsonschema:"How much the signal added to (or subtracted from) the score"`
+	Detail string  `json:"detail" jsonschema:"Human-readable explanation of the signal"`
+}
+
+type ScoredSynergyCard struct {
+	Card        scryfall.Card   `json:"card" jsonschema:"The synergistic card"`
+	Score       float64         `json:"score" jsonschema:"Overall synergy score, higher is better"`
+	Categories  []string        `json:"categories" jsonschema:"Synergy categories the card was found through"`
+	Signals     []SynergySignal `json:"signals" jsonschema:"Signals that contributed to the score"`
+	Explanation string          `json:"explanation" jsonschema:"Summary of why the card synergizes with the main card"`
 }
 
 type FindCardSynergiesResult struct {
-	MainCard        scryfall.Card     `json:"main_card" jsonschema:"The original card being analyzed"`
-	ExtractedThemes []string          `json:"extracted_themes" jsonschema:"Themes and mechanics identified from the card"`
-	Synergies       []SynergyCategory `json:"synergies" jsonschema:"Categories of synrgistic cards"`
+	MainCard        scryfall.Card       `json:"main_card" jsonschema:"The original card being analyzed"`
+	ExtractedThemes []string            `json:"extracted_themes" jsonschema:"Themes and mechanics identified from the card"`
+	Synergies       []SynergyCategory   `json:"synergies" jsonschema:"Categories of synrgistic cards, containing the ranked cards each category contributed"`
+	RankedCards     []ScoredSynergyCard `json:"ranked_cards" jsonschema:"Synergistic cards from every category, deduplicated and ranked by score"`
+	Partial         bool                `json:"partial,omitempty" jsonschema:"True when the search was cancelled or timed out and only the synergies found so far are ranked"`
+	PartialReason   string              `json:"partial_reason,omitempty" jsonschema:"Why the results are partial: 'cancelled' or 'timed out'"`
+}
+
+type DeckCollectionDiffArgs struct {
+	Decklist                string `json:"decklist" jsonschema:"required,The decklist to check, one '<quantity> <card name>' per line. Arena/MTGO style set codes and collector numbers such as '1 Sol Ring (CMR) 472' are supported."`
+	Collection              string `json:"collection" jsonschema:"required,The user's collection in the same format as the decklist"`
+	AllowAlternatePrintings *bool  `json:"allow_alternate_printings,omitempty" jsonschema:"Count any printing of a card as owned even when the decklist asks for a specific printing (default: true)"`
+}
+
+type DeckCardStatus struct {
+	Name            string  `json:"name" jsonschema:"The card name"`
+	Set             string  `json:"set,omitempty" jsonschema:"Set code of the printing requested by the decklist"`
+	CollectorNumber string  `json:"collector_number,omitempty" jsonschema:"Collector number of the printing requested by the decklist"`
+	Section         string  `json:"section,omitempty" jsonschema:"Deck section the card belongs to"`
+	Needed          int     `json:"needed" jsonschema:"Copies required by the decklist"`
+	Owned           int     `json:"owned" jsonschema:"Copies covered by the collection"`
+	Missing         int     `json:"missing" jsonschema:"Copies still needed"`
+	UnitPriceUSD    float64 `json:"unit_price_usd,omitempty" jsonschema:"Price of a single copy in USD, if known"`
+	MissingCostUSD  float64 `json:"missing_cost_usd,omitempty" jsonschema:"Cost of the missing copies in USD, if known"`
+}
+
+type DeckCollectionDiffResult struct {
+	Owned          []DeckCardStatus `json:"owned" jsonschema:"Cards fully covered by the collection"`
+	Missing        []DeckCardStatus `json:"missing" jsonschema:"Cards with at least one copy missing"`
+	NotFound       []string         `json:"not_found" jsonschema:"Decklist or collection entries that could not be found on Scryfall"`
+	UnpricedCards  []string         `json:"unpriced_cards" jsonschema:"Missing cards without a USD price"`
+	TotalCards     int              `json:"total_cards" jsonschema:"Total number of cards in the decklist"`
+	OwnedCount     int              `json:"owned_count" jsonschema:"Number of decklist cards covered by the collection"`
+	MissingCount   int              `json:"missing_count" jsonschema:"Number of decklist cards missing from the collection"`
+	MissingCostUSD float64          `json:"missing_cost_usd" jsonschema:"Total USD cost of the missing cards"`
+}
+
+type FindFunctionalAlternativesArgs struct {
+	CardName       string `json:"card_name" jsonschema:"required,The name of the card to find alternatives for"`
+	IncludeSimilar *bool  `json:"include_similar,omitempty" jsonschema:"Include near-equivalent cards that are neither strictly better nor strictly worse (default: true)"`
+	MaxResults     int    `json:"max_results,omitempty" jsonschema:"Maximum number of alternatives to return (default: 15)"`
+}
+
+type FunctionalAlternative struct {
+	Card           scryfall.Card `json:"card" jsonschema:"The alternative card"`
+	Classification string        `json:"classification" jsonschema:"How the card compares: 'functional reprint', 'strictly better', 'strictly worse' or 'similar'"`
+	Similarity     float64       `json:"similarity" jsonschema:"Similarity of the normalized rules text, from 0 to 1"`
+	Differences    []string      `json:"differences" jsonschema:"Differences in mana value, cost, power/toughness, keywords and rules text"`
+	SharedThemes   []string      `json:"shared_themes,omitempty" jsonschema:"Themes both cards share"`
+}
+
+type FindFunctionalAlternativesResult struct {
+	MainCard     scryfall.Card           `json:"main_card" jsonschema:"The original card being compared"`
+	Alternatives []FunctionalAlternative `json:"alternatives" jsonschema:"Alternatives ordered from closest substitute to loosest match"`
+}
+
+type ListThemesArgs struct {
+	Source string `json:"source,omitempty" jsonschema:"Only list themes from this source: 'embedded' or the path of a theme pack file"`
+}
+
+type ThemeInfo struct {
+	Name             string `json:"name" jsonschema:"The theme name, usable as the 'theme' argument of find_card_synergies"`
+	SynergyType      string `json:"synergy_type" jsonschema:"Name of the theme's synergy category"`
+	Description      string `json:"description" jsonschema:"What the theme is about"`
+	Source           string `json:"source" jsonschema:"Where the theme was loaded from: 'embedded' or the path of a theme pack file"`
+	HasEnablerPayoff bool   `json:"has_enabler_payoff" jsonschema:"Whether the theme distinguishes enablers from payoffs"`
+}
+
+type ListThemesResult struct {
+	Themes []ThemeInfo `json:"themes" jsonschema:"All available themes sorted by name"`
+}
+
+type ParseCardTextArgs struct {
+	CardName string `json:"card_name,omitempty" jsonschema:"The name of the card to parse"`
+	Text     string `json:"text,omitempty" jsonschema:"Rules text to parse instead of looking up a card, e.g. for a custom card. Use CARDNAME to refer to the card itself."`
+}
+
+type CardAbility struct {
+	Kind            string   `json:"kind" jsonschema:"The kind of ability: keyword, activated, triggered, static or spell"`
+	Text            string   `json:"text" jsonschema:"The ability's text without reminder text and with self-references replaced by CARDNAME"`
+	Face            string   `json:"face,omitempty" jsonschema:"The face of a multi-faced card the ability is on"`
+	AbilityWord     string   `json:"ability_word,omitempty" jsonschema:"Ability word that labels the ability, such as Landfall"`
+	Keyword         string   `json:"keyword,omitempty" jsonschema:"The keyword of a keyword ability"`
+	Parameter       string   `json:"parameter,omitempty" jsonschema:"The keyword's parameter, such as '{2}' for Ward {2} or '3' for Toxic 3"`
+	Cost            string   `json:"cost,omitempty" jsonschema:"The cost of an activated ability"`
+	Trigger         string   `json:"trigger,omitempty" jsonschema:"The trigger condition of a triggered ability"`
+	Effect          string   `json:"effect,omitempty" jsonschema:"What the ability does"`
+	Reminder        string   `json:"reminder,omitempty" jsonschema:"Reminder text printed with the ability"`
+	GrantedKeywords []string `json:"granted_keywords,omitempty" jsonschema:"Keywords the ability gives to this or other objects"`
+}
+
+type ParseCardTextResult struct {
+	Name      string         `json:"name,omitempty" jsonschema:"The name of the parsed card"`
+	TypeLine  []CardTypeLine `json:"type_line,omitempty" jsonschema:"The card's type line, one entry per face"`
+	Abilities []CardAbility  `json:"abilities" jsonschema:"The card's abilities in the order they appear"`
+	Keywords  []string       `json:"keywords" jsonschema:"Keywords the card has or grants"`
+	Themes    []string       `json:"themes" jsonschema:"Deckbuilding themes detected from the abilities"`
+}
+
+type FindCombosArgs struct {
+	CardName         string `json:"card_name,omitempty" jsonschema:"The name of a card to find combos for"`
+	Decklist         string `json:"decklist,omitempty" jsonschema:"A decklist to search for combos, one '<quantity> <card name>' per line. When given with card_name, combos for the card are checked against the deck."`
+	IncludeHeuristic *bool  `json:"include_heuristic,omitempty" jsonschema:"Also detect possible untap and copy loops from the cards' abilities (default: true)"`
+	MaxResults       int    `json:"max_results,omitempty" jsonschema:"Maximum number of combos to return (default: 20)"`
+}
+
+type Combo struct {
+	ID            string   `json:"id" jsonschema:"Unique identifier of the combo"`
+	Cards         []string `json:"cards" jsonschema:"Names of the combo pieces"`
+	Prerequisites []string `json:"prerequisites" jsonschema:"What must be true before the combo can be performed"`
+	Results       []string `json:"results" jsonschema:"What the combo produces, e.g. 'infinite mana' or 'win'"`
+	Description   string   `json:"description" jsonschema:"How the combo works"`
+}
+
+type ComboMatch struct {
+	Combo
+	Source        string   `json:"source" jsonschema:"Where the combo comes from: 'dataset' for known combos or 'heuristic' for detected loops"`
+	InDeck        bool     `json:"in_deck" jsonschema:"Whether every piece is in the supplied deck"`
+	MissingPieces []string `json:"missing_pieces,omitempty" jsonschema:"Pieces not in the supplied deck"`
+}
+
+type FindCombosResult struct {
+	Card           string       `json:"card,omitempty" jsonschema:"The full name of the card combos were searched for"`
+	Combos         []ComboMatch `json:"combos" jsonschema:"Combos found, complete ones in the deck first"`
+	DatasetVersion string       `json:"dataset_version" jsonschema:"Version of the combo dataset that was searched"`
+	NotFound       []string     `json:"not_found,omitempty" jsonschema:"Decklist entries that could not be matched to a card"`
+	Warnings       []string     `json:"warnings,omitempty" jsonschema:"Problems that limited the search, such as cards that could not be looked up"`
+}
+
+type SuggestCommandersArgs struct {
+	Theme      string   `json:"theme,omitempty" jsonschema:"A theme from list_themes to build around, such as 'tokens' or 'sacrifice'"`
+	Colors     string   `json:"colors,omitempty" jsonschema:"Colors the deck should play: WUBRG letters (e.g. 'GW'), color names, or combination names such as 'Selesnya', 'Esper' or 'Temur'"`
+	SeedCards  []string `json:"seed_cards,omitempty" jsonschema:"Cards the deck should be built around. Their themes are used when no theme is given and their colors when no colors are given."`
+	MaxResults int      `json:"max_results,omitempty" jsonschema:"Maximum number of commanders to return (default: 10)"`
+}
+
+type CommanderSuggestion struct {
+	Card                scryfall.Card   `json:"card" jsonschema:"The suggested commander"`
+	Score               float64         `json:"score" jsonschema:"Overall fit; higher is better"`
+	MatchedThemes       []string        `json:"matched_themes" jsonschema:"Requested themes the commander fits"`
+	SeedCardsInIdentity int             `json:"seed_cards_in_identity" jsonschema:"Number of seed cards within the commander's color identity"`
+	Signals             []SynergySignal `json:"signals" jsonschema:"Signals that contributed to the score"`
+	Explanation         string          `json:"explanation" jsonschema:"Human-readable summary of the signals"`
+}
+
+type SuggestCommandersResult struct {
+	Themes        []string              `json:"themes" jsonschema:"Themes the commanders were matched against"`
+	ColorIdentity string                `json:"color_identity,omitempty" jsonschema:"Color identity the commanders were limited to, in WUBRG order"`
+	Commanders    []CommanderSuggestion `json:"commanders" jsonschema:"Legal commanders ordered from best to worst fit"`
+	NotFound      []string              `json:"not_found,omitempty" jsonschema:"Seed cards that could not be found"`
+}
+
+type BuildCommanderDeckArgs struct {
+	Commander       string  `json:"commander" jsonschema:"required,The name of the commander to build around"`
+	Theme           string  `json:"theme,omitempty" jsonschema:"Optional theme to focus the synergy slots on, such as 'tokens'"`
+	Seed            *int64  `json:"seed,omitempty" jsonschema:"Seed for breaking ties between similar cards. The same seed and inputs always give the same deck; a random seed is used and returned when omitted."`
+	MaxCardPriceUSD float64 `json:"max_card_price_usd,omitempty" jsonschema:"Skip cards that cost more than this many US dollars"`
+	BudgetUSD       float64 `json:"budget_usd,omitempty" jsonschema:"Total budget in US dollars for the 99 cards, spread across roles by their quotas"`
+	Collection      string  `json:"collection,omitempty" jsonschema:"The user's collection, one '<quantity> <card name>' per line. Owned cards are preferred."`
+	CollectionOnly  bool    `json:"collection_only,omitempty" jsonschema:"Only use cards from the collection, besides basic lands"`
+}
+
+type DeckSlot struct {
+	Role      string  `json:"role" jsonschema:"The role the card fills: ramp, draw, removal, wipes, synergy or lands"`
+	Name      string  `json:"name" jsonschema:"The card name"`
+	Quantity  int     `json:"quantity" jsonschema:"Number of copies; only basic lands have more than one"`
+	ManaValue float64 `json:"mana_value" jsonschema:"The card's mana value"`
+	ManaCost  string  `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine  string  `json:"type_line" jsonschema:"The card's type line"`
+	PriceUSD  float64 `json:"price_usd,omitempty" jsonschema:"Cheapest USD price of one copy"`
+	Owned     bool    `json:"owned,omitempty" jsonschema:"Whether the card is in the supplied collection"`
+	Reason    string  `json:"reason" jsonschema:"Why the card was included"`
+}
+
+type BuildCommanderDeckResult struct {
+	Commander     string         `json:"commander" jsonschema:"The commander the deck was built around"`
+	ColorIdentity string         `json:"color_identity" jsonschema:"The commander's color identity in WUBRG order"`
+	Seed          int64          `json:"seed" jsonschema:"Seed used to build the deck; pass it again to get the same deck"`
+	Slots         []DeckSlot     `json:"slots" jsonschema:"The 99 cards grouped by role"`
+	RoleCounts    map[string]int `json:"role_counts" jsonschema:"Number of cards in each role"`
+	CardCount     int            `json:"card_count" jsonschema:"Number of cards besides the commander"`
+	TotalPriceUSD float64        `json:"total_price_usd" jsonschema:"Total USD price of the 99 cards"`
+	Decklist      string         `json:"decklist" jsonschema:"The deck as a plain-text list that can be imported into deckbuilding sites"`
+	DeckURI       string         `json:"deck_uri" jsonschema:"URI of the deck as an MCP resource, readable while the server runs"`
+	Notes         []string       `json:"notes,omitempty" jsonschema:"Roles that could not be filled and other caveats"`
+}
+
+type CompareCardsArgs struct {
+	CardNames []string `json:"card_names" jsonschema:"required,The names of the 2 to 6 cards to compare"`
+}
+
+type CardComparison struct {
+	Name       string            `json:"name" jsonschema:"The card name"`
+	ManaCost   string            `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	ManaValue  float64           `json:"mana_value" jsonschema:"The card's mana value"`
+	TypeLine   string            `json:"type_line" jsonschema:"The card's type line"`
+	Power      string            `json:"power,omitempty" jsonschema:"Power, for creatures"`
+	Toughness  string            `json:"toughness,omitempty" jsonschema:"Toughness, for creatures"`
+	Loyalty    string            `json:"loyalty,omitempty" jsonschema:"Starting loyalty, for planeswalkers"`
+	Keywords   []string          `json:"keywords" jsonschema:"Keyword abilities the card has"`
+	Themes     []string          `json:"themes" jsonschema:"Keywords and deck themes the card fits"`
+	Legalities map[string]string `json:"legalities" jsonschema:"Legality in each format: legal, not_legal, restricted or banned"`
+	PriceUSD   float64           `json:"price_usd,omitempty" jsonschema:"Cheapest USD price of one copy, if known"`
+	Rarity     string            `json:"rarity" jsonschema:"Rarity of the printing found"`
+	Printings  int               `json:"printings,omitempty" jsonschema:"Number of printings of the card, including the first; omitted when it could not be looked up"`
+	Reprints   *int              `json:"reprints,omitempty" jsonschema:"Number of times the card has been reprinted since its first printing"`
+	OracleText string            `json:"oracle_text" jsonschema:"Rules text with the card's own name replaced by CARDNAME and reminder text removed, one ability per line"`
+}
+
+type TextDiffOp struct {
+	Op   string `json:"op" jsonschema:"equal, insert or delete"`
+	Text string `json:"text" jsonschema:"The words the operation applies to"`
+}
+
+type OracleTextDiff struct {
+	Base    string       `json:"base" jsonschema:"The card the text is compared against: the first card given"`
+	Against string       `json:"against" jsonschema:"The card whose text is compared"`
+	Ops     []TextDiffOp `json:"ops" jsonschema:"Word-level edits turning the base card's text into this card's text"`
+}
+
+type CompareCardsResult struct {
+	Cards            []CardComparison `json:"cards" jsonschema:"The cards side by side, in the order given"`
+	DifferingFields  []string         `json:"differing_fields" jsonschema:"Attributes that are not the same on every card"`
+	DifferingFormats []string         `json:"differing_formats,omitempty" jsonschema:"Formats where the cards' legality differs"`
+	OracleTextDiffs  []OracleTextDiff `json:"oracle_text_diffs" jsonschema:"Diff of each card's rules text against the first card's"`
+	NotFound         []string         `json:"not_found,omitempty" jsonschema:"Card names that could not be found"`
+	Warnings         []string         `json:"warnings,omitempty" jsonschema:"Problems that limited the comparison, such as printings that could not be counted"`
+}
+
+type ListSetsArgs struct {
+	SetType        string `json:"set_type,omitempty" jsonschema:"Only sets of these comma-separated Scryfall set types, such as 'expansion,core' or 'commander'"`
+	ReleasedAfter  string `json:"released_after,omitempty" jsonschema:"Only sets released on or after this date (YYYY-MM-DD)"`
+	ReleasedBefore string `json:"released_before,omitempty" jsonschema:"Only sets released on or before this date (YYYY-MM-DD)"`
+	Block          string `json:"block,omitempty" jsonschema:"Only sets in this block, by name or code, such as 'Innistrad' or 'isd'"`
+	Digital        *bool  `json:"digital,omitempty" jsonschema:"true for only digital sets, false for only paper sets"`
+	MaxResults     int    `json:"max_results,omitempty" jsonschema:"Maximum number of sets to return, newest first (default 100)"`
+}
+
+type SetInfo struct {
+	Code          string `json:"code" jsonschema:"The set code"`
+	Name          string `json:"name" jsonschema:"The set name"`
+	SetType       string `json:"set_type" jsonschema:"Scryfall's set type, such as expansion, core, masters or commander"`
+	ReleasedAt    string `json:"released_at,omitempty" jsonschema:"Release date (YYYY-MM-DD)"`
+	Block         string `json:"block,omitempty" jsonschema:"The block the set belongs to"`
+	BlockCode     string `json:"block_code,omitempty" jsonschema:"The block code"`
+	ParentSetCode string `json:"parent_set_code,omitempty" jsonschema:"The code of the set this one belongs to, for commander decks, promos and tokens"`
+	CardCount     int    `json:"card_count,omitempty" jsonschema:"Number of cards in the set, when known"`
+	Digital       bool   `json:"digital" jsonschema:"Whether the set was only released digitally"`
+	FoilOnly      bool   `json:"foil_only,omitempty" jsonschema:"Whether the set only contains foil cards"`
+	IconSVGURI    string `json:"icon_svg_uri,omitempty" jsonschema:"URI of the set's SVG icon"`
+	ScryfallURI   string `json:"scryfall_uri,omitempty" jsonschema:"Link to the set on Scryfall"`
+}
+
+type ListSetsResult struct {
+	Sets         []SetInfo `json:"sets" jsonschema:"Matching sets, newest first"`
+	TotalCount   int       `json:"total_count" jsonschema:"Number of matching sets before max_results was applied"`
+	Source       string    `json:"source" jsonschema:"Where the set data came from: scryfall; or when Scryfall could not be reached, snapshot for the set list last saved from Scryfall or bundled for the one shipped with the server"`
+	SnapshotDate string    `json:"snapshot_date,omitempty" jsonschema:"Date of the snapshot, when one was used"`
+}
+
+type GetSetArgs struct {
+	Set string `json:"set" jsonschema:"required,The set code, such as 'dmu', or the full set name"`
+}
+
+type GetSetResult struct {
+	Set          SetInfo `json:"set" jsonschema:"The set"`
+	Source       string  `json:"source" jsonschema:"Where the set data came from: scryfall; or when Scryfall could not be reached, snapshot for the set list last saved from Scryfall or bundled for the one shipped with the server"`
+	SnapshotDate string  `json:"snapshot_date,omitempty" jsonschema:"Date of the snapshot, when one was used"`
+}
+
+type BrowseSetArgs struct {
+	Set      string `json:"set" jsonschema:"required,The set code, such as 'dmu', or the full set name"`
+	Rarity   string `json:"rarity,omitempty" jsonschema:"Only cards of this rarity: common, uncommon, rare, mythic, special or bonus"`
+	Colors   string `json:"colors,omitempty" jsonschema:"Only cards that include these colors, as WUBRG letters or names such as 'Izzet'; 'C' for colorless"`
+	Page     int    `json:"page,omitempty" jsonschema:"Page number, starting at 1"`
+	PageSize int    `json:"page_size,omitempty" jsonschema:"Cards per page (default 50, max 175)"`
+}
+
+type SetCard struct {
+	CollectorNumber string  `json:"collector_number" jsonschema:"The card's collector number in the set"`
+	Name            string  `json:"name" jsonschema:"The card name"`
+	ManaCost        string  `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine        string  `json:"type_line" jsonschema:"The card's type line"`
+	Rarity          string  `json:"rarity" jsonschema:"The card's rarity in this set"`
+	PriceUSD        float64 `json:"price_usd,omitempty" jsonschema:"Cheapest USD price of this printing"`
+	ScryfallURI     string  `json:"scryfall_uri,omitempty" jsonschema:"Link to the printing on Scryfall"`
+}
+
+type BrowseSetResult struct {
+	Set        SetInfo   `json:"set" jsonschema:"The set being browsed"`
+	Page       int       `json:"page" jsonschema:"The page returned"`
+	PageSize   int       `json:"page_size" jsonschema:"Cards per page"`
+	TotalCards int       `json:"total_cards" jsonschema:"Number of cards matching the filters"`
+	HasMore    bool      `json:"has_more" jsonschema:"Whether there are more pages"`
+	Cards      []SetCard `json:"cards" jsonschema:"Cards on this page in collector number order"`
+	Source     string    `json:"source" jsonschema:"Where the cards came from: scryfall, or offline when Scryfall could not be reached"`
+	Note       string    `json:"note,omitempty" jsonschema:"Caveats, such as the card list being unavailable without Scryfall"`
+}
+
+type FormatInfoArgs struct {
+	Format   string `json:"format,omitempty" jsonschema:"The format, such as 'modern' or 'commander'. Alone, lists its banned and restricted cards; with a card, limits the card's legality to this format."`
+	CardName string `json:"card_name,omitempty" jsonschema:"A card whose legality to check in every format"`
+}
+
+type FormatBanList struct {
+	Format     string   `json:"format" jsonschema:"The format"`
+	Banned     []string `json:"banned" jsonschema:"Cards banned in the format"`
+	Restricted []string `json:"restricted" jsonschema:"Cards restricted to one copy in the format"`
+}
+
+type FormatLegality struct {
+	Format      string `json:"format" jsonschema:"The format"`
+	Status      string `json:"status" jsonschema:"legal, not_legal, banned or restricted"`
+	Explanation string `json:"explanation" jsonschema:"Why the card has this status"`
+}
+
+type LegalityChange struct {
+	Date   string `json:"date" jsonschema:"Date of the first snapshot that showed the change (YYYY-MM-DD)"`
+	Format string `json:"format" jsonschema:"The format"`
+	Card   string `json:"card" jsonschema:"The card whose status changed"`
+	From   string `json:"from" jsonschema:"Status before: legal, not_legal, banned or restricted; unlisted when only the ban lists were known and the card was on neither"`
+	To     string `json:"to" jsonschema:"Status after: legal, not_legal, banned or restricted; unlisted when only the ban lists were known and the card was on neither"`
+}
+
+type FormatInfoResult struct {
+	BanList       *FormatBanList   `json:"ban_list,omitempty" jsonschema:"The format's banned and restricted cards, when only a format was given"`
+	Card          string           `json:"card,omitempty" jsonschema:"The card whose legality was checked"`
+	Legalities    []FormatLegality `json:"legalities,omitempty" jsonschema:"The card's legality in each format, with explanations"`
+	Changes       []LegalityChange `json:"changes" jsonschema:"Legality changes recorded between local snapshots, oldest first: the card's status changes, or the format's ban-list changes"`
+	SnapshotCount int              `json:"snapshot_count" jsonschema:"Number of local legality snapshots the changes were computed from"`
+	Notes         []string         `json:"notes,omitempty" jsonschema:"Caveats, such as legality history not being recorded"`
+}
+
+type RandomCardArgs struct {
+	Query string `json:"query,omitempty" jsonschema:"Optional Scryfall search the card must match, such as 't:dragon r:mythic'"`
+	Seed  *int64 `json:"seed,omitempty" jsonschema:"Seed for picking the card. The same seed and query give the same card while Scryfall's results are unchanged; a random seed is used and returned when omitted."`
+}
+
+type RandomCardResult struct {
+	Card         scryfall.Card `json:"card" jsonschema:"The randomly picked card"`
+	Query        string        `json:"query" jsonschema:"The search the card was picked from"`
+	TotalMatches int           `json:"total_matches" jsonschema:"Number of cards the search matched"`
+	Seed         int64         `json:"seed" jsonschema:"Seed used to pick the card; pass it again to get the same card"`
+	Source       string        `json:"source" jsonschema:"Where the card was picked from: scryfall, or offline when Scryfall could not be reached"`
+	Note         string        `json:"note,omitempty" jsonschema:"Caveats, such as the card coming from the offline card data"`
+}
+
+type GenerateBoosterArgs struct {
+	Set   string `json:"set" jsonschema:"required,The set code, such as 'dmu', or the full set name"`
+	Type  string `json:"type,omitempty" jsonschema:"Booster type: play (default), draft or set"`
+	Count int    `json:"count,omitempty" jsonschema:"Number of boosters to open (default 1, max 36)"`
+	Seed  *int64 `json:"seed,omitempty" jsonschema:"Seed for opening the boosters. The same seed and inputs give the same boosters; a random seed is used and returned when omitted."`
+}
+
+type BoosterCard struct {
+	Slot            string `json:"slot" jsonschema:"The booster slot the card was opened in, such as common, rare, wildcard or foil"`
+	Name            string `json:"name" jsonschema:"The card name"`
+	Set             string `json:"set" jsonschema:"The card's set code; special guests and The List come from other sets"`
+	CollectorNumber string `json:"collector_number,omitempty" jsonschema:"The card's collector number"`
+	Rarity          string `json:"rarity,omitempty" jsonschema:"The card's rarity"`
+	Foil            bool   `json:"foil,omitempty" jsonschema:"Whether the card is foil"`
+	ManaCost        string `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine        string `json:"type_line,omitempty" jsonschema:"The card's type line"`
+	ColorIdentity   string `json:"color_identity,omitempty" jsonschema:"The card's color identity in WUBRG order"`
+}
+
+type Booster struct {
+	Number int           `json:"number" jsonschema:"The booster's number, starting at 1"`
+	Cards  []BoosterCard `json:"cards" jsonschema:"The cards in the booster, in slot order"`
+}
+
+type GenerateBoosterResult struct {
+	Set         string    `json:"set" jsonschema:"The set code"`
+	SetName     string    `json:"set_name,omitempty" jsonschema:"The set name"`
+	Type        string    `json:"type" jsonschema:"The booster type"`
+	Definition  string    `json:"definition" jsonschema:"Which booster definition was used: the set's own, or default"`
+	Description string    `json:"description,omitempty" jsonschema:"Description of the booster's contents"`
+	Seed        int64     `json:"seed" jsonschema:"Seed used to open the boosters; pass it again to get the same boosters"`
+	Boosters    []Booster `json:"boosters" jsonschema:"The opened boosters"`
+	Notes       []string  `json:"notes,omitempty" jsonschema:"Slots that could not be filled and other caveats"`
+}
+
+type SealedPoolArgs struct {
+	Set  string `json:"set,omitempty" jsonschema:"The set to open six boosters of, by code or name"`
+	Type string `json:"type,omitempty" jsonschema:"Booster type to open: play (default), draft or set"`
+	Seed *int64 `json:"seed,omitempty" jsonschema:"Seed for opening the boosters. The same seed and set give the same pool; a random seed is used and returned when omitted."`
+	Pool string `json:"pool,omitempty" jsonschema:"A pool to summarize instead of opening boosters, one '<quantity> <card name>' per line"`
+}
+
+type PoolCard struct {
+	Name          string `json:"name" jsonschema:"The card name"`
+	Quantity      int    `json:"quantity" jsonschema:"Number of copies in the pool"`
+	Rarity        string `json:"rarity,omitempty" jsonschema:"The card's rarity"`
+	ColorIdentity string `json:"color_identity,omitempty" jsonschema:"The card's color identity in WUBRG order"`
+	ManaCost      string `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine      string `json:"type_line,omitempty" jsonschema:"The card's type line"`
+}
+
+type SealedPoolResult struct {
+	Set          string         `json:"set,omitempty" jsonschema:"The set code the boosters were opened from"`
+	SetName      string         `json:"set_name,omitempty" jsonschema:"The set name"`
+	Type         string         `json:"type,omitempty" jsonschema:"The booster type opened"`
+	Seed         *int64         `json:"seed,omitempty" jsonschema:"Seed used to open the boosters; pass it to sealed_pool or build_limited_deck to get the same pool"`
+	Boosters     []Booster      `json:"boosters,omitempty" jsonschema:"The six opened boosters"`
+	Pool         []PoolCard     `json:"pool" jsonschema:"Every card in the pool"`
+	PoolSize     int            `json:"pool_size" jsonschema:"Number of cards in the pool"`
+	ColorCounts  map[string]int `json:"color_counts" jsonschema:"Number of cards of each color; C counts colorless cards"`
+	RarityCounts map[string]int `json:"rarity_counts" jsonschema:"Number of cards of each rarity"`
+	Decklist     string         `json:"decklist" jsonschema:"The pool as a plain-text list that build_limited_deck accepts"`
+	NotFound     []string       `json:"not_found,omitempty" jsonschema:"Pool cards that could not be found"`
+	Notes        []string       `json:"notes,omitempty" jsonschema:"Slots that could not be filled and other caveats"`
+}
+
+type BuildLimitedDeckArgs struct {
+	Pool   string `json:"pool,omitempty" jsonschema:"The sealed pool, one '<quantity> <card name>' per line"`
+	Set    string `json:"set,omitempty" jsonschema:"Instead of a pool, open a sealed pool of this set with sealed_pool's seed and type"`
+	Type   string `json:"type,omitempty" jsonschema:"Booster type when opening a pool: play (default), draft or set"`
+	Seed   *int64 `json:"seed,omitempty" jsonschema:"Seed of the sealed pool to open"`
+	Builds int    `json:"builds,omitempty" jsonschema:"Number of candidate builds to return, 2 or 3 (default 3)"`
+}
+
+type LimitedDeckCard struct {
+	Name      string   `json:"name" jsonschema:"The card name"`
+	Quantity  int      `json:"quantity" jsonschema:"Number of copies"`
+	ManaValue float64  `json:"mana_value,omitempty" jsonschema:"The card's mana value"`
+	ManaCost  string   `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine  string   `json:"type_line" jsonschema:"The card's type line"`
+	Rarity    string   `json:"rarity,omitempty" jsonschema:"The card's rarity"`
+	Score     float64  `json:"score,omitempty" jsonschema:"The card's limited rating"`
+	Notes     []string `json:"notes,omitempty" jsonschema:"What raised or lowered the rating, such as removal or evasion"`
+}
+
+type LimitedDeckBuild struct {
+	Colors        string            `json:"colors" jsonschema:"The build's colors in WUBRG order"`
+	Score         float64           `json:"score" jsonschema:"Overall score used to rank the builds"`
+	Playables     int               `json:"playables" jsonschema:"Number of nonland cards in the pool within these colors"`
+	CreatureCount int               `json:"creature_count" jsonschema:"Number of creatures"`
+	RemovalCount  int               `json:"removal_count" jsonschema:"Number of removal spells"`
+	Curve         map[string]int    `json:"curve" jsonschema:"Number of spells at each mana value, with 6+ grouped"`
+	Spells        []LimitedDeckCard `json:"spells" jsonschema:"The 23 spells"`
+	Lands         []LimitedDeckCard `json:"lands" jsonschema:"The 17 lands"`
+	Reasoning     []string          `json:"reasoning" jsonschema:"Why the build looks the way it does"`
+	Decklist      string            `json:"decklist" jsonschema:"The deck as a plain-text list"`
+	DeckURI       string            `json:"deck_uri" jsonschema:"URI of the deck as an MCP resource, readable while the server runs"`
+}
+
+type ColorDepth struct {
+	Color     string  `json:"color" jsonschema:"The color"`
+	Playables int     `json:"playables" jsonschema:"Number of nonland cards of this color"`
+	Creatures int     `json:"creatures" jsonschema:"Number of creatures of this color"`
+	Removal   int     `json:"removal" jsonschema:"Number of removal spells of this color"`
+	Score     float64 `json:"score" jsonschema:"Total limited rating of the color's cards"`
+}
+
+type BuildLimitedDeckResult struct {
+	PoolSize   int                `json:"pool_size" jsonschema:"Number of cards in the pool"`
+	Seed       *int64             `json:"seed,omitempty" jsonschema:"Seed of the opened pool, when one was opened"`
+	ColorDepth []ColorDepth       `json:"color_depth" jsonschema:"The pool's depth in each color, deepest first"`
+	Builds     []LimitedDeckBuild `json:"builds" jsonschema:"Candidate 40-card builds, best first"`
+	NotFound   []string           `json:"not_found,omitempty" jsonschema:"Pool cards that could not be found"`
+	Notes      []string           `json:"notes,omitempty" jsonschema:"Caveats about the pool or the builds"`
+}
+
+type CardRating struct {
+	Name    string   `json:"name" jsonschema:"The card name"`
+	Color   string   `json:"color,omitempty" jsonschema:"The card's colors as letters, such as WU"`
+	Rarity  string   `json:"rarity,omitempty" jsonschema:"The card's rarity"`
+	WinRate *float64 `json:"win_rate,omitempty" jsonschema:"The card's win rate in percent, from the metric column"`
+	Games   int      `json:"games,omitempty" jsonschema:"Number of games the win rate is based on"`
+	ALSA    float64  `json:"alsa,omitempty" jsonschema:"Average last seen at: the average pick the card was last seen in a pack"`
+	ATA     float64  `json:"ata,omitempty" jsonschema:"Average taken at: the average pick the card was taken"`
+}
+
+type LoadSetRatingsArgs struct {
+	Set    string `json:"set" jsonschema:"The set code the ratings are for, such as mkm"`
+	CSV    string `json:"csv" jsonschema:"The ratings CSV, with a header row naming a Name column and a win rate column such as GIH WR, GP WR, OH WR or Rating"`
+	Source string `json:"source,omitempty" jsonschema:"Where the ratings came from, such as a 17Lands export date"`
+}
+
+type LoadSetRatingsResult struct {
+	Set         string       `json:"set" jsonschema:"The set code"`
+	Metric      string       `json:"metric" jsonschema:"The CSV column the win rates were read from"`
+	CardCount   int          `json:"card_count" jsonschema:"Number of cards imported"`
+	RatedCount  int          `json:"rated_count" jsonschema:"Number of cards with a win rate"`
+	MeanWinRate float64      `json:"mean_win_rate" jsonschema:"The set's average win rate"`
+	ImportedAt  string       `json:"imported_at" jsonschema:"When the ratings were imported"`
+	Path        string       `json:"path" jsonschema:"The file the ratings are stored in"`
+	TopCards    []CardRating `json:"top_cards" jsonschema:"The highest rated cards"`
+}
+
+type DraftPickArgs struct {
+	Set   string   `json:"set,omitempty" jsonschema:"The set being drafted, to use its imported ratings"`
+	Pack  []string `json:"pack" jsonschema:"Names of the cards in the current pack"`
+	Picks []string `json:"picks,omitempty" jsonschema:"Names of the cards picked so far"`
+}
+
+type DraftPickRanking struct {
+	Rank          int      `json:"rank" jsonschema:"The card's rank in the pack, 1 being the recommended pick"`
+	Name          string   `json:"name" jsonschema:"The card name"`
+	Score         float64  `json:"score" jsonschema:"The pick score, including color and curve adjustments"`
+	BaseScore     float64  `json:"base_score" jsonschema:"The card's score on its own, from its win rate or the fallback rating"`
+	RatingSource  string   `json:"rating_source" jsonschema:"Where the base score came from: ratings or fallback"`
+	WinRate       *float64 `json:"win_rate,omitempty" jsonschema:"The card's win rate from the set's ratings"`
+	ALSA          float64  `json:"alsa,omitempty" jsonschema:"The card's average last seen at from the set's ratings"`
+	Rarity        string   `json:"rarity,omitempty" jsonschema:"The card's rarity"`
+	ColorIdentity string   `json:"color_identity,omitempty" jsonschema:"The card's color identity"`
+	ManaCost      string   `json:"mana_cost,omitempty" jsonschema:"The card's mana cost"`
+	TypeLine      string   `json:"type_line,omitempty" jsonschema:"The card's type line"`
+	ColorFit      string   `json:"color_fit,omitempty" jsonschema:"How the card fits the picks' colors: on-color, off-color or colorless"`
+	Reasons       []string `json:"reasons" jsonschema:"What went into the score"`
+}
+
+type DraftPickResult struct {
+	Set            string             `json:"set,omitempty" jsonschema:"The set being drafted"`
+	Recommendation string             `json:"recommendation" jsonschema:"The recommended pick"`
+	Rankings       []DraftPickRanking `json:"rankings" jsonschema:"The pack's cards, best pick first"`
+	PickCount      int                `json:"pick_count" jsonschema:"Number of picks so far"`
+	MainColors     string             `json:"main_colors,omitempty" jsonschema:"The colors the picks lean towards, heaviest first"`
+	ColorWeights   map[string]float64 `json:"color_weights,omitempty" jsonschema:"Total score of the picks in each color"`
+	Commitment     float64            `json:"commitment" jsonschema:"How settled the picks' colors are, from 0 to 1"`
+	RatingsMetric  string             `json:"ratings_metric,omitempty" jsonschema:"The win rate the set's ratings use, when it has any"`
+	NotFound       []string           `json:"not_found,omitempty" jsonschema:"Cards that could not be found"`
+	Notes          []string           `json:"notes,omitempty" jsonschema:"Caveats about the ranking"`
 }